@@ -35,7 +35,7 @@ func BenchmarkBlockRequests(b *testing.B) {
 		logger.Infof("Running with configuration: chain: %s, database: %s", cfg.Chain.NodeURL, cfg.DB.Database)
 
 		// connect to the database
-		db, err := database.ConnectAndInitialize(ctx, &cfg.DB)
+		db, err := database.ConnectAndInitialize(ctx, &cfg.DB, nil)
 		if err != nil {
 			logger.Fatalf("Database connect and initialize error: %s", err)
 		}
@@ -45,7 +45,7 @@ func BenchmarkBlockRequests(b *testing.B) {
 			logger.Fatalf("Invalid node URL in config: %s", err)
 		}
 
-		ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RpcConcurrency)
+		ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RPCLimits())
 		if err != nil {
 			logger.Fatalf("Eth client error: %s", err)
 		}
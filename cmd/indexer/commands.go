@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/fsp"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
+
+	"github.com/pkg/errors"
+)
+
+// command is a subcommand of the binary. flags registers command-specific
+// flags next to the global --config flag; args are the positional arguments
+// after the command name.
+type command struct {
+	run   func(ctx context.Context, args []string) error
+	flags func(fs *flag.FlagSet)
+}
+
+// commands are selected by the first non-flag argument, e.g.
+// `flare-cchain-indexer status --config config.toml`. Without a command the
+// binary runs the indexer.
+var commands = map[string]command{
+	"status":          {run: runStatus},
+	"migrate":         {run: runMigrate, flags: migrateFlags},
+	"convert-storage": {run: runConvertStorage, flags: convertStorageFlags},
+	"export":          {run: runExport, flags: registerExportFlags},
+	"snapshot":        {run: runSnapshot, flags: snapshotFlags},
+}
+
+func dispatch(ctx context.Context, args []string) error {
+	cmd := command{run: func(ctx context.Context, _ []string) error { return run(ctx) }}
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		c, ok := commands[args[0]]
+		if !ok {
+			return errors.Errorf("unknown command %q", args[0])
+		}
+		cmd = c
+		args = args[1:]
+	}
+
+	if cmd.flags != nil {
+		cmd.flags(flag.CommandLine)
+	}
+	positional, err := parseInterspersed(flag.CommandLine, args)
+	if err != nil {
+		return err
+	}
+
+	return cmd.run(ctx, positional)
+}
+
+// parseInterspersed parses flags anywhere among args (the flag package stops
+// at the first positional argument) and returns the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// loadConfig builds the config and applies the global config callbacks
+// (logger, timeouts).
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.BuildConfig()
+	if err != nil {
+		// The logger is not initialized yet so fallback to directly
+		// printing to stdout.
+		fmt.Println("Error building config: ", err)
+		return nil, err
+	}
+
+	config.GlobalConfigCallback.Call(cfg)
+
+	return cfg, nil
+}
+
+var chainFlag = flag.String("chain", "", "with [[chains]] in the config, the chain the command operates on")
+
+// loadChainConfig is loadConfig for commands that work on one chain: the
+// config of the --chain entry, or the config itself without [[chains]].
+func loadChainConfig() (*config.Config, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.ChainConfig(*chainFlag)
+}
+
+// connectChain dials the RPC node, checks its chain ID against
+// expected_chain_id and resolves the configured contract names to addresses
+// in place. It returns the chain ID the node reports.
+func connectChain(ctx context.Context, cfg *config.Config) (*chain.Client, *contracts.ContractResolver, *big.Int, error) {
+	nodeURL, err := cfg.Chain.FullNodeURL()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "Invalid node URL in config")
+	}
+
+	ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RPCLimits())
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "Could not connect to the RPC nodes")
+	}
+
+	// Checked before anything is read from or written for the chain.
+	chainID, err := ethClient.ChainID(ctx)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "failed to get chain ID")
+	}
+	if err := cfg.Chain.CheckChainID(chainID); err != nil {
+		return nil, nil, nil, err
+	}
+
+	resolver, err := contracts.NewContractResolver(ethClient)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "Failed to initialize contract registry resolver")
+	}
+
+	if err := config.ResolveContractAddresses(ctx, cfg, resolver); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "Failed to resolve configured contract addresses")
+	}
+
+	return ethClient, resolver, chainID, nil
+}
+
+// chainIdentity is the identity of the chain with chainID served by
+// ethClient, indexed in the mode of cfg.
+func chainIdentity(ctx context.Context, cfg *config.Config, ethClient *chain.Client, chainID *big.Int) (*database.ChainIdentity, error) {
+	blockHash := func(ctx context.Context, number uint64) (string, error) {
+		header, err := ethClient.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+		if err != nil {
+			return "", err
+		}
+		return strings.ToLower(header.Hash().Hex()[2:]), nil
+	}
+	genesisHash, err := blockHash(ctx, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get genesis block")
+	}
+	return &database.ChainIdentity{
+		Metadata: database.ChainMetadata{
+			ChainID:     chainID.Uint64(),
+			GenesisHash: genesisHash,
+			Mode:        cfg.Indexer.Mode,
+		},
+		BlockHash: blockHash,
+	}, nil
+}
+
+func fspStatusInfo(cfg *config.Config, ethClient *chain.Client, resolver *contracts.ContractResolver) status.FspInfoFunc {
+	if !cfg.Indexer.IsFspMode() {
+		return nil
+	}
+	return fsp.StatusInfo(resolver, ethClient, cfg.Indexer.HistoryEpochs)
+}
+
+// runStatus prints the status report as JSON. It only reads the database, so
+// it is safe to run next to a live indexer; startup progress is only known to
+// the indexer process itself and is served on its /status endpoint.
+func runStatus(ctx context.Context, _ []string) error {
+	cfg, err := loadChainConfig()
+	if err != nil {
+		return err
+	}
+
+	ethClient, resolver, _, err := connectChain(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Connect(ctx, &cfg.DB)
+	if err != nil {
+		return errors.Wrap(err, "Database connect error")
+	}
+
+	report, err := status.NewReporter(cfg, db, ethClient, nil, fspStatusInfo(cfg, ethClient, resolver), nil).Report(ctx)
+	if err != nil {
+		return errors.Wrap(err, "build status report")
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var convertStorageTo *string
+
+func convertStorageFlags(fs *flag.FlagSet) {
+	convertStorageTo = fs.String("to", "", "convert-storage: target storage format, hex or binary (default db.storage_format)")
+}
+
+// runConvertStorage rewrites an existing database to another storage format
+// (`convert-storage --to binary`). The indexer must be stopped while it
+// runs; each table is rewritten in a single transaction, and an interrupted
+// conversion is completed by running the command again. Set
+// db.storage_format to the new format before restarting the indexer.
+func runConvertStorage(ctx context.Context, _ []string) error {
+	cfg, err := loadChainConfig()
+	if err != nil {
+		return err
+	}
+
+	target := cfg.DB.StorageFormat
+	if *convertStorageTo != "" {
+		target = *convertStorageTo
+	}
+
+	db, err := database.Connect(ctx, &cfg.DB)
+	if err != nil {
+		return errors.Wrap(err, "Database connect error")
+	}
+
+	if err := printStorageFormats(db); err != nil {
+		return err
+	}
+	if err := database.ConvertStorageFormat(ctx, db, target); err != nil {
+		return err
+	}
+	fmt.Printf("Converted to %s storage\n", target)
+	if target != cfg.DB.StorageFormat {
+		fmt.Printf("Set db.storage_format = %q before starting the indexer.\n", target)
+	}
+	return nil
+}
+
+func printStorageFormats(db *gorm.DB) error {
+	formats, err := database.TableStorageFormats(db)
+	if err != nil {
+		return err
+	}
+
+	tables := make([]string, 0, len(formats))
+	for table := range formats {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		fmt.Printf("  %s: %s\n", table, formats[table])
+	}
+	return nil
+}
@@ -2,13 +2,14 @@ package main
 
 import (
 	"context"
-	"flag"
-	"fmt"
+	"math/big"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/admin"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/boff"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
@@ -17,94 +18,227 @@ import (
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/fsp"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/health"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/tracing"
 
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
 
+const tracingShutdownTimeout = 5 * time.Second
+
 func main() {
-	defer logger.SyncFileLogger()
+	defer logging.Sync()
 
-	if err := run(context.Background()); err != nil {
-		logger.Fatalf("Fatal error: %s", err)
+	if err := dispatch(context.Background(), os.Args[1:]); err != nil {
+		logging.For(logging.Main).Fatalw("Fatal error", "error", err)
 	}
 }
 
 func run(ctx context.Context) error {
-	flag.Parse()
-	cfg, err := config.BuildConfig()
+	cfg, err := loadConfig()
 	if err != nil {
-		// The logger is not initialized yet so fallback to directly
-		// printing to stdout.
-		fmt.Println("Error building config: ", err)
 		return err
 	}
 
-	config.GlobalConfigCallback.Call(cfg)
+	if *planFlag {
+		return runPlan(ctx, cfg)
+	}
 
-	// Sync logger when docker container stops or Ctrl+C is pressed
+	stopTracing, err := startTracing(ctx, cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	defer stopTracing()
+
+	// Sync logger and flush traces when docker container stops or Ctrl+C is
+	// pressed
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGTERM, syscall.SIGINT)
 	go func() {
 		sig := <-signalChan
-		logger.Infof("Received signal: %v", sig)
-		logger.SyncFileLogger()
+		logging.From(ctx, logging.Main).Infof("Received signal: %v", sig)
+		stopTracing()
+		logging.Sync()
 		os.Exit(0)
 	}()
 
-	nodeURL, err := cfg.Chain.FullNodeURL()
-	if err != nil {
-		return errors.Wrap(err, "Invalid node URL in config")
+	if !cfg.IsMultiChain() {
+		indexer, err := setupChain(ctx, cfg)
+		if err != nil {
+			return err
+		}
+		ctx = admin.WithControls(ctx, admin.NewControls(indexer.ethClient))
+		health.Handle("/status", indexer.reporter(ctx).Handler())
+		indexer.handleAdmin(ctx, "")
+		health.Start(cfg.Health)
+
+		return indexer.run(ctx)
 	}
 
-	ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RpcConcurrency)
+	return runChains(ctx, cfg.Health, cfg.ChainConfigs())
+}
+
+// startTracing starts exporting traces if tracing.endpoint is set and
+// returns the func flushing the spans still buffered.
+func startTracing(ctx context.Context, cfg config.TracingConfig) (func(), error) {
+	shutdown, err := tracing.Setup(ctx, tracing.Config{
+		Endpoint:    cfg.Endpoint,
+		Insecure:    cfg.Insecure,
+		SampleRatio: cfg.SampleRatio,
+		ServiceName: cfg.ServiceName,
+	})
 	if err != nil {
-		return errors.Wrap(err, "Could not connect to the RPC nodes")
+		return nil, err
+	}
+	if cfg.Endpoint != "" {
+		logging.From(ctx, logging.Main).Infow("Exporting traces", "endpoint", cfg.Endpoint, "sample_ratio", cfg.SampleRatio)
 	}
 
-	resolver, err := contracts.NewContractResolver(ethClient)
-	if err != nil {
-		return errors.Wrap(err, "Failed to initialize contract registry resolver")
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logging.From(ctx, logging.Main).Warnw("Failed to flush traces", "error", err)
+		}
+	}, nil
+}
+
+// chainIndexer is the indexer of one chain, connected and with its database
+// initialized.
+type chainIndexer struct {
+	cfg       *config.Config
+	db        *gorm.DB
+	ethClient *chain.Client
+	resolver  *contracts.ContractResolver
+	chainID   *big.Int
+}
+
+// runChains runs one indexer per [[chains]] entry. Each runs under a context
+// carrying its own startup progress, ready flag and admin controls, served on
+// /chains/<name>/status, /health, /livez, /readyz and /admin/, and a "chain"
+// log field; the top-level probes pass once they pass for every chain. All
+// chains are set up before the health server starts, and the first chain to
+// fail stops the process.
+func runChains(ctx context.Context, healthCfg config.HealthConfig, cfgs []*config.Config) error {
+	indexers := make([]*chainIndexer, len(cfgs))
+	chainCtxs := make([]context.Context, len(cfgs))
+	for i, cfg := range cfgs {
+		synced := new(ready.Flag)
+		chainCtx := status.WithProgress(ready.WithFlag(ctx, synced), new(status.Progress))
+		chainCtx = logging.WithFields(chainCtx, "chain", cfg.Chain.Name)
+
+		logging.From(chainCtx, logging.Main).Infow(
+			"Setting up chain",
+			"database", cfg.DB.Database,
+			"table_prefix", cfg.DB.TablePrefix,
+		)
+		indexer, err := setupChain(chainCtx, cfg)
+		if err != nil {
+			return errors.Wrapf(err, "chain %s", cfg.Chain.Name)
+		}
+		chainCtx = admin.WithControls(chainCtx, admin.NewControls(indexer.ethClient))
+
+		prefix := "/chains/" + cfg.Chain.Name
+		health.Handle(prefix+"/status", indexer.reporter(chainCtx).Handler())
+		indexer.handleAdmin(chainCtx, prefix)
+		health.Handle(prefix+"/health", health.SyncedHandler(synced))
+		health.Handle(prefix+"/livez", health.LivenessHandler(synced))
+		health.Handle(prefix+"/readyz", health.ReadinessHandler(synced))
+		health.Track(synced)
+
+		indexers[i], chainCtxs[i] = indexer, chainCtx
 	}
+	health.Start(healthCfg)
 
-	if err := config.ResolveContractAddresses(ctx, cfg, resolver); err != nil {
-		return errors.Wrap(err, "Failed to resolve configured contract addresses")
+	errs := make(chan error, len(indexers))
+	for i, indexer := range indexers {
+		go func() {
+			errs <- errors.Wrapf(indexer.run(chainCtxs[i]), "chain %s", indexer.cfg.Chain.Name)
+		}()
+	}
+	for range indexers {
+		if err := <-errs; err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// setupChain connects to the node and the database of one chain.
+func setupChain(ctx context.Context, cfg *config.Config) (*chainIndexer, error) {
+	status.SetPhase(ctx, status.PhaseStarting)
 
-	db, err := database.ConnectAndInitialize(ctx, &cfg.DB)
+	ethClient, resolver, chainID, err := connectChain(ctx, cfg)
 	if err != nil {
-		return errors.Wrap(err, "Database connect and initialize errors")
+		return nil, err
 	}
 
-	ready.SetSynced(false)
-	health.Start()
+	logging.From(ctx, logging.Main).Infof("Connected to chain ID %s", chainID)
+
+	identity, err := chainIdentity(ctx, cfg, ethClient, chainID)
+	if err != nil {
+		return nil, err
+	}
 
-	chainID, err := ethClient.ChainID(ctx)
+	db, err := database.ConnectAndInitialize(ctx, &cfg.DB, identity)
 	if err != nil {
-		return errors.Wrap(err, "failed to get chain ID")
+		return nil, errors.Wrap(err, "Database connect and initialize errors")
+	}
+
+	if cfg.DB.PartitioningEnabled() {
+		go database.MaintainPartitions(ctx, db, &cfg.DB)
 	}
 
-	logger.Infof("Connected to chain ID %s", chainID)
+	ready.SetSynced(ctx, false)
+
+	return &chainIndexer{cfg: cfg, db: db, ethClient: ethClient, resolver: resolver, chainID: chainID}, nil
+}
+
+func (c *chainIndexer) reporter(ctx context.Context) *status.Reporter {
+	return status.NewReporter(
+		c.cfg,
+		c.db,
+		c.ethClient,
+		status.ProgressFrom(ctx),
+		fspStatusInfo(c.cfg, c.ethClient, c.resolver),
+		admin.From(ctx),
+	)
+}
+
+// handleAdmin serves the admin API of the indexer running under ctx on
+// prefix+"/admin/" when admin.token is set.
+func (c *chainIndexer) handleAdmin(ctx context.Context, prefix string) {
+	if !c.cfg.Admin.Enabled() {
+		return
+	}
+	path := prefix + "/admin"
+	health.Handle(path+"/", http.StripPrefix(path, admin.Handler(ctx, admin.From(ctx), c.cfg.Admin.Token)))
+	logging.From(ctx, logging.Main).Infow("Admin API enabled", "path", path+"/")
+}
+
+func (c *chainIndexer) run(ctx context.Context) error {
+	cfg, db, ethClient := c.cfg, c.db, c.ethClient
 
 	if cfg.Indexer.IsFspMode() {
-		return fsp.RunIndexer(ctx, cfg, db, ethClient, resolver)
+		return fsp.RunIndexer(ctx, cfg, db, ethClient, c.resolver)
 	}
 
-	historyDrop, err := cfg.DB.GetHistoryDrop(ctx, chainID)
+	historyDrop, err := cfg.DB.GetHistoryDrop(ctx, c.chainID)
 	if err != nil {
 		return errors.Wrap(err, "Failed to get history drop configuration")
 	}
 
 	historyDropDays := (float64(historyDrop) * float64(time.Second)) / float64(24*time.Hour)
 	if cfg.DB.HistoryDrop == nil {
-		logger.Infof("Using default history drop value of %.1f days", historyDropDays)
+		logging.From(ctx, logging.Main).Infof("Using default history drop value of %.1f days", historyDropDays)
 	} else {
 		if *cfg.DB.HistoryDrop == 0 {
-			logger.Infof("History drop disabled")
+			logging.From(ctx, logging.Main).Infof("History drop disabled")
 		} else {
-			logger.Infof("Using configured history drop value of %.1f days", historyDropDays)
+			logging.From(ctx, logging.Main).Infof("Using configured history drop value of %.1f days", historyDropDays)
 		}
 	}
 
@@ -115,7 +249,7 @@ func run(ctx context.Context) error {
 
 	cfg.Indexer.StartIndex = startIndex
 
-	return runIndexer(ctx, cfg, db, ethClient, resolver, historyDrop)
+	return runIndexer(ctx, cfg, db, ethClient, c.resolver, historyDrop)
 }
 
 func getStartIndex(
@@ -125,24 +259,42 @@ func getStartIndex(
 	cfg *config.Config,
 	historyDrop uint64,
 ) (uint64, error) {
+	latestIndexed, ok, err := latestIndexedBlock(db)
+	if err != nil {
+		return 0, err
+	}
+	if ok {
+		logging.From(ctx, logging.Main).Infof("Starting after latest indexed block from DB: %d", latestIndexed)
+		return latestIndexed + 1, nil
+	}
+
+	return emptyDBStartIndex(ctx, ethClient, cfg, historyDrop)
+}
+
+// latestIndexedBlock returns the highest block number in the blocks table;
+// ok is false when no blocks are indexed yet.
+func latestIndexedBlock(db *gorm.DB) (uint64, bool, error) {
 	var latestIndexedBlock database.Block
 	err := db.Last(&database.Block{}).Select("number").Scan(&latestIndexedBlock).Error
-
-	// If a latest indexed block is found, return the next block number
-	if err == nil {
-		logger.Infof("Starting after latest indexed block from DB: %d", latestIndexedBlock.Number)
-		return latestIndexedBlock.Number + 1, nil
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
 	}
-
-	// In case of an unexpected error, return it
-	if !errors.Is(err, gorm.ErrRecordNotFound) {
-		return 0, errors.Wrap(err, "DB query error")
+	if err != nil {
+		return 0, false, errors.Wrap(err, "DB query error")
 	}
+	return latestIndexedBlock.Number, true, nil
+}
 
-	// No blocks are indexed yet
+// emptyDBStartIndex is the start index when no blocks are indexed yet.
+func emptyDBStartIndex(
+	ctx context.Context,
+	ethClient *chain.Client,
+	cfg *config.Config,
+	historyDrop uint64,
+) (uint64, error) {
 	// If history drop is disabled, return the configured start index
 	if historyDrop == 0 {
-		logger.Infof("No indexed blocks found in DB, starting from configured start index: %d", cfg.Indexer.StartIndex)
+		logging.From(ctx, logging.Main).Infof("No indexed blocks found in DB, starting from configured start index: %d", cfg.Indexer.StartIndex)
 		return cfg.Indexer.StartIndex, nil
 	}
 
@@ -160,7 +312,7 @@ func getStartIndex(
 		return 0, errors.Wrap(err, "GetStartBlock error")
 	}
 
-	logger.Infof("No indexed blocks found in DB, starting from calculated start index based on history drop: %d", firstBlockNumber)
+	logging.From(ctx, logging.Main).Infof("No indexed blocks found in DB, starting from calculated start index based on history drop: %d", firstBlockNumber)
 	return firstBlockNumber, nil
 }
 
@@ -176,8 +328,20 @@ func runIndexer(
 	if err != nil {
 		return err
 	}
+	go cIndexer.WatchContractRegistry(ctx)
+
+	err = boff.RetryNoReturn(
+		ctx,
+		func() error {
+			return cIndexer.SyncContractDeployments(ctx, cfg.Indexer.StartIndex)
+		},
+		"SyncContractDeployments",
+	)
+	if err != nil {
+		return errors.Wrap(err, "Contract deployments sync fatal error")
+	}
 
-	historyLastIndex, err := boff.Retry(
+	historyLastIndex, err := boff.RetryAlways(
 		ctx,
 		func() (uint64, error) {
 			return cIndexer.IndexHistory(ctx, cfg.Indexer.StartIndex)
@@ -197,9 +361,10 @@ func runIndexer(
 		)
 	}
 
-	ready.SetSynced(true)
+	ready.SetSynced(ctx, true)
+	status.SetPhase(ctx, status.PhaseContinuous)
 
-	err = boff.RetryNoReturn(
+	err = boff.RetryAlwaysNoReturn(
 		ctx,
 		func() error {
 			// Re-read progress each attempt so a retry resumes from the
@@ -216,7 +381,7 @@ func runIndexer(
 		return errors.Wrap(err, "Index continuous fatal error")
 	}
 
-	logger.Infof("Finished indexing")
+	logging.From(ctx, logging.Main).Infof("Finished indexing")
 
 	return nil
 }
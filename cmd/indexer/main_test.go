@@ -157,7 +157,7 @@ func (suite *IntegrationIndex) prepareSuite(ctx context.Context, isHistory bool)
 
 	suite.cfg = initConfig(tCfg, isHistory)
 
-	suite.db, err = database.ConnectAndInitialize(ctx, &suite.cfg.DB)
+	suite.db, err = database.ConnectAndInitialize(ctx, &suite.cfg.DB, nil)
 	if err != nil {
 		return errors.Wrap(err, "Could not connect to the database")
 	}
@@ -263,7 +263,7 @@ func createIndexer(cfg *config.Config, db *gorm.DB) (*core.Engine, error) {
 		return nil, errors.Wrap(err, "Invalid node URL in config")
 	}
 
-	ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RpcConcurrency)
+	ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RPCLimits())
 	if err != nil {
 		return nil, errors.Wrap(err, "Could not connect to the RPC nodes")
 	}
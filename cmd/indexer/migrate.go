@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/pkg/errors"
+)
+
+var migrateDryRun *bool
+
+func migrateFlags(fs *flag.FlagSet) {
+	migrateDryRun = fs.Bool("dry-run", false, "migrate: print the pending migrations without applying them")
+}
+
+// runMigrate applies pending schema migrations (`migrate` or `migrate up`) or
+// prints the schema version and pending migrations (`migrate status`). The
+// indexer also applies pending migrations at startup; running them ahead of
+// time keeps long migrations out of the deployment's startup window.
+func runMigrate(ctx context.Context, args []string) error {
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	if action != "up" && action != "status" {
+		return errors.Errorf("unknown migrate action %q: must be \"up\" or \"status\"", action)
+	}
+
+	cfg, err := loadChainConfig()
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Connect(ctx, &cfg.DB)
+	if err != nil {
+		return errors.Wrap(err, "Database connect error")
+	}
+
+	status, err := database.GetMigrationStatus(db)
+	if err != nil {
+		return err
+	}
+
+	printMigrationStatus(status)
+	if action == "status" || *migrateDryRun || len(status.Pending) == 0 {
+		return nil
+	}
+
+	if err := database.Migrate(ctx, db); err != nil {
+		return err
+	}
+	fmt.Printf("Migrated to schema version %d\n", status.Latest)
+	return nil
+}
+
+func printMigrationStatus(status *database.MigrationStatus) {
+	fmt.Printf("Schema version: database=%d, indexer=%d\n", status.Current, status.Latest)
+	if status.TooNew() {
+		fmt.Println("The database was migrated by a newer indexer; this binary will refuse to start against it.")
+		return
+	}
+
+	for _, m := range status.Applied {
+		fmt.Printf("  applied %d: %s (%s)\n", m.Version, m.Name, m.AppliedAt.Format("2006-01-02 15:04:05"))
+	}
+	if len(status.Pending) == 0 {
+		fmt.Println("No pending migrations.")
+		return
+	}
+	fmt.Println("Pending migrations:")
+	for _, m := range status.Pending {
+		fmt.Printf("  %s\n", m.Describe())
+	}
+}
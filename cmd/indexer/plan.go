@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/fsp"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var planFlag = flag.Bool("plan", false, "print the startup plan (ranges, block counts, RPC estimate, retention boundary) and exit without writing")
+
+// runPlan runs the startup computations of the configured mode read-only
+// against the node and the database and prints what startup would do, for
+// every [[chains]] entry unless --chain selects one. It never migrates or
+// writes: a database that has not been created yet reads as empty.
+func runPlan(ctx context.Context, cfg *config.Config) error {
+	cfgs := cfg.ChainConfigs()
+	if *chainFlag != "" {
+		chainCfg, err := cfg.ChainConfig(*chainFlag)
+		if err != nil {
+			return err
+		}
+		cfgs = []*config.Config{chainCfg}
+	}
+
+	for _, chainCfg := range cfgs {
+		if chainCfg.Chain.Name != "" {
+			fmt.Printf("Chain %s\n", chainCfg.Chain.Name)
+		}
+		if err := planChain(ctx, chainCfg); err != nil {
+			if chainCfg.Chain.Name != "" {
+				return errors.Wrapf(err, "chain %s", chainCfg.Chain.Name)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func planChain(ctx context.Context, cfg *config.Config) error {
+	ethClient, resolver, _, err := connectChain(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Connect(ctx, &cfg.DB)
+	if err != nil {
+		return errors.Wrap(err, "Database connect error")
+	}
+
+	migration, err := database.GetMigrationStatus(db)
+	if err != nil {
+		return err
+	}
+	if migration.TooNew() {
+		return errors.Wrapf(database.ErrSchemaTooNew, "database at version %d, indexer supports up to %d", migration.Current, migration.Latest)
+	}
+	hasTables := db.Migrator().HasTable(&database.State{}) && db.Migrator().HasTable(&database.Block{})
+
+	states := map[database.StateName]database.State{}
+	if hasTables {
+		states, err = database.GetStates(db, database.AllStateNames...)
+		if err != nil {
+			return errors.Wrap(err, "database.GetStates")
+		}
+	}
+
+	engine, err := core.NewEngine(cfg, db, ethClient, resolver)
+	if err != nil {
+		return err
+	}
+
+	var plan *core.StartupPlan
+	if cfg.Indexer.IsFspMode() {
+		plan, err = fsp.Plan(ctx, engine, states)
+	} else {
+		plan, err = planFull(ctx, cfg, db, engine, states, hasTables)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(migration.Pending) > 0 {
+		plan.Notef("startup would first apply %d pending schema migration(s)", len(migration.Pending))
+	}
+	if hasTables {
+		if err := noteStorageFormat(db, cfg, plan); err != nil {
+			return err
+		}
+	}
+
+	plan.Print(os.Stdout)
+	return nil
+}
+
+// planFull mirrors run for full mode: getStartIndex with history_drop, the
+// IndexHistory range, and the first TipAgeBoundary.
+func planFull(
+	ctx context.Context,
+	cfg *config.Config,
+	db *gorm.DB,
+	engine *core.Engine,
+	states map[database.StateName]database.State,
+	hasTables bool,
+) (*core.StartupPlan, error) {
+	ethClient := engine.Client()
+
+	chainID, err := ethClient.ChainID(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get chain ID")
+	}
+	historyDrop, err := cfg.DB.GetHistoryDrop(ctx, chainID)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get history drop configuration")
+	}
+
+	latestConfirmed, latestConfirmedTimestamp, err := engine.FetchLastBlockIndex(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := &core.StartupPlan{
+		Mode:                     config.IndexerModeFull,
+		LatestConfirmed:          latestConfirmed,
+		LatestConfirmedTimestamp: latestConfirmedTimestamp,
+		RetentionEnabled:         historyDrop > 0,
+	}
+
+	var startIndex uint64
+	latestIndexed, ok := uint64(0), false
+	if hasTables {
+		latestIndexed, ok, err = latestIndexedBlock(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ok {
+		startIndex = latestIndexed + 1
+		plan.Notef("resuming after the latest indexed block %d", latestIndexed)
+	} else {
+		startIndex, err = emptyDBStartIndex(ctx, ethClient, cfg, historyDrop)
+		if err != nil {
+			return nil, err
+		}
+		plan.Notef("no blocks indexed yet: starting at block %d (start_index=%d, history_drop=%ds)",
+			startIndex, cfg.Indexer.StartIndex, historyDrop)
+	}
+
+	rangePlan, historyLastIndex, err := engine.PlanIndexRange(ctx, startIndex)
+	if err != nil {
+		return nil, err
+	}
+	if rangePlan != nil {
+		plan.Ranges = append(plan.Ranges, *rangePlan)
+	}
+	plan.ContinuousFrom = database.ResumeIndex(historyLastIndex, states[database.LastIndexed])
+
+	if historyDrop > 0 {
+		plan.RetentionBoundary, err = database.TipAgeBoundary(ethClient, historyDrop)(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+func noteStorageFormat(db *gorm.DB, cfg *config.Config, plan *core.StartupPlan) error {
+	formats, err := database.TableStorageFormats(db)
+	if err != nil {
+		return err
+	}
+	for table, format := range formats {
+		if format != cfg.DB.StorageFormat {
+			plan.Notef(
+				"table %s is in %s storage but db.storage_format is %q: startup converts it if empty and refuses otherwise",
+				table, format, cfg.DB.StorageFormat,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/snapshot"
+
+	"github.com/pkg/errors"
+)
+
+var snapshotAllowConfigChange *bool
+
+func snapshotFlags(fs *flag.FlagSet) {
+	snapshotAllowConfigChange = fs.Bool(
+		"allow-config-change", false,
+		"snapshot restore: restore a snapshot taken with different collect_transactions/collect_logs",
+	)
+}
+
+// runSnapshot creates (`snapshot create FILE`) or restores
+// (`snapshot restore FILE`) a compressed archive of the database. Create is
+// safe next to a running indexer; restore needs an empty database, after
+// which the indexer resumes from the snapshot's last_database_block.
+func runSnapshot(ctx context.Context, args []string) error {
+	if len(args) != 2 || (args[0] != "create" && args[0] != "restore") {
+		return errors.New("usage: snapshot create|restore FILE")
+	}
+	action, path := args[0], args[1]
+
+	cfg, err := loadChainConfig()
+	if err != nil {
+		return err
+	}
+
+	// The hash covers the filters as configured, so compute it before
+	// anything resolves contract names in place.
+	meta := snapshot.Meta{ConfigHash: cfg.CollectionHash(), Mode: cfg.Indexer.Mode}
+	identity, err := nodeIdentity(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	meta.ChainID = strconv.FormatUint(identity.Metadata.ChainID, 10)
+
+	if action == "create" {
+		return createSnapshot(ctx, cfg, meta, path)
+	}
+	return restoreSnapshot(ctx, cfg, identity, meta, path)
+}
+
+// nodeIdentity is the identity of the chain the configured node serves.
+func nodeIdentity(ctx context.Context, cfg *config.Config) (*database.ChainIdentity, error) {
+	nodeURL, err := cfg.Chain.FullNodeURL()
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid node URL in config")
+	}
+
+	ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RPCLimits())
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not connect to the RPC nodes")
+	}
+
+	chainID, err := ethClient.ChainID(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get chain ID")
+	}
+	if err := cfg.Chain.CheckChainID(chainID); err != nil {
+		return nil, err
+	}
+	return chainIdentity(ctx, cfg, ethClient, chainID)
+}
+
+func createSnapshot(ctx context.Context, cfg *config.Config, meta snapshot.Meta, path string) error {
+	db, err := database.Connect(ctx, &cfg.DB)
+	if err != nil {
+		return errors.Wrap(err, "Database connect error")
+	}
+
+	// Write next to the target and rename, so a file under the final name
+	// is always a complete archive.
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "create snapshot file")
+	}
+	defer os.Remove(tmp.Name())
+
+	trailer, err := snapshot.Create(ctx, db, tmp, meta)
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "close snapshot file")
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return errors.Wrap(err, "rename snapshot file")
+	}
+
+	fmt.Printf(
+		"Snapshot written to %s: blocks=%d, transactions=%d, logs=%d\n",
+		path, trailer.Rows["blocks"], trailer.Rows["transactions"], trailer.Rows["logs"],
+	)
+	return nil
+}
+
+// restoreSnapshot refuses a database already recorded for another chain
+// before any table is truncated.
+func restoreSnapshot(
+	ctx context.Context, cfg *config.Config, identity *database.ChainIdentity, meta snapshot.Meta, path string,
+) error {
+	db, err := database.ConnectAndInitialize(ctx, &cfg.DB, identity)
+	if err != nil {
+		return errors.Wrap(err, "Database connect error")
+	}
+
+	open := func() (io.ReadCloser, error) { return os.Open(path) }
+	header, err := snapshot.Restore(ctx, db, open, meta, snapshot.RestoreOptions{
+		AllowConfigChange: *snapshotAllowConfigChange,
+	})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Restored snapshot of chain ID %s taken at %s\n", header.ChainID, header.CreatedAt.Format("2006-01-02 15:04:05"))
+	return nil
+}
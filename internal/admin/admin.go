@@ -0,0 +1,211 @@
+// Package admin lets operators control a running indexer without restarting
+// it: pause and resume continuous indexing, run a history drop now, and
+// change the history drop interval, the RPC concurrency and the log levels.
+// Every action is logged and kept in a short audit trail reported on the
+// status endpoint.
+package admin
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
+)
+
+// maxActions is the number of admin actions kept for the status endpoint.
+const maxActions = 50
+
+// pausePollInterval is how often a paused indexing loop checks whether it
+// was resumed, keeping its liveness heartbeat going meanwhile.
+const pausePollInterval = time.Second
+
+// Controls are the runtime knobs of one indexer. Like ready.Flag they travel
+// in the context (WithControls); without any, the process-wide controls are
+// used.
+type Controls struct {
+	client *chain.Client
+
+	paused atomic.Bool
+
+	// dropNow holds at most one pending history drop request.
+	dropNow chan struct{}
+
+	mu sync.Mutex
+	// historyDropInterval is zero while history drop is not running.
+	historyDropInterval time.Duration
+	// intervalChanged is closed and replaced when the interval changes, to
+	// wake a history drop waiting on the old one.
+	intervalChanged chan struct{}
+	actions         []Action
+}
+
+// Action is one admin action in the audit trail.
+type Action struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	Detail string    `json:"detail,omitempty"`
+	Remote string    `json:"remote,omitempty"`
+}
+
+// State is the admin section of the status report.
+type State struct {
+	Paused                     bool              `json:"paused"`
+	HistoryDropIntervalSeconds uint64            `json:"history_drop_interval_seconds,omitempty"`
+	RPCConcurrency             int               `json:"rpc_concurrency,omitempty"`
+	RPCInFlight                int               `json:"rpc_in_flight"`
+	LogLevels                  map[string]string `json:"log_levels"`
+	Actions                    []Action          `json:"actions"`
+}
+
+// NewControls builds the controls of the indexer using client; client may be
+// nil, in which case the RPC concurrency cannot be changed.
+func NewControls(client *chain.Client) *Controls {
+	return &Controls{
+		client:          client,
+		dropNow:         make(chan struct{}, 1),
+		intervalChanged: make(chan struct{}),
+	}
+}
+
+var processControls = NewControls(nil)
+
+type controlsKey struct{}
+
+// WithControls returns a context whose indexer is controlled by c.
+func WithControls(ctx context.Context, c *Controls) context.Context {
+	return context.WithValue(ctx, controlsKey{}, c)
+}
+
+// From returns the controls of the indexer running under ctx.
+func From(ctx context.Context) *Controls {
+	if c, ok := ctx.Value(controlsKey{}).(*Controls); ok {
+		return c
+	}
+	return processControls
+}
+
+// WaitWhilePaused blocks while continuous indexing of the indexer running
+// under ctx is paused. block is the next block to index, for the logs.
+func WaitWhilePaused(ctx context.Context, block uint64) error {
+	c := From(ctx)
+	if !c.paused.Load() {
+		return nil
+	}
+
+	logging.From(ctx, logging.Engine).Infow("Continuous indexing paused", "next_block", block)
+	for c.paused.Load() {
+		ready.Heartbeat(ctx)
+		select {
+		case <-time.After(pausePollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	logging.From(ctx, logging.Engine).Infow("Continuous indexing resumed", "next_block", block)
+	return nil
+}
+
+// StartHistoryDrop records that history drop of the indexer running under
+// ctx runs every interval, and returns its controls.
+func StartHistoryDrop(ctx context.Context, interval time.Duration) *Controls {
+	c := From(ctx)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.historyDropInterval = interval
+	return c
+}
+
+// WaitHistoryDrop blocks until the next history drop iteration is due: the
+// interval has passed since last, or an operator asked for one now. A
+// changed interval applies to the wait in progress.
+func (c *Controls) WaitHistoryDrop(ctx context.Context, last time.Time) error {
+	for {
+		c.mu.Lock()
+		interval, changed := c.historyDropInterval, c.intervalChanged
+		c.mu.Unlock()
+
+		timer := time.NewTimer(time.Until(last.Add(interval)))
+		select {
+		case <-timer.C:
+			return nil
+		case <-c.dropNow:
+			timer.Stop()
+			return nil
+		case <-changed:
+			timer.Stop()
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// State returns the current settings and the audit trail, oldest first.
+func (c *Controls) State() *State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	state := &State{
+		Paused:                     c.paused.Load(),
+		HistoryDropIntervalSeconds: uint64(c.historyDropInterval / time.Second),
+		LogLevels:                  make(map[string]string),
+		Actions:                    append([]Action{}, c.actions...),
+	}
+	if c.client != nil {
+		state.RPCConcurrency, state.RPCInFlight = c.client.Concurrency()
+	}
+	for s, level := range logging.Levels() {
+		state.LogLevels[string(s)] = level
+	}
+	return state
+}
+
+func (c *Controls) setPaused(paused bool) {
+	c.paused.Store(paused)
+}
+
+// triggerHistoryDrop requests a history drop now; false means history drop
+// is not running.
+func (c *Controls) triggerHistoryDrop() bool {
+	c.mu.Lock()
+	running := c.historyDropInterval > 0
+	c.mu.Unlock()
+	if !running {
+		return false
+	}
+
+	select {
+	case c.dropNow <- struct{}{}:
+	default: // one is already pending
+	}
+	return true
+}
+
+// setHistoryDropInterval changes the interval; false means history drop is
+// not running.
+func (c *Controls) setHistoryDropInterval(interval time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.historyDropInterval == 0 {
+		return false
+	}
+	c.historyDropInterval = interval
+	close(c.intervalChanged)
+	c.intervalChanged = make(chan struct{})
+	return true
+}
+
+func (c *Controls) record(ctx context.Context, a Action) {
+	logging.From(ctx, logging.Main).Infow("Admin action", "action", a.Action, "detail", a.Detail, "remote", a.Remote)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = append(c.actions, a)
+	if len(c.actions) > maxActions {
+		c.actions = c.actions[len(c.actions)-maxActions:]
+	}
+}
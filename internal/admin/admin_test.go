@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testToken = "secret"
+
+func post(t *testing.T, h http.Handler, target, token string) (int, *State) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec.Code, nil
+	}
+
+	state := new(State)
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(state))
+	return rec.Code, state
+}
+
+func TestHandlerRequiresToken(t *testing.T) {
+	c := NewControls(nil)
+	h := Handler(context.Background(), c, testToken)
+
+	code, _ := post(t, h, "/pause", "")
+	require.Equal(t, http.StatusUnauthorized, code)
+	code, _ = post(t, h, "/pause", "wrong")
+	require.Equal(t, http.StatusUnauthorized, code)
+	require.False(t, c.State().Paused)
+	require.Empty(t, c.State().Actions)
+}
+
+func TestPauseAndResume(t *testing.T) {
+	c := NewControls(nil)
+	ctx := WithControls(context.Background(), c)
+	h := Handler(ctx, c, testToken)
+
+	code, state := post(t, h, "/pause", testToken)
+	require.Equal(t, http.StatusOK, code)
+	require.True(t, state.Paused)
+
+	waited := make(chan error)
+	go func() { waited <- WaitWhilePaused(ctx, 100) }()
+	select {
+	case <-waited:
+		t.Fatal("expected the indexing loop to stay paused")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	code, state = post(t, h, "/resume", testToken)
+	require.Equal(t, http.StatusOK, code)
+	require.False(t, state.Paused)
+	require.NoError(t, <-waited)
+
+	require.Len(t, state.Actions, 2)
+	require.Equal(t, "pause", state.Actions[0].Action)
+	require.Equal(t, "resume", state.Actions[1].Action)
+}
+
+func TestHistoryDropControls(t *testing.T) {
+	c := NewControls(nil)
+	ctx := WithControls(context.Background(), c)
+	h := Handler(ctx, c, testToken)
+
+	// Nothing to trigger before history drop starts.
+	code, _ := post(t, h, "/history-drop", testToken)
+	require.Equal(t, http.StatusConflict, code)
+
+	StartHistoryDrop(ctx, time.Hour)
+	last := time.Now()
+
+	code, _ = post(t, h, "/history-drop", testToken)
+	require.Equal(t, http.StatusOK, code)
+	require.NoError(t, c.WaitHistoryDrop(ctx, last))
+
+	// A shorter interval applies to the wait in progress.
+	waited := make(chan error)
+	go func() { waited <- c.WaitHistoryDrop(ctx, last) }()
+	code, state := post(t, h, "/history-drop/interval?seconds=1", testToken)
+	require.Equal(t, http.StatusOK, code)
+	require.EqualValues(t, 1, state.HistoryDropIntervalSeconds)
+	select {
+	case err := <-waited:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the new interval to end the wait")
+	}
+
+	code, _ = post(t, h, "/history-drop/interval?seconds=0", testToken)
+	require.Equal(t, http.StatusBadRequest, code)
+}
+
+func TestLogLevel(t *testing.T) {
+	require.NoError(t, logging.Setup(logging.Config{Level: "INFO"}))
+	c := NewControls(nil)
+	h := Handler(context.Background(), c, testToken)
+
+	code, state := post(t, h, "/log-level?subsystem=chain&level=debug", testToken)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "DEBUG", state.LogLevels["chain"])
+	require.Equal(t, "INFO", state.LogLevels["engine"])
+	require.Equal(t, "chain=DEBUG", state.Actions[0].Detail)
+
+	code, _ = post(t, h, "/log-level?subsystem=nope&level=debug", testToken)
+	require.Equal(t, http.StatusBadRequest, code)
+	code, _ = post(t, h, "/log-level", testToken)
+	require.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = post(t, h, "/rpc-concurrency?value=4", testToken)
+	require.Equal(t, http.StatusConflict, code)
+}
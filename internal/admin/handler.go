@@ -0,0 +1,145 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+)
+
+// Handler serves the admin API of the indexer controlled by c. Every request
+// must carry "Authorization: Bearer <token>". ctx supplies the log fields of
+// the indexer, e.g. its chain name. The routes, relative to where the
+// handler is mounted, are:
+//   - POST /pause and /resume stop and restart continuous indexing between
+//     blocks
+//   - POST /history-drop runs a history drop iteration now
+//   - POST /history-drop/interval?seconds=N changes the history drop interval
+//   - POST /rpc-concurrency?value=N changes the cap on simultaneous RPC calls
+//   - POST /log-level?level=L[&subsystem=S] changes the log level of one
+//     subsystem, or of all of them; log levels are process-wide
+//
+// Each returns the resulting State.
+func Handler(ctx context.Context, c *Controls, token string) http.Handler {
+	a := &api{ctx: ctx, c: c}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /pause", a.pause)
+	mux.HandleFunc("POST /resume", a.resume)
+	mux.HandleFunc("POST /history-drop", a.historyDrop)
+	mux.HandleFunc("POST /history-drop/interval", a.historyDropInterval)
+	mux.HandleFunc("POST /rpc-concurrency", a.rpcConcurrency)
+	mux.HandleFunc("POST /log-level", a.logLevel)
+
+	return authenticate(token, mux)
+}
+
+func authenticate(token string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+type api struct {
+	ctx context.Context
+	c   *Controls
+}
+
+func (a *api) pause(w http.ResponseWriter, r *http.Request) {
+	a.c.setPaused(true)
+	a.done(w, r, "pause", "")
+}
+
+func (a *api) resume(w http.ResponseWriter, r *http.Request) {
+	a.c.setPaused(false)
+	a.done(w, r, "resume", "")
+}
+
+func (a *api) historyDrop(w http.ResponseWriter, r *http.Request) {
+	if !a.c.triggerHistoryDrop() {
+		http.Error(w, "history drop is not running", http.StatusConflict)
+		return
+	}
+	a.done(w, r, "history_drop", "")
+}
+
+func (a *api) historyDropInterval(w http.ResponseWriter, r *http.Request) {
+	seconds, ok := positiveParam(w, r, "seconds")
+	if !ok {
+		return
+	}
+	if !a.c.setHistoryDropInterval(time.Duration(seconds) * time.Second) {
+		http.Error(w, "history drop is not running", http.StatusConflict)
+		return
+	}
+	a.done(w, r, "history_drop_interval", fmt.Sprintf("%ds", seconds))
+}
+
+func (a *api) rpcConcurrency(w http.ResponseWriter, r *http.Request) {
+	value, ok := positiveParam(w, r, "value")
+	if !ok {
+		return
+	}
+	if a.c.client == nil {
+		http.Error(w, "no RPC client to resize", http.StatusConflict)
+		return
+	}
+	a.c.client.SetConcurrency(value)
+	a.done(w, r, "rpc_concurrency", strconv.Itoa(value))
+}
+
+func (a *api) logLevel(w http.ResponseWriter, r *http.Request) {
+	level := r.FormValue("level")
+	subsystems := logging.Subsystems
+	if s := r.FormValue("subsystem"); s != "" {
+		subsystems = []logging.Subsystem{logging.Subsystem(s)}
+	}
+	if _, err := logging.ParseLevel(level); level == "" || err != nil {
+		http.Error(w, fmt.Sprintf("invalid level %q", level), http.StatusBadRequest)
+		return
+	}
+	for _, s := range subsystems {
+		if err := logging.SetLevel(s, level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	detail := strings.ToUpper(level)
+	if len(subsystems) == 1 {
+		detail = string(subsystems[0]) + "=" + detail
+	}
+	a.done(w, r, "log_level", detail)
+}
+
+// done records a successful action and replies with the resulting state.
+func (a *api) done(w http.ResponseWriter, r *http.Request, action, detail string) {
+	a.c.record(a.ctx, Action{Time: time.Now().UTC(), Action: action, Detail: detail, Remote: r.RemoteAddr})
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.c.State()); err != nil {
+		logging.From(a.ctx, logging.Main).Warnw("Failed to write admin response", "error", err)
+	}
+}
+
+func positiveParam(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	value, err := strconv.Atoi(r.FormValue(name))
+	if err != nil || value < 1 {
+		http.Error(w, fmt.Sprintf("%s must be a positive integer", name), http.StatusBadRequest)
+		return 0, false
+	}
+	return value, true
+}
@@ -4,20 +4,42 @@ package boff
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/tracing"
 
 	"github.com/cenkalti/backoff/v5"
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// failures counts the failed attempts of every retried operation in the
+// process, by chain.ErrorClass.
+var failures [chain.NeedsSmallerRange + 1]atomic.Uint64
+
+// FailureCounts returns the number of failed attempts so far per error class
+// (retryable, permanent, needs_smaller_range).
+func FailureCounts() map[string]uint64 {
+	counts := make(map[string]uint64, len(failures))
+	for _, class := range chain.ErrorClasses {
+		counts[class.String()] = failures[class].Load()
+	}
+	return counts
+}
+
+// RetryWithMaxElapsed retries operation until it succeeds or
+// config.BackoffMaxElapsedTime has passed. Like Retry, it gives up at once on
+// an error that chain.Classify does not find retryable, such as a bad API key
+// or an eth_getLogs range the node refuses.
 func RetryWithMaxElapsed[T any](ctx context.Context, operation func() (T, error), name string) (T, error) {
-	return retry(ctx, operation, name, config.BackoffMaxElapsedTime)
+	return retry(ctx, operation, name, config.BackoffMaxElapsedTime, false)
 }
 
 func Retry[T any](ctx context.Context, operation func() (T, error), name string) (T, error) {
-	return retry(ctx, operation, name, 0) // 0 means no max elapsed time
+	return retry(ctx, operation, name, 0, false) // 0 means no max elapsed time
 }
 
 func RetryNoReturn(ctx context.Context, operation func() error, name string) error {
@@ -32,20 +54,72 @@ func RetryNoReturn(ctx context.Context, operation func() error, name string) err
 	return err
 }
 
-func retry[T any](ctx context.Context, operation func() (T, error), name string, maxElapsedTime time.Duration) (T, error) {
+// RetryAlways retries operation until it succeeds or ctx is done, backing off
+// on errors of every class. It is meant for the outer indexing loops, which
+// should outlast e.g. a rotated API key or a node briefly refusing a range
+// rather than exit the process.
+func RetryAlways[T any](ctx context.Context, operation func() (T, error), name string) (T, error) {
+	return retry(ctx, operation, name, 0, true)
+}
+
+func RetryAlwaysNoReturn(ctx context.Context, operation func() error, name string) error {
+	_, err := RetryAlways(
+		ctx,
+		func() (struct{}, error) {
+			return struct{}{}, operation()
+		},
+		name,
+	)
+
+	return err
+}
+
+func retry[T any](
+	ctx context.Context, operation func() (T, error), name string, maxElapsedTime time.Duration, always bool,
+) (T, error) {
+	attempt := 0
 	result, err := backoff.Retry(
 		ctx,
-		operation,
+		func() (T, error) {
+			// One span per attempt, so a trace shows the time lost to
+			// failed attempts and the backoff delays between them.
+			attempt++
+			_, span := tracing.Start(ctx, "boff.attempt", attribute.String("op", name), attribute.Int("attempt", attempt))
+			result, err := operation()
+			tracing.End(span, err)
+			if err == nil || ctx.Err() != nil {
+				return result, err
+			}
+
+			class := chain.Classify(err)
+			failures[class].Add(1)
+			if always {
+				if class != chain.Retryable {
+					logging.From(ctx, logging.Main).Errorw("Retrying non-retryable error", "op", name, "class", class.String(), "error", err)
+				}
+				return result, err
+			}
+			switch class {
+			case chain.Permanent:
+				logging.From(ctx, logging.Main).Errorw("Not retrying permanent error", "op", name, "error", err)
+				return result, backoff.Permanent(err)
+			case chain.NeedsSmallerRange:
+				// Left to the caller, which can split the range.
+				logging.From(ctx, logging.Main).Debugw("Not retrying, range too large", "op", name, "error", err)
+				return result, backoff.Permanent(err)
+			}
+			return result, err
+		},
 		backoff.WithBackOff(backoff.NewExponentialBackOff()),
 		backoff.WithMaxElapsedTime(maxElapsedTime),
 		backoff.WithNotify(
 			func(err error, d time.Duration) {
-				logger.Debugf("Backoff retry: op=%s, error=%s, delay=%v", name, err, d)
+				logging.From(ctx, logging.Main).Debugw("Backoff retry", "op", name, "error", err, "delay", d)
 			},
 		),
 	)
-	if err != nil && ctx.Err() == nil {
-		logger.Warnf("Backoff exhausted: op=%s, error=%s", name, err)
+	if err != nil && ctx.Err() == nil && chain.Classify(err) == chain.Retryable {
+		logging.From(ctx, logging.Main).Warnw("Backoff exhausted", "op", name, "error", err)
 	}
 	return result, err
 }
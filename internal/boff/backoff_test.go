@@ -0,0 +1,75 @@
+package boff
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+)
+
+func TestRetryStopsOnPermanentError(t *testing.T) {
+	before := FailureCounts()
+
+	attempts := 0
+	permanent := &chain.Error{Method: "eth_getLogs", Class: chain.Permanent, Err: errors.New("401 Unauthorized")}
+	_, err := RetryWithMaxElapsed(context.Background(), func() (int, error) {
+		attempts++
+		return 0, permanent
+	}, "test")
+
+	if !errors.Is(err, permanent) {
+		t.Fatalf("expected the permanent error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", attempts)
+	}
+	if got := FailureCounts()["permanent"] - before["permanent"]; got != 1 {
+		t.Fatalf("expected 1 more permanent failure, got %d", got)
+	}
+}
+
+func TestRetryRetriesTransientError(t *testing.T) {
+	before := FailureCounts()
+
+	attempts := 0
+	result, err := Retry(context.Background(), func() (int, error) {
+		attempts++
+		if attempts < 3 {
+			return 0, errors.New("connection reset by peer")
+		}
+		return 42, nil
+	}, "test")
+
+	if err != nil || result != 42 {
+		t.Fatalf("expected 42, got %d, %v", result, err)
+	}
+	if got := FailureCounts()["retryable"] - before["retryable"]; got != 2 {
+		t.Fatalf("expected 2 more retryable failures, got %d", got)
+	}
+}
+
+func TestRetryAlwaysRetriesEveryClass(t *testing.T) {
+	before := FailureCounts()
+
+	attempts := 0
+	errs := []error{
+		&chain.Error{Method: "eth_getBlockByNumber", Class: chain.Permanent, Err: errors.New("401 Unauthorized")},
+		&chain.Error{Method: "eth_getLogs", Class: chain.NeedsSmallerRange, Err: errors.New("block range too large")},
+	}
+	result, err := RetryAlways(context.Background(), func() (int, error) {
+		attempts++
+		if attempts <= len(errs) {
+			return 0, errs[attempts-1]
+		}
+		return 42, nil
+	}, "test")
+
+	if err != nil || result != 42 {
+		t.Fatalf("expected 42, got %d, %v", result, err)
+	}
+	after := FailureCounts()
+	if after["permanent"]-before["permanent"] != 1 || after["needs_smaller_range"]-before["needs_smaller_range"] != 1 {
+		t.Fatalf("expected 1 more permanent and needs_smaller_range failure, got %v -> %v", before, after)
+	}
+}
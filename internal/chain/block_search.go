@@ -3,9 +3,9 @@ package chain
 import (
 	"context"
 	"math"
-	"math/big"
 
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
 	"github.com/pkg/errors"
 )
 
@@ -40,9 +40,11 @@ func nearestBlockByTimestamp(
 	endBlockNumber uint64,
 	blockTime blockTimeLookup,
 ) (uint64, error) {
-	logger.Debugf(
-		"Block search starting: search_timestamp=%d, start_block=%d, end_block=%d",
-		searchTimestamp, startBlockNumber, endBlockNumber,
+	logging.From(ctx, logging.Chain).Debugw(
+		"Block search starting",
+		"search_timestamp", searchTimestamp,
+		"start_block", startBlockNumber,
+		"end_block", endBlockNumber,
 	)
 
 	searchStartBlockNumber := startBlockNumber
@@ -78,7 +80,7 @@ func nearestBlockByTimestamp(
 		// Clamp to endBlockNumber: when the window is floored at genesis the
 		// nominal upper bound can exceed the chain head.
 		searchEndBlockNumber = min(startCandidate+searchWindowBlocks, endBlockNumber)
-		logger.Debugf("Block search window narrowed: from=%d, to=%d", searchStartBlockNumber, searchEndBlockNumber)
+		logging.From(ctx, logging.Chain).Debugw("Block search window narrowed", "from", searchStartBlockNumber, "to", searchEndBlockNumber)
 	}
 
 	blockNumber, err := binarySearchBlockByTimestamp(
@@ -88,7 +90,7 @@ func nearestBlockByTimestamp(
 		return 0, errors.Wrap(err, "GetNearestBlockByTimestampFromChain")
 	}
 
-	logger.Debugf("Block search complete: block=%d", blockNumber)
+	logging.From(ctx, logging.Chain).Debugw("Block search complete", "block", blockNumber)
 	return blockNumber, nil
 }
 
@@ -121,10 +123,5 @@ func binarySearchBlockByTimestamp(
 }
 
 func blockTimestampByNumber(ctx context.Context, client *Client, blockNumber uint64) (uint64, error) {
-	block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(blockNumber))
-	if err != nil {
-		return 0, err
-	}
-
-	return block.Time(), nil
+	return client.BlockTimestamp(ctx, blockNumber)
 }
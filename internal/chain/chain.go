@@ -5,13 +5,22 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"net/http"
 	"net/url"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/tracing"
 
 	avxClient "github.com/ava-labs/coreth/ethclient"
 	"github.com/ava-labs/coreth/interfaces"
+	avxRPC "github.com/ava-labs/coreth/rpc"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	ethClient "github.com/ethereum/go-ethereum/ethclient"
+	ethRPC "github.com/ethereum/go-ethereum/rpc"
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap/zapcore"
 
 	avxTypes "github.com/ava-labs/coreth/core/types"
 	ethTypes "github.com/ethereum/go-ethereum/core/types"
@@ -47,29 +56,91 @@ type Client struct {
 	avx   avxClient.Client
 	// sem caps the number of simultaneous RPC calls across every caller of this
 	// client (catchup, continuous indexing, FSP backfill, start-block search,
-	// contract calls, history drop), making it a true process-wide ceiling. A
-	// nil sem means unlimited.
-	sem chan struct{}
-}
-
-// acquire blocks until an RPC slot is free or ctx is cancelled. A nil sem
-// (client built without a limit) is treated as unlimited.
-func (c *Client) acquire(ctx context.Context) error {
+	// contract calls, history drop), making it a true per-chain ceiling. It
+	// can be resized at runtime with SetConcurrency. A nil sem means
+	// unlimited.
+	sem *semaphore
+	// throttle paces the calls sent to the node; nil means unpaced.
+	throttle *throttle
+	// headers caches headers by number; nil means no caching.
+	headers *headerCache
+	// rpc is the raw client, for calls such as debug_traceBlockByNumber.
+	rpc rpcCaller
+}
+
+// SetConcurrency changes the cap on simultaneous RPC calls (values < 1 are
+// treated as 1). Calls in flight are not interrupted; lowering the cap makes
+// new calls wait until enough of them finished.
+func (c *Client) SetConcurrency(maxConcurrency int) {
 	if c.sem == nil {
-		return nil
-	}
-	select {
-	case c.sem <- struct{}{}:
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
+		return
 	}
+	c.sem.resize(max(maxConcurrency, 1))
 }
 
-func (c *Client) release() {
+// Concurrency returns the cap on simultaneous RPC calls and the number of
+// calls in flight; the cap is 0 for an unlimited client.
+func (c *Client) Concurrency() (limit, inFlight int) {
+	if c.sem == nil {
+		return 0, 0
+	}
+	return c.sem.size()
+}
+
+// acquire blocks until an RPC slot is free and the throttle lets a call of
+// method through, or ctx is cancelled. A nil sem (client built without a
+// limit) is treated as unlimited. It starts the chain.<name> span of the
+// call, with child spans for the waits on the slot and on the throttle. The
+// returned release frees the slot, ends the span and logs the call with its
+// queueing and call times at debug level, tagged with the fields of ctx such
+// as the batch correlation ID. It returns the error of the call classified
+// as an *Error.
+func (c *Client) acquire(ctx context.Context, name, method string) (release func(error) error, err error) {
+	ctx, span := tracing.Start(ctx, "chain."+name, attribute.String("rpc.method", method))
+	start := time.Now()
 	if c.sem != nil {
-		<-c.sem
+		_, wait := tracing.Start(ctx, "chain.semaphoreWait")
+		if err := c.sem.acquire(ctx); err != nil {
+			tracing.End(wait, err)
+			tracing.End(span, err)
+			return nil, err
+		}
+		wait.End()
+	}
+	if c.throttle != nil {
+		_, wait := tracing.Start(ctx, "chain.rateLimitWait")
+		if err := c.throttle.wait(ctx, method); err != nil {
+			if c.sem != nil {
+				c.sem.release()
+			}
+			tracing.End(wait, err)
+			tracing.End(span, err)
+			return nil, err
+		}
+		wait.End()
 	}
+	acquired := time.Now()
+
+	return func(err error) error {
+		if c.sem != nil {
+			c.sem.release()
+		}
+		tracing.End(span, err)
+		err = wrapError(method, err)
+		if !logging.Enabled(logging.Chain, zapcore.DebugLevel) {
+			return err
+		}
+		fields := []interface{}{
+			"method", method,
+			"wait_ms", acquired.Sub(start).Milliseconds(),
+			"duration_ms", time.Since(acquired).Milliseconds(),
+		}
+		if err != nil {
+			fields = append(fields, "error", err)
+		}
+		logging.From(ctx, logging.Chain).Debugw("RPC call", fields...)
+		return err
+	}, nil
 }
 
 type Block struct {
@@ -96,25 +167,38 @@ type Transaction struct {
 	avx   *avxTypes.Transaction
 }
 
-// DialRPCNode connects to the node and caps concurrent RPC calls at
-// maxConcurrency (values < 1 are treated as 1).
-func DialRPCNode(nodeURL *url.URL, chainType ChainType, maxConcurrency int) (*Client, error) {
-	if maxConcurrency < 1 {
-		maxConcurrency = 1
+// DialRPCNode connects to the node and caps the RPC calls sent to it at
+// limits. Over HTTP, a 429 answer pauses every call of the client for the
+// Retry-After the node asks for.
+func DialRPCNode(nodeURL *url.URL, chainType ChainType, limits Limits) (*Client, error) {
+	c := &Client{
+		chain:    chainType,
+		sem:      newSemaphore(max(limits.Concurrency, 1)),
+		throttle: newThrottle(limits),
+		headers:  newHeaderCache(limits.HeaderCacheSize),
 	}
-	c := &Client{chain: chainType, sem: make(chan struct{}, maxConcurrency)}
-	var err error
+	httpClient := &http.Client{Transport: &throttleTransport{base: http.DefaultTransport, throttle: c.throttle}}
 
 	switch c.chain {
 	case ChainTypeAvax:
-		c.avx, err = avxClient.Dial(nodeURL.String())
+		rpcClient, err := avxRPC.DialOptions(context.Background(), nodeURL.String(), avxRPC.WithHTTPClient(httpClient))
+		if err != nil {
+			return nil, err
+		}
+		c.avx = avxClient.NewClient(rpcClient)
+		c.rpc = rpcClient
 	case ChainTypeEth:
-		c.eth, err = ethClient.Dial(nodeURL.String())
+		rpcClient, err := ethRPC.DialOptions(context.Background(), nodeURL.String(), ethRPC.WithHTTPClient(httpClient))
+		if err != nil {
+			return nil, err
+		}
+		c.eth = ethClient.NewClient(rpcClient)
+		c.rpc = rpcClient
 	default:
 		return nil, errors.New("invalid chain")
 	}
 
-	return c, err
+	return c, nil
 }
 
 func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
@@ -128,14 +212,14 @@ func (c *Client) ChainID(ctx context.Context) (*big.Int, error) {
 	}
 }
 
-func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (*Block, error) {
-	if err := c.acquire(ctx); err != nil {
+func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (_ *Block, err error) {
+	release, err := c.acquire(ctx, "BlockByNumber", "eth_getBlockByNumber")
+	if err != nil {
 		return nil, err
 	}
-	defer c.release()
+	defer func() { err = release(err) }()
 
 	block := &Block{chain: c.chain}
-	var err error
 	switch c.chain {
 	case ChainTypeAvax:
 		block.avx, err = c.avx.BlockByNumber(ctx, number)
@@ -144,18 +228,27 @@ func (c *Client) BlockByNumber(ctx context.Context, number *big.Int) (*Block, er
 	default:
 		return nil, errors.New("invalid chain")
 	}
+	if err == nil {
+		c.cacheHeader(number, block.header())
+	}
 
 	return block, err
 }
 
-func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*Header, error) {
-	if err := c.acquire(ctx); err != nil {
+// HeaderByNumber returns the header of a block, from the header cache when
+// looked up by number before.
+func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (_ *Header, err error) {
+	if header, ok := c.cachedHeader(number); ok {
+		return header, nil
+	}
+
+	release, err := c.acquire(ctx, "HeaderByNumber", "eth_getBlockByNumber")
+	if err != nil {
 		return nil, err
 	}
-	defer c.release()
+	defer func() { err = release(err) }()
 
 	block := &Header{chain: c.chain}
-	var err error
 	switch c.chain {
 	case ChainTypeAvax:
 		block.avx, err = c.avx.HeaderByNumber(ctx, number)
@@ -164,18 +257,21 @@ func (c *Client) HeaderByNumber(ctx context.Context, number *big.Int) (*Header,
 	default:
 		return nil, errors.New("invalid chain")
 	}
+	if err == nil {
+		c.cacheHeader(number, block)
+	}
 
 	return block, err
 }
 
-func (c *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*Receipt, error) {
-	if err := c.acquire(ctx); err != nil {
+func (c *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (_ *Receipt, err error) {
+	release, err := c.acquire(ctx, "TransactionReceipt", "eth_getTransactionReceipt")
+	if err != nil {
 		return nil, err
 	}
-	defer c.release()
+	defer func() { err = release(err) }()
 
 	receipt := &Receipt{chain: c.chain}
-	var err error
 	switch c.chain {
 	case ChainTypeAvax:
 		receipt.avx, err = c.avx.TransactionReceipt(ctx, txHash)
@@ -188,11 +284,12 @@ func (c *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*R
 	return receipt, err
 }
 
-func (c *Client) FilterLogs(ctx context.Context, q interfaces.FilterQuery) ([]avxTypes.Log, error) {
-	if err := c.acquire(ctx); err != nil {
+func (c *Client) FilterLogs(ctx context.Context, q interfaces.FilterQuery) (_ []avxTypes.Log, err error) {
+	release, err := c.acquire(ctx, "FilterLogs", methodGetLogs)
+	if err != nil {
 		return nil, err
 	}
-	defer c.release()
+	defer func() { err = release(err) }()
 
 	switch c.chain {
 	case ChainTypeAvax:
@@ -213,11 +310,12 @@ func (c *Client) FilterLogs(ctx context.Context, q interfaces.FilterQuery) ([]av
 	}
 }
 
-func (c *Client) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) ([]byte, error) {
-	if err := c.acquire(ctx); err != nil {
+func (c *Client) CodeAt(ctx context.Context, contract common.Address, blockNumber *big.Int) (_ []byte, err error) {
+	release, err := c.acquire(ctx, "CodeAt", "eth_getCode")
+	if err != nil {
 		return nil, err
 	}
-	defer c.release()
+	defer func() { err = release(err) }()
 
 	switch c.chain {
 	case ChainTypeAvax:
@@ -229,11 +327,12 @@ func (c *Client) CodeAt(ctx context.Context, contract common.Address, blockNumbe
 	}
 }
 
-func (c *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
-	if err := c.acquire(ctx); err != nil {
+func (c *Client) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) (_ []byte, err error) {
+	release, err := c.acquire(ctx, "CallContract", "eth_call")
+	if err != nil {
 		return nil, err
 	}
-	defer c.release()
+	defer func() { err = release(err) }()
 
 	switch c.chain {
 	case ChainTypeAvax:
@@ -289,6 +388,17 @@ func (b *Header) Time() uint64 {
 	}
 }
 
+func (b *Header) Hash() common.Hash {
+	switch b.chain {
+	case ChainTypeAvax:
+		return b.avx.Hash()
+	case ChainTypeEth:
+		return b.eth.Hash()
+	default:
+		return common.Hash{}
+	}
+}
+
 func (b *Block) Number() *big.Int {
 	switch b.chain {
 	case ChainTypeAvax:
@@ -311,6 +421,17 @@ func (b *Block) Time() uint64 {
 	}
 }
 
+func (b *Block) header() *Header {
+	switch b.chain {
+	case ChainTypeAvax:
+		return &Header{chain: b.chain, avx: b.avx.Header()}
+	case ChainTypeEth:
+		return &Header{chain: b.chain, eth: b.eth.Header()}
+	default:
+		return nil
+	}
+}
+
 func (b *Block) Hash() common.Hash {
 	switch b.chain {
 	case ChainTypeAvax:
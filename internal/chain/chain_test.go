@@ -0,0 +1,130 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanNames(spans []sdktrace.ReadOnlySpan) map[string]sdktrace.ReadOnlySpan {
+	byName := make(map[string]sdktrace.ReadOnlySpan, len(spans))
+	for _, s := range spans {
+		byName[s.Name()] = s
+	}
+	return byName
+}
+
+func TestAcquireTracesCallAndSemaphoreWait(t *testing.T) {
+	recorder := recordSpans(t)
+	c := &Client{sem: newSemaphore(1)}
+
+	release, err := c.acquire(context.Background(), "FilterLogs", "eth_getLogs")
+	if err != nil {
+		t.Fatalf("acquire: %s", err)
+	}
+	release(errors.New("query returned more than 10000 results"))
+
+	spans := spanNames(recorder.Ended())
+	call, ok := spans["chain.FilterLogs"]
+	if !ok {
+		t.Fatalf("no chain.FilterLogs span in %v", spans)
+	}
+	if call.Status().Code != codes.Error {
+		t.Fatalf("expected the call span to record the error, got status %v", call.Status())
+	}
+	wait, ok := spans["chain.semaphoreWait"]
+	if !ok {
+		t.Fatalf("no chain.semaphoreWait span in %v", spans)
+	}
+	if wait.Parent().SpanID() != call.SpanContext().SpanID() {
+		t.Fatal("expected the semaphore wait to be a child of the call span")
+	}
+	if _, inFlight := c.Concurrency(); inFlight != 0 {
+		t.Fatal("expected release to free the slot")
+	}
+}
+
+func TestAcquireCancelledWhileWaiting(t *testing.T) {
+	recorder := recordSpans(t)
+	c := &Client{sem: newSemaphore(1)}
+	if err := c.sem.acquire(context.Background()); err != nil {
+		t.Fatalf("acquire: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.acquire(ctx, "BlockByNumber", "eth_getBlockByNumber"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	spans := spanNames(recorder.Ended())
+	for _, name := range []string{"chain.BlockByNumber", "chain.semaphoreWait"} {
+		s, ok := spans[name]
+		if !ok {
+			t.Fatalf("no %s span in %v", name, spans)
+		}
+		if s.Status().Code != codes.Error {
+			t.Fatalf("expected %s to record the cancellation, got status %v", name, s.Status())
+		}
+	}
+}
+
+func TestSetConcurrency(t *testing.T) {
+	c := &Client{sem: newSemaphore(1)}
+	ctx := context.Background()
+
+	first, err := c.acquire(ctx, "BlockByNumber", "eth_getBlockByNumber")
+	if err != nil {
+		t.Fatalf("acquire: %s", err)
+	}
+
+	// A second call waits on the full semaphore until it is grown.
+	acquired := make(chan func(error) error)
+	go func() {
+		release, err := c.acquire(ctx, "BlockByNumber", "eth_getBlockByNumber")
+		if err != nil {
+			t.Errorf("acquire: %s", err)
+		}
+		acquired <- release
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("expected the second call to wait for a slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.SetConcurrency(2)
+	second := <-acquired
+	if limit, inFlight := c.Concurrency(); limit != 2 || inFlight != 2 {
+		t.Fatalf("expected 2 of 2 slots in use, got %d of %d", inFlight, limit)
+	}
+
+	// Shrinking keeps the calls in flight and holds back new ones until
+	// they are below the new cap.
+	c.SetConcurrency(1)
+	first(nil)
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := c.sem.acquire(cancelled); err == nil {
+		t.Fatal("expected no free slot while a call is still over the cap")
+	}
+	second(nil)
+	if limit, inFlight := c.Concurrency(); limit != 1 || inFlight != 0 {
+		t.Fatalf("expected 0 of 1 slots in use, got %d of %d", inFlight, limit)
+	}
+}
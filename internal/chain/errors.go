@@ -0,0 +1,219 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	avxRPC "github.com/ava-labs/coreth/rpc"
+	ethRPC "github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrorClass tells the callers of a Client whether retrying a failed call can
+// help.
+type ErrorClass int
+
+const (
+	// Retryable errors are transient: timeouts, connection failures, 429s,
+	// 5xx answers, and anything not known to be permanent.
+	Retryable ErrorClass = iota
+	// Permanent errors fail the same way on every attempt: bad API key,
+	// unsupported method, invalid params, pruned history.
+	Permanent
+	// NeedsSmallerRange errors are eth_getLogs answers that the block range
+	// or the result set is too large; the same query over a smaller range
+	// can succeed.
+	NeedsSmallerRange
+)
+
+// ErrorClasses lists every class, e.g. for reporting counts per class.
+var ErrorClasses = []ErrorClass{Retryable, Permanent, NeedsSmallerRange}
+
+func (c ErrorClass) String() string {
+	switch c {
+	case Permanent:
+		return "permanent"
+	case NeedsSmallerRange:
+		return "needs_smaller_range"
+	default:
+		return "retryable"
+	}
+}
+
+// JSON-RPC 2.0 error codes that no retry can fix.
+const (
+	rpcParseError     = -32700
+	rpcInvalidRequest = -32600
+	rpcMethodNotFound = -32601
+	rpcInvalidParams  = -32602
+)
+
+// rpcLimitExceeded is the EIP-1474 code several nodes and providers answer
+// an eth_getLogs query over too large a range with.
+const rpcLimitExceeded = -32005
+
+// Node messages of eth_getLogs queries over too large a range or with too
+// many results, across geth, Avalanche and the common hosted providers.
+var rangeMessages = []string{
+	"query returned more than",
+	"more than 10000 results",
+	"block range too large",
+	"range too large",
+	"range is too large",
+	"max block range",
+	"maximum block range",
+	"block range limit",
+	"too many blocks",
+	"response size exceeded",
+	"response size should not greater than",
+	"is limited to a",
+	"query timeout exceeded",
+}
+
+// Node messages of queries for state or history the node no longer keeps.
+var prunedMessages = []string{
+	"missing trie node",
+	"pruned",
+	"historical state",
+	"state not available",
+}
+
+// Error is a failed Client call with its class.
+type Error struct {
+	Method string
+	Class  ErrorClass
+	// Hint says what to do about a non-retryable error, empty otherwise.
+	Hint string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	if e.Hint == "" {
+		return fmt.Sprintf("%s: %s", e.Method, e.Err)
+	}
+	return fmt.Sprintf("%s: %s (%s)", e.Method, e.Err, e.Hint)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Classify returns the class of an error returned by a Client call, or of
+// one wrapping it. An error that did not come from a Client call is never
+// found to need a smaller range.
+func Classify(err error) ErrorClass {
+	var callErr *Error
+	if errors.As(err, &callErr) {
+		return callErr.Class
+	}
+	class, _ := classify("", err)
+	return class
+}
+
+// wrapError attaches the method and class to an error of a call.
+func wrapError(method string, err error) error {
+	if err == nil {
+		return nil
+	}
+	class, hint := classify(method, err)
+	return &Error{Method: method, Class: class, Hint: hint, Err: err}
+}
+
+// classify matches messages only on errors the node answered with a JSON-RPC
+// error code, so that e.g. a reverted eth_call whose reason happens to say
+// "pruned" is not taken for a node problem.
+func classify(method string, err error) (ErrorClass, string) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return Retryable, ""
+	}
+
+	rpcErr, isRPC := rpcError(err)
+	if isRPC && method == "eth_getLogs" {
+		msg := strings.ToLower(rpcErr.Error())
+		if rpcErr.ErrorCode() == rpcLimitExceeded || containsAny(msg, rangeMessages) {
+			return NeedsSmallerRange, "lower indexer.log_range to the node's eth_getLogs limit"
+		}
+	}
+
+	if status, _, ok := httpStatus(err); ok {
+		switch {
+		case status == http.StatusUnauthorized || status == http.StatusForbidden:
+			return Permanent, "the node rejected the request; check chain.api_key"
+		case status == http.StatusNotFound:
+			return Permanent, "no RPC endpoint at this URL; check chain.node_url"
+		case status == http.StatusRequestEntityTooLarge && method == "eth_getLogs":
+			return NeedsSmallerRange, "lower indexer.log_range"
+		}
+	}
+
+	if !isRPC {
+		return Retryable, ""
+	}
+	switch rpcErr.ErrorCode() {
+	case rpcMethodNotFound:
+		return Permanent, "the node does not support this method"
+	case rpcParseError, rpcInvalidRequest, rpcInvalidParams:
+		return Permanent, "the node rejected the request as invalid"
+	}
+	if containsAny(strings.ToLower(rpcErr.Error()), prunedMessages) {
+		return Permanent, "the node no longer keeps this history; use an archive node or a later start block"
+	}
+	return Retryable, ""
+}
+
+// bodyError is a JSON-RPC error carried in the body of a non-2xx HTTP
+// answer, which the RPC clients do not decode.
+type bodyError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *bodyError) Error() string  { return e.Message }
+func (e *bodyError) ErrorCode() int { return e.Code }
+
+// codedError is a JSON-RPC error answered by the node.
+type codedError interface {
+	error
+	ErrorCode() int
+}
+
+// rpcError returns the JSON-RPC error err carries, if any.
+func rpcError(err error) (codedError, bool) {
+	var rpcErr codedError
+	if errors.As(err, &rpcErr) {
+		return rpcErr, true
+	}
+	if _, body, ok := httpStatus(err); ok {
+		var resp struct {
+			Error *bodyError `json:"error"`
+		}
+		if json.Unmarshal(body, &resp) == nil && resp.Error != nil && resp.Error.Code != 0 {
+			return resp.Error, true
+		}
+	}
+	return nil, false
+}
+
+func httpStatus(err error) (int, []byte, bool) {
+	var ethErr ethRPC.HTTPError
+	if errors.As(err, &ethErr) {
+		return ethErr.StatusCode, ethErr.Body, true
+	}
+	var avxErr avxRPC.HTTPError
+	if errors.As(err, &avxErr) {
+		return avxErr.StatusCode, avxErr.Body, true
+	}
+	return 0, nil, false
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
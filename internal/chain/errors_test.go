@@ -0,0 +1,91 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	ethRPC "github.com/ethereum/go-ethereum/rpc"
+)
+
+type codeError struct {
+	code int
+	msg  string
+}
+
+func (e codeError) Error() string  { return e.msg }
+func (e codeError) ErrorCode() int { return e.code }
+
+func TestClassify(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want ErrorClass
+	}{
+		{context.DeadlineExceeded, Retryable},
+		{errors.New("connection reset by peer"), Retryable},
+		{ethRPC.HTTPError{StatusCode: http.StatusTooManyRequests, Status: "429 Too Many Requests"}, Retryable},
+		{ethRPC.HTTPError{StatusCode: http.StatusBadGateway, Status: "502 Bad Gateway"}, Retryable},
+		{ethRPC.HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}, Permanent},
+		{ethRPC.HTTPError{StatusCode: http.StatusForbidden, Status: "403 Forbidden"}, Permanent},
+		{codeError{rpcMethodNotFound, "the method eth_foo does not exist/is not available"}, Permanent},
+		{codeError{rpcInvalidParams, "invalid argument 0: hex string without 0x prefix"}, Permanent},
+		{codeError{-32000, "missing trie node 1234 (path )"}, Permanent},
+		{codeError{-32000, "header not found"}, Retryable},
+		{codeError{-32005, "query returned more than 10000 results"}, NeedsSmallerRange},
+		{codeError{rpcInvalidParams, "eth_getLogs is limited to a 10,000 range"}, NeedsSmallerRange},
+		{codeError{-32000, "block range too large"}, NeedsSmallerRange},
+		{ethRPC.HTTPError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request", Body: []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"block range too large"}}`)}, NeedsSmallerRange},
+		{ethRPC.HTTPError{StatusCode: http.StatusBadRequest, Status: "400 Bad Request", Body: []byte("block range too large")}, Retryable},
+		{ethRPC.HTTPError{StatusCode: http.StatusRequestEntityTooLarge, Status: "413 Request Entity Too Large"}, NeedsSmallerRange},
+		{errors.New("query returned more than 10000 results"), Retryable},
+	} {
+		if got := Classify(wrapError("eth_getLogs", tc.err)); got != tc.want {
+			t.Errorf("Classify(%v) = %s, want %s", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestClassifyOtherMethods(t *testing.T) {
+	for _, tc := range []struct {
+		method string
+		err    error
+		want   ErrorClass
+	}{
+		// Revert reasons and other methods' answers can contain the
+		// phrases of an eth_getLogs range or a pruned node.
+		{"eth_call", codeError{3, "execution reverted: block range too large"}, Retryable},
+		{"eth_call", codeError{-32005, "limit exceeded"}, Retryable},
+		{"eth_getBlockByNumber", codeError{-32000, "response size exceeded"}, Retryable},
+		{"debug_traceBlockByNumber", ethRPC.HTTPError{StatusCode: http.StatusRequestEntityTooLarge, Status: "413 Request Entity Too Large"}, Retryable},
+		{"eth_call", errors.New("abi: cannot unmarshal, state pruned"), Retryable},
+		{"eth_call", codeError{-32000, "missing trie node 1234 (path )"}, Permanent},
+	} {
+		if got := Classify(wrapError(tc.method, tc.err)); got != tc.want {
+			t.Errorf("Classify(%s: %v) = %s, want %s", tc.method, tc.err, got, tc.want)
+		}
+	}
+	if got := Classify(errors.New("query returned more than 10000 results")); got != Retryable {
+		t.Errorf("expected an error from outside a Client call to be retryable, got %s", got)
+	}
+}
+
+func TestErrorKeepsCauseAndHint(t *testing.T) {
+	err := fmt.Errorf("fetch block: %w", wrapError("eth_getBlockByNumber", ethRPC.HTTPError{StatusCode: http.StatusUnauthorized, Status: "401 Unauthorized"}))
+
+	if Classify(err) != Permanent {
+		t.Fatalf("expected a wrapped call error to keep its class, got %s", Classify(err))
+	}
+	var httpErr ethRPC.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatal("expected the HTTP error to stay reachable")
+	}
+	want := "fetch block: eth_getBlockByNumber: 401 Unauthorized (the node rejected the request; check chain.api_key)"
+	if err.Error() != want {
+		t.Fatalf("got %q, want %q", err.Error(), want)
+	}
+	if wrapError("eth_call", nil) != nil {
+		t.Fatal("expected no error for a successful call")
+	}
+}
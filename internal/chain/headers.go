@@ -0,0 +1,88 @@
+package chain
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common/lru"
+)
+
+// DefaultHeaderCacheSize is the number of headers a Client keeps by default.
+const DefaultHeaderCacheSize = 10000
+
+// TimestampSource looks up the timestamp of an already indexed block; ok is
+// false for a block it does not have.
+type TimestampSource func(ctx context.Context, number uint64) (timestamp uint64, ok bool, err error)
+
+// headerCache is the LRU of headers by block number shared by every caller
+// of a Client. Only headers requested by number are cached, never the
+// "latest" answer, so a cached header is one the caller already trusted as
+// final.
+type headerCache struct {
+	headers *lru.Cache[uint64, *Header]
+	// timestamps is consulted before the node on a cache miss; nil when
+	// unset.
+	timestamps atomic.Pointer[TimestampSource]
+}
+
+func newHeaderCache(size int) *headerCache {
+	if size < 1 {
+		size = DefaultHeaderCacheSize
+	}
+	return &headerCache{headers: lru.NewCache[uint64, *Header](size)}
+}
+
+// cacheable reports whether a lookup by number refers to a fixed block
+// rather than a tag like "latest".
+func cacheable(number *big.Int) bool {
+	return number != nil && number.Sign() >= 0 && number.IsUint64()
+}
+
+func (c *Client) cachedHeader(number *big.Int) (*Header, bool) {
+	if c.headers == nil || !cacheable(number) {
+		return nil, false
+	}
+	return c.headers.headers.Get(number.Uint64())
+}
+
+func (c *Client) cacheHeader(number *big.Int, header *Header) {
+	if c.headers == nil || !cacheable(number) {
+		return
+	}
+	c.headers.headers.Add(number.Uint64(), header)
+}
+
+// SetTimestampSource makes BlockTimestamp consult source, e.g. the blocks
+// table, before asking the node for a header it has not cached.
+func (c *Client) SetTimestampSource(source TimestampSource) {
+	if c.headers != nil {
+		c.headers.timestamps.Store(&source)
+	}
+}
+
+// BlockTimestamp returns the timestamp of a block from the header cache, the
+// timestamp source or, failing both, the header fetched from the node.
+func (c *Client) BlockTimestamp(ctx context.Context, number uint64) (uint64, error) {
+	n := new(big.Int).SetUint64(number)
+	if header, ok := c.cachedHeader(n); ok {
+		return header.Time(), nil
+	}
+	if c.headers != nil {
+		if source := c.headers.timestamps.Load(); source != nil {
+			timestamp, ok, err := (*source)(ctx, number)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				return timestamp, nil
+			}
+		}
+	}
+
+	header, err := c.HeaderByNumber(ctx, n)
+	if err != nil {
+		return 0, err
+	}
+	return header.Time(), nil
+}
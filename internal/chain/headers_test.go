@@ -0,0 +1,131 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	avxTypes "github.com/ava-labs/coreth/core/types"
+)
+
+// headerNode answers eth_getBlockByNumber with an empty block whose
+// timestamp is ten times its number, counting the requests.
+func headerNode(t *testing.T) (*Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %s", err)
+			return
+		}
+		var tag string
+		if err := json.Unmarshal(req.Params[0], &tag); err != nil {
+			t.Errorf("decode block number: %s", err)
+			return
+		}
+		number, err := strconv.ParseUint(tag, 0, 64)
+		if err != nil {
+			number = 100 // "latest"
+		}
+
+		header := &avxTypes.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Time:       number * 10,
+			Difficulty: big.NewInt(1),
+			Extra:      []byte{},
+			UncleHash:  avxTypes.EmptyUncleHash,
+			TxHash:     avxTypes.EmptyRootHash,
+		}
+		block := map[string]interface{}{}
+		raw, _ := json.Marshal(header)
+		_ = json.Unmarshal(raw, &block)
+		block["transactions"] = []interface{}{}
+		block["uncles"] = []interface{}{}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": block})
+	}))
+	t.Cleanup(node.Close)
+
+	nodeURL, err := url.Parse(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := DialRPCNode(nodeURL, ChainTypeAvax, Limits{Concurrency: 1, HeaderCacheSize: 2})
+	if err != nil {
+		t.Fatalf("DialRPCNode: %s", err)
+	}
+	return c, &calls
+}
+
+func TestHeaderCache(t *testing.T) {
+	c, calls := headerNode(t)
+	ctx := context.Background()
+
+	for range 2 {
+		ts, err := c.BlockTimestamp(ctx, 5)
+		if err != nil {
+			t.Fatalf("BlockTimestamp: %s", err)
+		}
+		if ts != 50 {
+			t.Fatalf("expected timestamp 50, got %d", ts)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected the second lookup to hit the cache, got %d requests", calls.Load())
+	}
+
+	// A fetched block seeds the cache with its header.
+	if _, err := c.BlockByNumber(ctx, big.NewInt(6)); err != nil {
+		t.Fatalf("BlockByNumber: %s", err)
+	}
+	if _, err := c.HeaderByNumber(ctx, big.NewInt(6)); err != nil {
+		t.Fatalf("HeaderByNumber: %s", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected the header of a fetched block to be cached, got %d requests", calls.Load())
+	}
+
+	// "latest" is never served from the cache, and the least recently used
+	// header is evicted past the size.
+	for range 2 {
+		if _, err := c.HeaderByNumber(ctx, nil); err != nil {
+			t.Fatalf("HeaderByNumber: %s", err)
+		}
+	}
+	if _, err := c.HeaderByNumber(ctx, big.NewInt(7)); err != nil {
+		t.Fatalf("HeaderByNumber: %s", err)
+	}
+	if _, err := c.BlockTimestamp(ctx, 5); err != nil {
+		t.Fatalf("BlockTimestamp: %s", err)
+	}
+	if calls.Load() != 6 {
+		t.Fatalf("expected 6 requests, got %d", calls.Load())
+	}
+}
+
+func TestTimestampSource(t *testing.T) {
+	c, calls := headerNode(t)
+	c.SetTimestampSource(func(_ context.Context, number uint64) (uint64, bool, error) {
+		return 1234, number == 8, nil
+	})
+
+	if ts, err := c.BlockTimestamp(context.Background(), 8); err != nil || ts != 1234 {
+		t.Fatalf("expected the indexed timestamp, got %d, %v", ts, err)
+	}
+	if ts, err := c.BlockTimestamp(context.Background(), 9); err != nil || ts != 90 {
+		t.Fatalf("expected the node's timestamp, got %d, %v", ts, err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected only the unindexed block to reach the node, got %d requests", calls.Load())
+	}
+}
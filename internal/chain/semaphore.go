@@ -0,0 +1,82 @@
+package chain
+
+import (
+	"context"
+	"sync"
+)
+
+// semaphore is a counting semaphore whose limit can be changed while calls
+// hold it. Waiters are served in arrival order. Lowering the limit never
+// interrupts calls in flight: new calls wait until enough of them finished.
+type semaphore struct {
+	mu      sync.Mutex
+	limit   int
+	inUse   int
+	waiters []chan struct{}
+}
+
+func newSemaphore(limit int) *semaphore {
+	return &semaphore{limit: limit}
+}
+
+// acquire blocks until a slot is free or ctx is cancelled.
+func (s *semaphore) acquire(ctx context.Context) error {
+	s.mu.Lock()
+	if s.inUse < s.limit && len(s.waiters) == 0 {
+		s.inUse++
+		s.mu.Unlock()
+		return nil
+	}
+	ready := make(chan struct{})
+	s.waiters = append(s.waiters, ready)
+	s.mu.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, w := range s.waiters {
+			if w == ready {
+				s.waiters = append(s.waiters[:i], s.waiters[i+1:]...)
+				return ctx.Err()
+			}
+		}
+		// Granted while being cancelled: hand the slot on.
+		s.inUse--
+		s.grant()
+		return ctx.Err()
+	}
+}
+
+func (s *semaphore) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.inUse--
+	s.grant()
+}
+
+// resize changes the limit, waking as many waiters as the new limit allows.
+func (s *semaphore) resize(limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit = limit
+	s.grant()
+}
+
+// size returns the limit and the number of slots in use.
+func (s *semaphore) size() (limit, inUse int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.limit, s.inUse
+}
+
+// grant hands free slots to the waiters in order. s.mu must be held.
+func (s *semaphore) grant() {
+	for s.inUse < s.limit && len(s.waiters) > 0 {
+		close(s.waiters[0])
+		s.waiters = s.waiters[1:]
+		s.inUse++
+	}
+}
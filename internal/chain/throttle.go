@@ -0,0 +1,139 @@
+package chain
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	methodGetLogs = "eth_getLogs"
+
+	// defaultRetryAfter is the pause after a 429 without a usable
+	// Retry-After header.
+	defaultRetryAfter = time.Second
+	// maxRetryAfter bounds the pause a node can ask for.
+	maxRetryAfter = time.Minute
+)
+
+// Limits caps the RPC traffic of a Client and the memory of its caches.
+type Limits struct {
+	// Concurrency is the max number of simultaneous calls (values < 1 are
+	// treated as 1).
+	Concurrency int
+	// RequestsPerSecond caps the rate of calls of any kind; 0 is unlimited.
+	RequestsPerSecond float64
+	// LogsPerSecond caps the rate of eth_getLogs calls on top of
+	// RequestsPerSecond; 0 is unlimited.
+	LogsPerSecond float64
+	// HeaderCacheSize is the number of headers kept for repeated lookups;
+	// values < 1 mean DefaultHeaderCacheSize.
+	HeaderCacheSize int
+}
+
+// throttle paces the calls of a Client: a token bucket for every call, one
+// for eth_getLogs, and a client-wide pause once the node answers 429 Too
+// Many Requests, for as long as its Retry-After asks.
+type throttle struct {
+	calls *rate.Limiter // nil is unlimited
+	logs  *rate.Limiter // nil is unlimited
+
+	mu          sync.Mutex
+	pausedUntil time.Time
+}
+
+func newThrottle(limits Limits) *throttle {
+	return &throttle{calls: newLimiter(limits.RequestsPerSecond), logs: newLimiter(limits.LogsPerSecond)}
+}
+
+// newLimiter allows perSecond calls per second with bursts of one second's
+// worth of calls.
+func newLimiter(perSecond float64) *rate.Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(perSecond), max(1, int(math.Ceil(perSecond))))
+}
+
+// wait blocks until a call of method may be sent or ctx is cancelled.
+func (t *throttle) wait(ctx context.Context, method string) error {
+	// Loop since another 429 may extend the pause while waiting.
+	for {
+		t.mu.Lock()
+		d := time.Until(t.pausedUntil)
+		t.mu.Unlock()
+		if d <= 0 {
+			break
+		}
+
+		timer := time.NewTimer(d)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+
+	if method == methodGetLogs && t.logs != nil {
+		if err := t.logs.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	if t.calls != nil {
+		return t.calls.Wait(ctx)
+	}
+	return nil
+}
+
+// pause holds back every call of the client for d, unless an earlier 429
+// already holds them back for longer.
+func (t *throttle) pause(ctx context.Context, d time.Duration) {
+	until := time.Now().Add(d)
+
+	t.mu.Lock()
+	wasPaused := time.Now().Before(t.pausedUntil)
+	extended := until.After(t.pausedUntil)
+	if extended {
+		t.pausedUntil = until
+	}
+	t.mu.Unlock()
+
+	// One line per pause rather than per rejected call.
+	if extended && !wasPaused {
+		logging.From(ctx, logging.Chain).Warnw("RPC node rate limited the client, pausing calls", "retry_after_ms", d.Milliseconds())
+	}
+}
+
+// throttleTransport reports the 429 responses of the node to the throttle.
+type throttleTransport struct {
+	base     http.RoundTripper
+	throttle *throttle
+}
+
+func (tr *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := tr.base.RoundTrip(req)
+	if err == nil && resp.StatusCode == http.StatusTooManyRequests {
+		tr.throttle.pause(req.Context(), retryAfter(resp.Header.Get("Retry-After"), time.Now()))
+	}
+	return resp, err
+}
+
+// retryAfter parses a Retry-After header, given in seconds or as an HTTP
+// date.
+func retryAfter(header string, now time.Time) time.Duration {
+	d := defaultRetryAfter
+	if seconds, err := strconv.Atoi(header); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(header); err == nil {
+		d = date.Sub(now)
+	}
+	return min(max(d, 0), maxRetryAfter)
+}
@@ -0,0 +1,89 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for header, want := range map[string]time.Duration{
+		"3":                             3 * time.Second,
+		"":                              defaultRetryAfter,
+		"soon":                          defaultRetryAfter,
+		"-5":                            0,
+		"3600":                          maxRetryAfter,
+		"Mon, 01 Jan 2024 12:00:10 GMT": 10 * time.Second,
+	} {
+		if got := retryAfter(header, now); got != want {
+			t.Errorf("retryAfter(%q) = %s, want %s", header, got, want)
+		}
+	}
+}
+
+func TestTooManyRequestsPausesClient(t *testing.T) {
+	var calls atomic.Int32
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "too many requests", http.StatusTooManyRequests)
+	}))
+	defer node.Close()
+
+	nodeURL, err := url.Parse(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := DialRPCNode(nodeURL, ChainTypeEth, Limits{Concurrency: 4})
+	if err != nil {
+		t.Fatalf("DialRPCNode: %s", err)
+	}
+
+	if _, err := c.BlockByNumber(context.Background(), big.NewInt(1)); err == nil {
+		t.Fatal("expected the 429 to fail the call")
+	}
+
+	// Every other call of the client now waits out the Retry-After instead
+	// of reaching the node.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.HeaderByNumber(ctx, big.NewInt(1)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the call to wait out the pause, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 request to the node, got %d", calls.Load())
+	}
+	if _, inFlight := c.Concurrency(); inFlight != 0 {
+		t.Fatal("expected the paused call to give back its slot")
+	}
+}
+
+func TestLogsRateLimit(t *testing.T) {
+	c := &Client{throttle: newThrottle(Limits{LogsPerSecond: 1})}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	release, err := c.acquire(ctx, "FilterLogs", methodGetLogs)
+	if err != nil {
+		t.Fatalf("acquire: %s", err)
+	}
+	release(nil)
+
+	// The eth_getLogs budget is spent for this second; other calls are not
+	// held back by it.
+	if _, err := c.acquire(ctx, "FilterLogs", methodGetLogs); err == nil {
+		t.Fatal("expected the second eth_getLogs to exceed its budget")
+	}
+	release, err = c.acquire(ctx, "BlockByNumber", "eth_getBlockByNumber")
+	if err != nil {
+		t.Fatalf("acquire: %s", err)
+	}
+	release(nil)
+}
@@ -0,0 +1,81 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const methodTraceBlock = "debug_traceBlockByNumber"
+
+// rpcCaller is the raw JSON-RPC client under the typed clients, for methods
+// they do not wrap.
+type rpcCaller interface {
+	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+}
+
+// CallFrame is one call of a callTracer trace. The frame of a transaction is
+// its top-level call; the calls it made are nested in Calls, in execution
+// order.
+type CallFrame struct {
+	Type   string          `json:"type"`
+	From   common.Address  `json:"from"`
+	To     *common.Address `json:"to,omitempty"`
+	Input  hexutil.Bytes   `json:"input"`
+	Output hexutil.Bytes   `json:"output,omitempty"`
+	Value  *hexutil.Big    `json:"value,omitempty"`
+	// Error is set when the call reverted or failed, e.g. "execution
+	// reverted" or "out of gas".
+	Error        string      `json:"error,omitempty"`
+	RevertReason string      `json:"revertReason,omitempty"`
+	Calls        []CallFrame `json:"calls,omitempty"`
+}
+
+// Reverted reports whether the call failed; its state changes, and those of
+// every call nested in it, were undone.
+func (f *CallFrame) Reverted() bool {
+	return f.Error != ""
+}
+
+// TransactionTrace is the call trace of one transaction of a block.
+type TransactionTrace struct {
+	TxHash common.Hash `json:"txHash"`
+	Result *CallFrame  `json:"result"`
+	Error  string      `json:"error,omitempty"`
+}
+
+var callTracer = map[string]interface{}{"tracer": "callTracer"}
+
+// TraceBlockByNumber returns the call traces of the transactions of a block,
+// in block order, from debug_traceBlockByNumber with the built-in callTracer.
+// The node must expose the debug API.
+func (c *Client) TraceBlockByNumber(ctx context.Context, number *big.Int) (_ []TransactionTrace, err error) {
+	release, err := c.acquire(ctx, "TraceBlockByNumber", methodTraceBlock)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { err = release(err) }()
+
+	if c.rpc == nil {
+		return nil, errors.New("invalid chain")
+	}
+
+	var traces []TransactionTrace
+	if err := c.rpc.CallContext(ctx, &traces, methodTraceBlock, hexutil.EncodeBig(number), callTracer); err != nil {
+		return nil, err
+	}
+	for i := range traces {
+		if traces[i].Error != "" {
+			return nil, fmt.Errorf("trace of transaction %d: %s", i, traces[i].Error)
+		}
+		if traces[i].Result == nil {
+			return nil, fmt.Errorf("trace of transaction %d: empty result", i)
+		}
+	}
+
+	return traces, nil
+}
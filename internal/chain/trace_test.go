@@ -0,0 +1,60 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestTraceBlockByNumber(t *testing.T) {
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %s", err)
+			return
+		}
+		if req.Method != methodTraceBlock || string(req.Params[0]) != `"0x7"` || string(req.Params[1]) != `{"tracer":"callTracer"}` {
+			t.Errorf("unexpected request %s %s", req.Method, req.Params)
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":[{
+			"txHash":"0x00000000000000000000000000000000000000000000000000000000000000aa",
+			"result":{"type":"CALL","from":"0x0000000000000000000000000000000000000001",
+				"to":"0x0000000000000000000000000000000000000002","input":"0x12345678","value":"0x0",
+				"calls":[{"type":"STATICCALL","from":"0x0000000000000000000000000000000000000002",
+					"to":"0x0000000000000000000000000000000000000003","input":"0xabcdef01","output":"0x01",
+					"error":"execution reverted","revertReason":"nope"}]}}]}`))
+	}))
+	defer node.Close()
+
+	nodeURL, err := url.Parse(node.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := DialRPCNode(nodeURL, ChainTypeEth, Limits{Concurrency: 1})
+	if err != nil {
+		t.Fatalf("DialRPCNode: %s", err)
+	}
+
+	traces, err := c.TraceBlockByNumber(context.Background(), big.NewInt(7))
+	if err != nil {
+		t.Fatalf("TraceBlockByNumber: %s", err)
+	}
+	if len(traces) != 1 || len(traces[0].Result.Calls) != 1 {
+		t.Fatalf("unexpected traces %+v", traces)
+	}
+	if traces[0].Result.Reverted() {
+		t.Error("expected the top-level call to succeed")
+	}
+	call := traces[0].Result.Calls[0]
+	if call.Type != "STATICCALL" || !call.Reverted() || call.RevertReason != "nope" || call.Output.String() != "0x01" {
+		t.Errorf("unexpected internal call %+v", call)
+	}
+}
@@ -7,10 +7,24 @@ import (
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
 )
 
+// ResolveContractAddresses replaces the contract names of the transaction and
+// log filters with the addresses they resolve to.
 func ResolveContractAddresses(ctx context.Context, cfg *Config, resolver *contracts.ContractResolver) error {
-	transactions := make([]TransactionInfo, 0, len(cfg.Indexer.CollectTransactions))
-	for _, transaction := range cfg.Indexer.CollectTransactions {
-		if strings.TrimSpace(transaction.ContractAddress) != "" {
+	return ResolveIndexerAddresses(ctx, &cfg.Indexer, resolver)
+}
+
+// ResolveIndexerAddresses resolves the contract names of the filters of
+// indexer in place. Names are always resolved from the filters as first
+// configured, so it can run again to follow redeployed contracts.
+func ResolveIndexerAddresses(ctx context.Context, indexer *IndexerConfig, resolver *contracts.ContractResolver) error {
+	if !indexer.resolved {
+		indexer.configuredTransactions = indexer.CollectTransactions
+		indexer.configuredLogs = indexer.CollectLogs
+	}
+
+	transactions := make([]TransactionInfo, 0, len(indexer.configuredTransactions))
+	for _, transaction := range indexer.configuredTransactions {
+		if strings.TrimSpace(transaction.ContractAddress) != "" || transaction.AnyContract() {
 			transactions = append(transactions, transaction)
 			continue
 		}
@@ -25,10 +39,9 @@ func ResolveContractAddresses(ctx context.Context, cfg *Config, resolver *contra
 			transactions = append(transactions, resolved)
 		}
 	}
-	cfg.Indexer.CollectTransactions = transactions
 
-	logs := make([]LogInfo, 0, len(cfg.Indexer.CollectLogs))
-	for _, logInfo := range cfg.Indexer.CollectLogs {
+	logs := make([]LogInfo, 0, len(indexer.configuredLogs))
+	for _, logInfo := range indexer.configuredLogs {
 		if strings.TrimSpace(logInfo.ContractAddress) != "" {
 			logs = append(logs, logInfo)
 			continue
@@ -44,7 +57,10 @@ func ResolveContractAddresses(ctx context.Context, cfg *Config, resolver *contra
 			logs = append(logs, resolved)
 		}
 	}
-	cfg.Indexer.CollectLogs = logs
+
+	indexer.CollectTransactions = transactions
+	indexer.CollectLogs = logs
+	indexer.resolved = true
 
 	return nil
 }
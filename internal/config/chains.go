@@ -0,0 +1,172 @@
+package config
+
+import (
+	"regexp"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+	"github.com/pkg/errors"
+)
+
+var (
+	chainNamePattern   = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	tablePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_]*$`)
+)
+
+// ChainSection is one [[chains]] entry of a multi-chain config. Its [chain]
+// and [indexer] settings start from the top-level sections and override
+// only the keys the entry sets. Its tables are kept apart from the other
+// chains' by Database and/or TablePrefix; the rest of [db] (connection,
+// storage format, partitioning) is shared.
+type ChainSection struct {
+	Name        string  `toml:"name"`
+	Database    string  `toml:"database"`
+	TablePrefix string  `toml:"table_prefix"`
+	HistoryDrop *uint64 `toml:"history_drop"`
+
+	Chain   ChainConfig   `toml:"-"`
+	Indexer IndexerConfig `toml:"-"`
+}
+
+// rawChainSection defers decoding of the per-chain sections until the
+// top-level defaults they override are known.
+type rawChainSection struct {
+	Chain   toml.Primitive `toml:"chain"`
+	Indexer toml.Primitive `toml:"indexer"`
+}
+
+// decodeChainSections fills the [chain] and [indexer] settings of every
+// [[chains]] entry on top of a copy of the top-level ones.
+func decodeChainSections(cfg *Config, content string) error {
+	var raw struct {
+		Chains []rawChainSection `toml:"chains"`
+	}
+	md, err := toml.Decode(content, &raw)
+	if err != nil {
+		return errors.Wrap(err, "error parsing [[chains]]")
+	}
+
+	for i := range cfg.Chains {
+		section := &cfg.Chains[i]
+		section.Chain = cfg.Chain
+		section.Indexer = cloneIndexerConfig(cfg.Indexer)
+
+		if err := md.PrimitiveDecode(raw.Chains[i].Chain, &section.Chain); err != nil {
+			return errors.Wrapf(err, "error parsing [chains.chain] of chain %q", section.Name)
+		}
+		if err := md.PrimitiveDecode(raw.Chains[i].Indexer, &section.Indexer); err != nil {
+			return errors.Wrapf(err, "error parsing [chains.indexer] of chain %q", section.Name)
+		}
+	}
+	return nil
+}
+
+// cloneIndexerConfig copies the collector lists too, since contract name
+// resolution writes the resolved addresses into them per chain.
+func cloneIndexerConfig(cfg IndexerConfig) IndexerConfig {
+	cfg.CollectTransactions = slices.Clone(cfg.CollectTransactions)
+	cfg.CollectLogs = slices.Clone(cfg.CollectLogs)
+	return cfg
+}
+
+// normalizeChainSections validates the [[chains]] entries: names are unique
+// and usable in URL paths, and no two chains share a set of tables.
+func normalizeChainSections(cfg *Config) error {
+	names := make(map[string]bool, len(cfg.Chains))
+	tables := make(map[[2]string]string, len(cfg.Chains))
+
+	for i := range cfg.Chains {
+		section := &cfg.Chains[i]
+		if !chainNamePattern.MatchString(section.Name) {
+			return errors.Errorf(
+				"invalid [[chains]] name %q: must be lowercase letters, digits, '-' or '_'", section.Name,
+			)
+		}
+		if names[section.Name] {
+			return errors.Errorf("duplicate [[chains]] name %q", section.Name)
+		}
+		names[section.Name] = true
+
+		if !tablePrefixPattern.MatchString(section.TablePrefix) {
+			return errors.Errorf(
+				"invalid table_prefix %q of chain %q: must be letters, digits or '_'", section.TablePrefix, section.Name,
+			)
+		}
+
+		database := section.Database
+		if database == "" {
+			database = cfg.DB.Database
+		}
+		key := [2]string{database, section.TablePrefix}
+		if other, ok := tables[key]; ok {
+			return errors.Errorf(
+				"chains %q and %q would share tables: give them a different database or table_prefix",
+				other, section.Name,
+			)
+		}
+		tables[key] = section.Name
+
+		if err := normalizeIndexerConfig(&section.Indexer); err != nil {
+			return errors.Wrapf(err, "chain %q", section.Name)
+		}
+	}
+	return nil
+}
+
+// IsMultiChain reports whether the config has [[chains]] entries.
+func (c *Config) IsMultiChain() bool {
+	return len(c.Chains) > 0
+}
+
+// ChainConfigs expands the config into one config per indexed chain: the
+// config itself without [[chains]], otherwise one per entry with the entry's
+// chain, indexer and table settings in place of the top-level ones.
+func (c *Config) ChainConfigs() []*Config {
+	if !c.IsMultiChain() {
+		return []*Config{c}
+	}
+
+	cfgs := make([]*Config, len(c.Chains))
+	for i := range c.Chains {
+		section := &c.Chains[i]
+
+		chainCfg := *c
+		chainCfg.Chains = nil
+		chainCfg.Chain = section.Chain
+		chainCfg.Chain.Name = section.Name
+		chainCfg.Indexer = cloneIndexerConfig(section.Indexer)
+		if section.Database != "" {
+			chainCfg.DB.Database = section.Database
+		}
+		chainCfg.DB.TablePrefix = section.TablePrefix
+		if section.HistoryDrop != nil {
+			chainCfg.DB.HistoryDrop = section.HistoryDrop
+		}
+
+		cfgs[i] = &chainCfg
+	}
+	return cfgs
+}
+
+// ChainConfig returns the config of the named [[chains]] entry. An empty
+// name selects the only chain of the config, and is required to be empty
+// without [[chains]].
+func (c *Config) ChainConfig(name string) (*Config, error) {
+	cfgs := c.ChainConfigs()
+	if name == "" {
+		if len(cfgs) > 1 {
+			return nil, errors.New("the config has several [[chains]]: select one with --chain")
+		}
+		return cfgs[0], nil
+	}
+	if !c.IsMultiChain() {
+		return nil, errors.Errorf("--chain %q given but the config has no [[chains]]", name)
+	}
+
+	for _, cfg := range cfgs {
+		if cfg.Chain.Name == name {
+			return cfg, nil
+		}
+	}
+	return nil, errors.Errorf("no [[chains]] entry named %q", name)
+}
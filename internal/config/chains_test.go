@@ -0,0 +1,116 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+)
+
+const multiChainConfig = `
+[db]
+database = "indexer"
+history_drop = 1209600
+
+[chain]
+chain_type = 1
+
+[indexer]
+batch_size = 500
+mode = "full"
+
+[[indexer.collect_logs]]
+contract_address = "0x1c78A073E3BD2aCa4cc327d55FB0cD4f0549B55b"
+topic = "undefined"
+
+[[chains]]
+name = "flare"
+table_prefix = "flr_"
+
+[chains.chain]
+node_url = "http://flare:9650/ext/C/rpc"
+
+[[chains]]
+name = "coston2"
+database = "coston2"
+history_drop = 172800
+
+[chains.chain]
+node_url = "http://coston2:9650/ext/C/rpc"
+chain_type = 2
+
+[chains.indexer]
+batch_size = 100
+`
+
+func TestChainConfigs(t *testing.T) {
+	cfg := &Config{}
+	if err := parseConfigFile(cfg, writeTempConfig(t, multiChainConfig)); err != nil {
+		t.Fatalf("parse: %s", err)
+	}
+	if err := normalizeChainSections(cfg); err != nil {
+		t.Fatalf("normalize: %s", err)
+	}
+
+	cfgs := cfg.ChainConfigs()
+	if len(cfgs) != 2 {
+		t.Fatalf("expected 2 chain configs, got %d", len(cfgs))
+	}
+	flare, coston2 := cfgs[0], cfgs[1]
+
+	if flare.Chain.Name != "flare" || flare.Chain.NodeURL != "http://flare:9650/ext/C/rpc" {
+		t.Fatalf("unexpected flare chain config: %+v", flare.Chain)
+	}
+	if flare.Chain.ChainType != chain.ChainTypeAvax || flare.Indexer.BatchSize != 500 {
+		t.Fatalf("flare should inherit the top-level settings, got %+v / batch_size=%d", flare.Chain, flare.Indexer.BatchSize)
+	}
+	if flare.DB.Database != "indexer" || flare.DB.TablePrefix != "flr_" || *flare.DB.HistoryDrop != 1209600 {
+		t.Fatalf("unexpected flare db config: %+v", flare.DB)
+	}
+
+	if coston2.Chain.ChainType != chain.ChainTypeEth || coston2.Indexer.BatchSize != 100 {
+		t.Fatalf("coston2 should override the top-level settings, got %+v / batch_size=%d", coston2.Chain, coston2.Indexer.BatchSize)
+	}
+	if coston2.DB.Database != "coston2" || coston2.DB.TablePrefix != "" || *coston2.DB.HistoryDrop != 172800 {
+		t.Fatalf("unexpected coston2 db config: %+v", coston2.DB)
+	}
+
+	// Contract resolution writes into the collector lists, so they must not
+	// be shared between chains.
+	flare.Indexer.CollectLogs[0].ContractAddress = "resolved"
+	if coston2.Indexer.CollectLogs[0].ContractAddress == "resolved" {
+		t.Fatalf("chains share their collect_logs")
+	}
+
+	if _, err := cfg.ChainConfig(""); err == nil {
+		t.Fatalf("expected an error selecting no chain of a multi-chain config")
+	}
+	selected, err := cfg.ChainConfig("coston2")
+	if err != nil || selected.Chain.Name != "coston2" {
+		t.Fatalf("select coston2: %v, %v", selected, err)
+	}
+}
+
+func TestNormalizeChainSectionsRejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		chains []ChainSection
+		errMsg string
+	}{
+		{"missing name", []ChainSection{{}}, "invalid [[chains]] name"},
+		{"name not usable in a path", []ChainSection{{Name: "Flare/1"}}, "invalid [[chains]] name"},
+		{"duplicate name", []ChainSection{{Name: "a", TablePrefix: "a_"}, {Name: "a", TablePrefix: "b_"}}, "duplicate"},
+		{"shared tables", []ChainSection{{Name: "a"}, {Name: "b", Database: "indexer"}}, "would share tables"},
+		{"bad prefix", []ChainSection{{Name: "a", TablePrefix: "a-"}}, "invalid table_prefix"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := &Config{DB: DBConfig{Database: "indexer"}, Chains: tc.chains}
+			err := normalizeChainSections(cfg)
+			if err == nil || !strings.Contains(err.Error(), tc.errMsg) {
+				t.Fatalf("expected error containing %q, got %v", tc.errMsg, err)
+			}
+		})
+	}
+}
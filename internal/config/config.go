@@ -8,14 +8,15 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
 
 	"github.com/BurntSushi/toml"
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 	"github.com/pkg/errors"
 )
 
@@ -27,6 +28,14 @@ const (
 	defaultLogRange                       = uint64(1000)
 	defaultRpcConcurrency                 = 100
 	defaultBatchSize                      = uint64(1000)
+	// One day per partition: ~1s blocks make the block-mode default the
+	// same width as the timestamp-mode one.
+	defaultPartitionSize          = uint64(24 * 60 * 60)
+	defaultPartitionsAhead        = uint64(3)
+	defaultTraceSampleRatio       = 1.0
+	defaultTraceServiceName       = "flare-cchain-indexer"
+	defaultHealthListenAddress    = ":8080"
+	defaultLivenessTimeoutSeconds = 600
 	// maxHistoryEpochs guards against a config typo (e.g. an extra digit).
 	maxHistoryEpochs = 1000
 )
@@ -71,12 +80,11 @@ func init() {
 		if loggerCfg.File != "" {
 			_ = os.MkdirAll(filepath.Dir(loggerCfg.File), 0o755)
 		}
-		logger.Set(logger.Config{
-			Level:       loggerCfg.Level,
-			File:        loggerCfg.File,
-			MaxFileSize: loggerCfg.MaxFileSize,
-			Console:     loggerCfg.Console,
-		})
+		if err := logging.Setup(loggerCfg.loggingConfig()); err != nil {
+			// Only reachable for a config that skipped BuildConfig, whose
+			// normalizeLoggerConfig rejects bad formats and levels.
+			fmt.Printf("ERROR: Invalid logger config: %v\n", err)
+		}
 	})
 }
 
@@ -91,6 +99,13 @@ type Config struct {
 	Chain   ChainConfig   `toml:"chain"`
 	Indexer IndexerConfig `toml:"indexer"`
 	Timeout TimeoutConfig `toml:"timeout"`
+	Tracing TracingConfig `toml:"tracing"`
+	Health  HealthConfig  `toml:"health"`
+	Admin   AdminConfig   `toml:"admin"`
+
+	// Chains indexes several chains in one process, one entry per chain;
+	// see ChainSection and ChainConfigs.
+	Chains []ChainSection `toml:"chains"`
 }
 
 type LoggerConfig struct {
@@ -98,6 +113,77 @@ type LoggerConfig struct {
 	File        string `toml:"file"`
 	MaxFileSize int    `toml:"max_file_size"` // In megabytes
 	Console     bool   `toml:"console"`
+
+	// Format is logging.FormatConsole (the default) or logging.FormatJSON,
+	// one JSON object per line with the key/value pairs as typed fields.
+	Format string `toml:"format"`
+	// Levels overrides Level per subsystem (engine, chain, fsp, database,
+	// main).
+	Levels map[string]string `toml:"levels"`
+}
+
+func (c LoggerConfig) loggingConfig() logging.Config {
+	levels := make(map[logging.Subsystem]string, len(c.Levels))
+	for s, level := range c.Levels {
+		levels[logging.Subsystem(s)] = level
+	}
+	return logging.Config{
+		Level:       c.Level,
+		File:        c.File,
+		MaxFileSize: c.MaxFileSize,
+		Console:     c.Console,
+		Format:      c.Format,
+		Levels:      levels,
+	}
+}
+
+// TracingConfig configures OpenTelemetry trace export; see the tracing
+// package.
+type TracingConfig struct {
+	// Endpoint is the host:port of an OTLP/HTTP collector; empty disables
+	// tracing.
+	Endpoint string `toml:"endpoint"`
+	Insecure bool   `toml:"insecure"`
+	// SampleRatio is the fraction of traces kept, in (0, 1]; unset keeps all.
+	SampleRatio float64 `toml:"sample_ratio"`
+	ServiceName string  `toml:"service_name"`
+}
+
+// HealthConfig configures the health server and its probes.
+type HealthConfig struct {
+	ListenAddress string `toml:"listen_address"`
+	// MaxLagBlocks is the largest gap between the chain tip and the last
+	// committed block at which /readyz passes; 0 disables the check.
+	MaxLagBlocks uint64 `toml:"max_lag_blocks"`
+	// CommitTimeoutSeconds fails /readyz when no block was committed for
+	// that long; 0 disables the check. Only for chains that never go
+	// without blocks for longer.
+	CommitTimeoutSeconds uint64 `toml:"commit_timeout_seconds"`
+	// LivenessTimeoutSeconds fails /livez when the indexing loop has not
+	// gone round for that long. It must exceed the backoff max elapsed time,
+	// for which a batch retries without going round; the default is twice
+	// that, and at least 600.
+	LivenessTimeoutSeconds uint64 `toml:"liveness_timeout_seconds"`
+}
+
+func (c HealthConfig) CommitTimeout() time.Duration {
+	return time.Duration(c.CommitTimeoutSeconds) * time.Second
+}
+
+func (c HealthConfig) LivenessTimeout() time.Duration {
+	return time.Duration(c.LivenessTimeoutSeconds) * time.Second
+}
+
+// AdminConfig configures the admin API served on the health server; see the
+// admin package.
+type AdminConfig struct {
+	// Token is the bearer token every admin request must carry; empty
+	// disables the admin API.
+	Token string `toml:"token"`
+}
+
+func (c AdminConfig) Enabled() bool {
+	return c.Token != ""
 }
 
 type DBConfig struct {
@@ -112,6 +198,47 @@ type DBConfig struct {
 	// disables history drop.
 	HistoryDrop      *uint64 `toml:"history_drop"`
 	DropTableAtStart bool    `toml:"drop_table_at_start"`
+
+	// Partitioning range-partitions blocks, transactions and logs by
+	// PartitioningTimestamp or PartitioningBlock so history drop can drop
+	// whole partitions; empty disables it. PartitionSize is the width of a
+	// partition in seconds or blocks, PartitionsAhead how many empty
+	// partitions are kept ready above the incoming data.
+	Partitioning    string `toml:"partitioning"`
+	PartitionSize   uint64 `toml:"partition_size"`
+	PartitionsAhead uint64 `toml:"partitions_ahead"`
+
+	// StorageFormat is StorageFormatHex (lowercase hex strings, the default)
+	// or StorageFormatBinary (raw bytes) for hashes, addresses, topics,
+	// transaction input and log data.
+	StorageFormat string `toml:"storage_format"`
+
+	// TablePrefix is prepended to the name of every indexer table, so
+	// several indexers can share one database. With [[chains]] it is set
+	// per chain.
+	TablePrefix string `toml:"table_prefix"`
+}
+
+// RPCLimits are the limits of the chain.Client of this indexer.
+func (c IndexerConfig) RPCLimits() chain.Limits {
+	return chain.Limits{
+		Concurrency:       c.RpcConcurrency,
+		RequestsPerSecond: c.RpcRateLimit,
+		LogsPerSecond:     c.LogsRateLimit,
+		HeaderCacheSize:   c.HeaderCacheSize,
+	}
+}
+
+const (
+	PartitioningTimestamp = "timestamp"
+	PartitioningBlock     = "block"
+
+	StorageFormatHex    = "hex"
+	StorageFormatBinary = "binary"
+)
+
+func (db *DBConfig) PartitioningEnabled() bool {
+	return db.Partitioning != ""
 }
 
 func (db *DBConfig) GetHistoryDrop(ctx context.Context, chainIDBig *big.Int) (uint64, error) {
@@ -146,9 +273,26 @@ func (db *DBConfig) GetHistoryDrop(ctx context.Context, chainIDBig *big.Int) (ui
 }
 
 type ChainConfig struct {
+	// Name is the [[chains]] entry name; empty for a single-chain config.
+	Name      string          `toml:"-"`
 	NodeURL   string          `toml:"node_url"`
 	APIKey    string          `toml:"api_key"`
 	ChainType chain.ChainType `toml:"chain_type"`
+	// ExpectedChainID, when set, must match the chain ID the node reports,
+	// or the indexer refuses to start before writing anything.
+	ExpectedChainID uint64 `toml:"expected_chain_id"`
+}
+
+// CheckChainID fails when expected_chain_id is set and differs from the
+// chain ID reported by the node.
+func (c ChainConfig) CheckChainID(chainID *big.Int) error {
+	if c.ExpectedChainID == 0 {
+		return nil
+	}
+	if !chainID.IsUint64() || chainID.Uint64() != c.ExpectedChainID {
+		return errors.Errorf("node reports chain ID %s, expected_chain_id is %d", chainID, c.ExpectedChainID)
+	}
+	return nil
 }
 
 type IndexerConfig struct {
@@ -168,16 +312,48 @@ type IndexerConfig struct {
 	HistoryEpochs uint64 `toml:"history_epochs"`
 	// RpcConcurrency is the max number of simultaneous RPC calls of any kind —
 	// block, receipt and log (eth_getLogs) fetches, plus contract calls and
-	// history-drop lookups — enforced process-wide in chain.Client.
+	// history-drop lookups — enforced per chain in chain.Client.
 	RpcConcurrency int `toml:"rpc_concurrency"`
+	// RpcRateLimit caps the RPC calls per second of any kind, for providers
+	// that enforce a request rate; 0 is unlimited.
+	RpcRateLimit float64 `toml:"rpc_rate_limit"`
+	// LogsRateLimit additionally caps the eth_getLogs calls per second, the
+	// heaviest calls and often budgeted separately by providers; 0 is
+	// unlimited.
+	LogsRateLimit float64 `toml:"logs_rate_limit"`
+	// HeaderCacheSize is the number of block headers kept in memory for the
+	// timestamp lookups of block search, history drop and FSP backfill.
+	HeaderCacheSize int `toml:"header_cache_size"`
+	// TimestampsFromDB makes those lookups read the blocks table before
+	// asking the RPC node, for blocks already indexed.
+	TimestampsFromDB bool `toml:"timestamps_from_db"`
+	// TraceCalls traces every block with transactions through
+	// debug_traceBlockByNumber and stores the internal calls matching
+	// CollectTransactions, with their parent transactions. The node must
+	// expose the debug API.
+	TraceCalls bool `toml:"trace_calls"`
 	// LogRange is the max blocks per eth_getLogs (FilterLogs) request,
 	// bounded by the RPC node's getLogs cap (typically 100-10000).
 	LogRange                uint64            `toml:"log_range"`
 	NewBlockCheckMillis     int               `toml:"new_block_check_millis"`
 	CollectTransactions     []TransactionInfo `toml:"collect_transactions"`
+	CollectTransfers        []TransferInfo    `toml:"collect_transfers"`
 	CollectLogs             []LogInfo         `toml:"collect_logs"`
 	Confirmations           uint64            `toml:"confirmations"`
 	NoNewBlocksDelayWarning float64           `toml:"no_new_blocks_delay_warning"`
+	// RegistryCheckSeconds is how often the ContractRegistry is polled for
+	// redeployed contracts, whose new addresses are then collected from the
+	// next batch on (default 300).
+	RegistryCheckSeconds uint64 `toml:"registry_check_seconds"`
+	// RetiredContractGraceSeconds is how long the replaced address of a
+	// redeployed contract is still collected (default 3600).
+	RetiredContractGraceSeconds uint64 `toml:"retired_contract_grace_seconds"`
+
+	// configuredTransactions and configuredLogs are the filters as configured,
+	// kept by ResolveContractAddresses so it can resolve them again.
+	configuredTransactions []TransactionInfo
+	configuredLogs         []LogInfo
+	resolved               bool
 }
 
 const (
@@ -200,6 +376,66 @@ type TransactionInfo struct {
 	FuncSig         string `toml:"func_sig"`
 	Status          bool   `toml:"status"`
 	CollectEvents   bool   `toml:"collect_events"`
+	// FromAddress and FromAddresses restrict the filter to transactions sent
+	// by one of the given addresses; with neither set, any sender matches. A
+	// filter with senders and no contract matches calls to any contract.
+	// omitempty keeps CollectionHash of filters without senders unchanged.
+	FromAddress   string   `toml:"from_address" json:",omitempty"`
+	FromAddresses []string `toml:"from_addresses" json:",omitempty"`
+}
+
+// Senders returns the sender addresses of the filter, from both from_address
+// and from_addresses; empty matches any sender.
+func (t *TransactionInfo) Senders() []string {
+	return addressList(t.FromAddress, t.FromAddresses)
+}
+
+// AnyContract reports whether the filter matches calls to any contract: it
+// names no contract but restricts the senders.
+func (t *TransactionInfo) AnyContract() bool {
+	return strings.TrimSpace(t.ContractAddress) == "" &&
+		strings.TrimSpace(t.ContractName) == "" &&
+		len(t.Senders()) > 0
+}
+
+// TransferInfo collects plain value transfers: transactions with less
+// calldata than a 4-byte function selector, which collect_transactions never
+// matches. Every condition set must hold; an address list matches any of its
+// addresses.
+type TransferInfo struct {
+	ToAddress     string   `toml:"to_address" json:",omitempty"`
+	ToAddresses   []string `toml:"to_addresses" json:",omitempty"`
+	FromAddress   string   `toml:"from_address" json:",omitempty"`
+	FromAddresses []string `toml:"from_addresses" json:",omitempty"`
+	// MinValue is the smallest value collected, in wei as a decimal string;
+	// empty collects any value, zero included.
+	MinValue      string `toml:"min_value" json:",omitempty"`
+	Status        bool   `toml:"status"`
+	CollectEvents bool   `toml:"collect_events"`
+}
+
+// Recipients returns the recipient addresses of the filter; empty matches
+// any recipient.
+func (t *TransferInfo) Recipients() []string {
+	return addressList(t.ToAddress, t.ToAddresses)
+}
+
+// Senders returns the sender addresses of the filter; empty matches any
+// sender.
+func (t *TransferInfo) Senders() []string {
+	return addressList(t.FromAddress, t.FromAddresses)
+}
+
+// addressList joins the single-address and list forms of an address
+// setting, dropping blanks.
+func addressList(single string, list []string) []string {
+	var addresses []string
+	for _, address := range append([]string{single}, list...) {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
 }
 
 type LogInfo struct {
@@ -232,6 +468,19 @@ func BuildConfig() (*Config, error) {
 	if err := normalizeIndexerConfig(&cfg.Indexer); err != nil {
 		return nil, err
 	}
+	if err := normalizeDBConfig(&cfg.DB); err != nil {
+		return nil, err
+	}
+	if err := normalizeLoggerConfig(&cfg.Logger); err != nil {
+		return nil, err
+	}
+	if err := normalizeTracingConfig(&cfg.Tracing); err != nil {
+		return nil, err
+	}
+	normalizeHealthConfig(&cfg.Health, cfg.Timeout)
+	if err := normalizeChainSections(cfg); err != nil {
+		return nil, err
+	}
 
 	return cfg, nil
 }
@@ -265,12 +514,110 @@ func normalizeIndexerConfig(cfg *IndexerConfig) error {
 	if cfg.RpcConcurrency <= 0 {
 		cfg.RpcConcurrency = defaultRpcConcurrency
 	}
+	if cfg.HeaderCacheSize <= 0 {
+		cfg.HeaderCacheSize = chain.DefaultHeaderCacheSize
+	}
+	if cfg.RpcRateLimit < 0 || cfg.LogsRateLimit < 0 {
+		return errors.New("indexer.rpc_rate_limit and indexer.logs_rate_limit must not be negative")
+	}
 	if cfg.BatchSize == 0 {
 		cfg.BatchSize = defaultBatchSize
 	}
 	return nil
 }
 
+func normalizeDBConfig(cfg *DBConfig) error {
+	if !tablePrefixPattern.MatchString(cfg.TablePrefix) {
+		return errors.Errorf("invalid db.table_prefix %q: must be letters, digits or '_'", cfg.TablePrefix)
+	}
+
+	cfg.StorageFormat = strings.ToLower(strings.TrimSpace(cfg.StorageFormat))
+	if cfg.StorageFormat == "" {
+		cfg.StorageFormat = StorageFormatHex
+	}
+	if cfg.StorageFormat != StorageFormatHex && cfg.StorageFormat != StorageFormatBinary {
+		return errors.Errorf(
+			"invalid db.storage_format %q: must be %q or %q",
+			cfg.StorageFormat, StorageFormatHex, StorageFormatBinary,
+		)
+	}
+
+	cfg.Partitioning = strings.ToLower(strings.TrimSpace(cfg.Partitioning))
+	if !cfg.PartitioningEnabled() {
+		return nil
+	}
+	if cfg.Partitioning != PartitioningTimestamp && cfg.Partitioning != PartitioningBlock {
+		return errors.Errorf(
+			"invalid db.partitioning %q: must be %q, %q or empty",
+			cfg.Partitioning, PartitioningTimestamp, PartitioningBlock,
+		)
+	}
+
+	if cfg.PartitionSize == 0 {
+		cfg.PartitionSize = defaultPartitionSize
+	}
+	if cfg.PartitionsAhead == 0 {
+		cfg.PartitionsAhead = defaultPartitionsAhead
+	}
+	return nil
+}
+
+func normalizeLoggerConfig(cfg *LoggerConfig) error {
+	cfg.Format = strings.ToLower(strings.TrimSpace(cfg.Format))
+	if cfg.Format == "" {
+		cfg.Format = logging.FormatConsole
+	}
+	if cfg.Format != logging.FormatConsole && cfg.Format != logging.FormatJSON {
+		return errors.Errorf(
+			"invalid logger.format %q: must be %q or %q",
+			cfg.Format, logging.FormatConsole, logging.FormatJSON,
+		)
+	}
+
+	if _, err := logging.ParseLevel(cfg.Level); err != nil {
+		return errors.Errorf("invalid logger.level %q", cfg.Level)
+	}
+	for s, level := range cfg.Levels {
+		if !slices.Contains(logging.Subsystems, logging.Subsystem(s)) {
+			return errors.Errorf("unknown logger.levels subsystem %q: must be one of %v", s, logging.Subsystems)
+		}
+		if _, err := logging.ParseLevel(level); err != nil {
+			return errors.Errorf("invalid logger.levels.%s %q", s, level)
+		}
+	}
+	return nil
+}
+
+// normalizeHealthConfig defaults the liveness timeout to twice the backoff
+// max elapsed time, and to at least defaultLivenessTimeoutSeconds: a batch
+// does not go round while it retries an RPC call, for up to that time, and
+// the failed batch then waits for the outer loop's own backoff.
+func normalizeHealthConfig(cfg *HealthConfig, timeout TimeoutConfig) {
+	if cfg.ListenAddress == "" {
+		cfg.ListenAddress = defaultHealthListenAddress
+	}
+	if cfg.LivenessTimeoutSeconds == 0 {
+		backoffSeconds := uint64(BackoffMaxElapsedTime.Seconds())
+		if timeout.BackoffMaxElapsedTimeSeconds != nil && *timeout.BackoffMaxElapsedTimeSeconds > 0 {
+			backoffSeconds = uint64(*timeout.BackoffMaxElapsedTimeSeconds)
+		}
+		cfg.LivenessTimeoutSeconds = max(defaultLivenessTimeoutSeconds, 2*backoffSeconds)
+	}
+}
+
+func normalizeTracingConfig(cfg *TracingConfig) error {
+	if cfg.SampleRatio == 0 {
+		cfg.SampleRatio = defaultTraceSampleRatio
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return errors.Errorf("tracing.sample_ratio must be in (0, 1], got %v", cfg.SampleRatio)
+	}
+	if cfg.ServiceName == "" {
+		cfg.ServiceName = defaultTraceServiceName
+	}
+	return nil
+}
+
 func parseConfigFile(cfg *Config, fileName string) error {
 	content, err := os.ReadFile(fileName)
 	if err != nil {
@@ -290,7 +637,7 @@ func parseConfigFile(cfg *Config, fileName string) error {
 			return fmt.Errorf("config key %q has been renamed to %q", key.String(), newName)
 		}
 	}
-	return nil
+	return decodeChainSections(cfg, string(content))
 }
 
 func (c Config) LoggerConfig() LoggerConfig {
@@ -332,6 +679,7 @@ var envOverrides = map[string]func(*Config, string){
 	"DB_PASSWORD":  func(c *Config, v string) { c.DB.Password = v },
 	"NODE_URL":     func(c *Config, v string) { c.Chain.NodeURL = v },
 	"NODE_API_KEY": func(c *Config, v string) { c.Chain.APIKey = v },
+	"ADMIN_TOKEN":  func(c *Config, v string) { c.Admin.Token = v },
 }
 
 func applyEnvOverrides(cfg *Config) {
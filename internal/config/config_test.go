@@ -63,6 +63,25 @@ func TestNormalizeIndexerConfigRejectsExcessiveHistoryEpochs(t *testing.T) {
 	}
 }
 
+func TestNormalizeIndexerConfigRejectsNegativeRateLimit(t *testing.T) {
+	cfg := IndexerConfig{Mode: IndexerModeFull, LogsRateLimit: -1}
+	if err := normalizeIndexerConfig(&cfg); err == nil {
+		t.Fatal("expected error for a negative logs_rate_limit, got nil")
+	}
+}
+
+func TestCheckChainID(t *testing.T) {
+	if err := (ChainConfig{}).CheckChainID(big.NewInt(14)); err != nil {
+		t.Fatalf("expected no check without expected_chain_id, got %s", err)
+	}
+	if err := (ChainConfig{ExpectedChainID: 14}).CheckChainID(big.NewInt(14)); err != nil {
+		t.Fatalf("expected matching chain ID to pass, got %s", err)
+	}
+	if err := (ChainConfig{ExpectedChainID: 14}).CheckChainID(big.NewInt(19)); err == nil {
+		t.Fatal("expected error for a different chain ID, got nil")
+	}
+}
+
 func TestGetHistoryDropRejectsExcessiveValue(t *testing.T) {
 	tooLarge := maxHistoryDropSeconds + 1
 	cfg := DBConfig{HistoryDrop: &tooLarge}
@@ -81,3 +100,106 @@ func TestParseConfigFileAcceptsCurrentTimeoutKey(t *testing.T) {
 		t.Fatalf("rpc_timeout_millis not decoded: got %d", cfg.Timeout.RPCTimeoutMillis)
 	}
 }
+
+func TestNormalizeDBConfigPartitioning(t *testing.T) {
+	cfg := DBConfig{Partitioning: " Timestamp "}
+	if err := normalizeDBConfig(&cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.Partitioning != PartitioningTimestamp || cfg.PartitionSize != defaultPartitionSize || cfg.PartitionsAhead != defaultPartitionsAhead {
+		t.Fatalf("partitioning defaults not applied: %+v", cfg)
+	}
+
+	cfg = DBConfig{Partitioning: "hash"}
+	if err := normalizeDBConfig(&cfg); err == nil {
+		t.Fatal("expected error for unknown partitioning mode, got nil")
+	}
+}
+
+func TestNormalizeDBConfigStorageFormat(t *testing.T) {
+	cfg := DBConfig{}
+	if err := normalizeDBConfig(&cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.StorageFormat != StorageFormatHex {
+		t.Fatalf("expected default storage format %q, got %q", StorageFormatHex, cfg.StorageFormat)
+	}
+
+	cfg = DBConfig{StorageFormat: "BINARY"}
+	if err := normalizeDBConfig(&cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.StorageFormat != StorageFormatBinary {
+		t.Fatalf("expected storage format %q, got %q", StorageFormatBinary, cfg.StorageFormat)
+	}
+
+	cfg = DBConfig{StorageFormat: "base64"}
+	if err := normalizeDBConfig(&cfg); err == nil {
+		t.Fatal("expected error for unknown storage format, got nil")
+	}
+}
+
+func TestNormalizeLoggerConfig(t *testing.T) {
+	cfg := LoggerConfig{Level: "INFO"}
+	if err := normalizeLoggerConfig(&cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.Format != "console" {
+		t.Fatalf("expected default log format %q, got %q", "console", cfg.Format)
+	}
+
+	cfg = LoggerConfig{Level: "INFO", Format: "JSON", Levels: map[string]string{"chain": "DEBUG"}}
+	if err := normalizeLoggerConfig(&cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.Format != "json" {
+		t.Fatalf("expected log format %q, got %q", "json", cfg.Format)
+	}
+
+	for name, bad := range map[string]LoggerConfig{
+		"format":    {Level: "INFO", Format: "logfmt"},
+		"level":     {Level: "LOUD"},
+		"subsystem": {Level: "INFO", Levels: map[string]string{"rpc": "DEBUG"}},
+		"override":  {Level: "INFO", Levels: map[string]string{"fsp": "LOUD"}},
+	} {
+		if err := normalizeLoggerConfig(&bad); err == nil {
+			t.Fatalf("expected error for invalid %s, got nil", name)
+		}
+	}
+}
+
+func TestNormalizeTracingConfig(t *testing.T) {
+	cfg := TracingConfig{}
+	if err := normalizeTracingConfig(&cfg); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if cfg.SampleRatio != 1 || cfg.ServiceName != defaultTraceServiceName {
+		t.Fatalf("expected defaults, got sample_ratio=%v service_name=%q", cfg.SampleRatio, cfg.ServiceName)
+	}
+
+	cfg = TracingConfig{SampleRatio: 1.5}
+	if err := normalizeTracingConfig(&cfg); err == nil {
+		t.Fatal("expected error for sample_ratio above 1, got nil")
+	}
+}
+
+func TestNormalizeHealthConfigLivenessTimeout(t *testing.T) {
+	seconds := func(s int) *int { return &s }
+	for _, tc := range []struct {
+		name    string
+		health  HealthConfig
+		timeout TimeoutConfig
+		want    uint64
+	}{
+		{"default backoff", HealthConfig{}, TimeoutConfig{}, 600},
+		{"long backoff", HealthConfig{}, TimeoutConfig{BackoffMaxElapsedTimeSeconds: seconds(900)}, 1800},
+		{"endless backoff", HealthConfig{}, TimeoutConfig{BackoffMaxElapsedTimeSeconds: seconds(0)}, 600},
+		{"configured", HealthConfig{LivenessTimeoutSeconds: 120}, TimeoutConfig{BackoffMaxElapsedTimeSeconds: seconds(900)}, 120},
+	} {
+		cfg := tc.health
+		normalizeHealthConfig(&cfg, tc.timeout)
+		if cfg.LivenessTimeoutSeconds != tc.want {
+			t.Fatalf("%s: expected liveness_timeout_seconds %d, got %d", tc.name, tc.want, cfg.LivenessTimeoutSeconds)
+		}
+	}
+}
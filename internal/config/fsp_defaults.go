@@ -50,10 +50,19 @@ var rewardEpochLogs = []LogInfo{
 	{ContractName: "FastUpdateIncentiveManager", Topic: getTopic(fumanager.FUManagerMetaData, "InflationRewardsOffered")},
 	{ContractName: "FdcHub", Topic: getTopic(fdchub.FdcHubMetaData, "InflationRewardsOffered")},
 	// Updated contracts with new event signatures:
-	{ContractName: "VoterRegistry", Topic: "0xbfb6cd90b6e2668916d9e034926c84f40bcf94094b0d625ec8eecfdeb2150ae1"},          // VoterRegistryNext.VoterRegistered
-	{ContractName: "FlareSystemsCalculator", Topic: "0xc49a5cabcc0776ace8cfd024e155bc303ee5e492b29d59f1ff7dbafa0b34a04b"}, // FlareSystemsCalculatorNext.VoterRegistrationInfo
+	{ContractName: "VoterRegistry", Topic: VoterRegisteredNextTopic},
+	{ContractName: "FlareSystemsCalculator", Topic: VoterRegistrationInfoNextTopic},
 }
 
+// Topics of the events of the updated contracts, which have no bindings yet.
+// FlareSystemsCalculatorNext.VoterRegistrationInfo only changes the reward
+// epoch ID to a uint32; the VoterRegistryNext.VoterRegistered signature is
+// not known.
+const (
+	VoterRegisteredNextTopic       = "0xbfb6cd90b6e2668916d9e034926c84f40bcf94094b0d625ec8eecfdeb2150ae1" // VoterRegistryNext.VoterRegistered
+	VoterRegistrationInfoNextTopic = "0xc49a5cabcc0776ace8cfd024e155bc303ee5e492b29d59f1ff7dbafa0b34a04b" // FlareSystemsCalculatorNext.VoterRegistrationInfo
+)
+
 // Events emitted anytime during voting rounds, requires full indexing
 var roundLogs = []LogInfo{
 	{ContractName: "FastUpdater"},
@@ -125,3 +125,44 @@ func containsLog(logs []LogInfo, contractName, topic string) bool {
 	}
 	return false
 }
+
+func TestNormalizeIndexerConfig_FspMergesSenderFilters(t *testing.T) {
+	cfg := IndexerConfig{
+		Mode: IndexerModeFsp,
+		CollectTransactions: []TransactionInfo{
+			// Same senders as the next entry, listed differently: merged.
+			{FromAddress: "0x00000000000000000000000000000000000000AA", FromAddresses: []string{"0x00000000000000000000000000000000000000bb"}},
+			{FromAddresses: []string{"0x00000000000000000000000000000000000000bb", "0x00000000000000000000000000000000000000aa"}, Status: true},
+			// A sender-restricted copy of a default collector stays apart.
+			{ContractName: "Submission", FuncSig: "6c532fae", FromAddress: "0x00000000000000000000000000000000000000aa", Status: true},
+		},
+	}
+
+	if err := normalizeIndexerConfig(&cfg); err != nil {
+		t.Fatalf("normalizeIndexerConfig: %v", err)
+	}
+
+	var senderOnly, submissions int
+	for _, tx := range cfg.CollectTransactions {
+		if tx.AnyContract() {
+			senderOnly++
+			if !tx.Status {
+				t.Fatalf("sender filter flags not merged: %+v", tx)
+			}
+		}
+		if tx.ContractName == "Submission" && tx.FuncSig == "6c532fae" {
+			submissions++
+		}
+	}
+	if senderOnly != 1 {
+		t.Fatalf("expected the sender filters to merge into 1, got %d", senderOnly)
+	}
+	if submissions != 2 {
+		t.Fatalf("expected the sender-restricted submission collector to stay apart, got %d", submissions)
+	}
+
+	tx, ok := findTransaction(cfg.CollectTransactions, "Submission", "6c532fae")
+	if !ok || len(tx.Senders()) != 0 || tx.Status {
+		t.Fatalf("default submission collector changed by a sender filter: %+v", tx)
+	}
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// CollectionHash fingerprints the settings that decide which rows the
+// indexer collects: the mode, the transaction, transfer and log filters as
+// configured (before contract names are resolved) and call tracing. Two indexers with
+// the same hash fill their tables the same way.
+func (c *Config) CollectionHash() string {
+	content, err := json.Marshal(struct {
+		Mode                string
+		CollectTransactions []TransactionInfo
+		CollectLogs         []LogInfo
+		// omitempty keeps the hash of configs without these unchanged.
+		TraceCalls       bool           `json:",omitempty"`
+		CollectTransfers []TransferInfo `json:",omitempty"`
+	}{c.Indexer.Mode, c.Indexer.CollectTransactions, c.Indexer.CollectLogs, c.Indexer.TraceCalls, c.Indexer.CollectTransfers})
+	if err != nil {
+		// Plain structs of strings and bools always marshal.
+		panic(err)
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
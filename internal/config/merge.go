@@ -1,6 +1,9 @@
 package config
 
-import "strings"
+import (
+	"slices"
+	"strings"
+)
 
 // mergeFspCollectors combines the default and user specified transaction and log configs
 func mergeFspCollectors(
@@ -49,7 +52,20 @@ func mergeFspCollectors(
 func txDedupKey(tx *TransactionInfo) string {
 	funcSig := strings.ToLower(strings.TrimSpace(tx.FuncSig))
 	funcSig = strings.TrimPrefix(funcSig, "0x")
-	return contractDedupKey(tx.ContractAddress, tx.ContractName) + "|sig:" + funcSig
+	return contractDedupKey(tx.ContractAddress, tx.ContractName) + "|sig:" + funcSig + "|from:" + sendersDedupKey(tx)
+}
+
+// sendersDedupKey is the set of senders of a filter: filters only merge when
+// they restrict the same senders, listed in any order or split across
+// from_address and from_addresses, so merging never widens or narrows whom a
+// filter matches.
+func sendersDedupKey(tx *TransactionInfo) string {
+	senders := tx.Senders()
+	for i := range senders {
+		senders[i] = strings.TrimPrefix(strings.ToLower(senders[i]), "0x")
+	}
+	slices.Sort(senders)
+	return strings.Join(slices.Compact(senders), ",")
 }
 
 func logDedupKey(log *LogInfo) string {
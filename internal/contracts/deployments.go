@@ -0,0 +1,124 @@
+package contracts
+
+import (
+	"cmp"
+	"context"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// Deployment is an address the ContractRegistry returns for a contract name
+// from block ActiveFrom on, until the next deployment of the name. An
+// ActiveFrom of 0 marks a known deployment active at unknown blocks, see
+// KnownDeployments.
+type Deployment struct {
+	Name       string
+	Address    common.Address
+	ActiveFrom uint64
+}
+
+// DiscoverDeployments returns the deployments of a contract name active
+// within the blocks [from, to], oldest first, by reading the registry at past
+// blocks: the address at from, with ActiveFrom set to from, and each change
+// after it, found by bisecting the range. The registry emits no events on
+// updates, so its state and the upgrades of KnownDeployments are the only
+// sources; the node must serve the state of the blocks in the range. A name replaced and later restored
+// between two probed blocks reads as unchanged, and blocks where the name is
+// not registered yield no deployment.
+func (r *ContractResolver) DiscoverDeployments(ctx context.Context, contractName string, from, to uint64) ([]Deployment, error) {
+	name := strings.TrimSpace(contractName)
+	if from > to {
+		return nil, errors.Errorf("discover deployments of %s: empty range [%d, %d]", name, from, to)
+	}
+
+	first, err := r.addressAt(ctx, name, from)
+	if err != nil {
+		return nil, err
+	}
+	last, err := r.addressAt(ctx, name, to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := r.addressChanges(ctx, name, from, first, to, last)
+	if err != nil {
+		return nil, err
+	}
+	deployments := append([]Deployment{{Name: name, Address: first, ActiveFrom: from}}, changes...)
+	return slices.DeleteFunc(deployments, func(d Deployment) bool { return d.Address == (common.Address{}) }), nil
+}
+
+// addressChanges returns the changes of a name's address within (lo, hi],
+// given its addresses at lo and hi.
+func (r *ContractResolver) addressChanges(
+	ctx context.Context, name string, lo uint64, atLo common.Address, hi uint64, atHi common.Address,
+) ([]Deployment, error) {
+	if atLo == atHi {
+		return nil, nil
+	}
+	if hi == lo+1 {
+		return []Deployment{{Name: name, Address: atHi, ActiveFrom: hi}}, nil
+	}
+
+	mid := lo + (hi-lo)/2
+	atMid, err := r.addressAt(ctx, name, mid)
+	if err != nil {
+		return nil, err
+	}
+	before, err := r.addressChanges(ctx, name, lo, atLo, mid, atMid)
+	if err != nil {
+		return nil, err
+	}
+	after, err := r.addressChanges(ctx, name, mid, atMid, hi, atHi)
+	if err != nil {
+		return nil, err
+	}
+	return append(before, after...), nil
+}
+
+// addressAt reads the registry address of a name at a block; the zero
+// address when the name or the registry itself did not exist yet.
+func (r *ContractResolver) addressAt(ctx context.Context, name string, block uint64) (common.Address, error) {
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(block)}
+	address, err := r.registry.GetContractAddressByName(opts, name)
+	if errors.Is(err, bind.ErrNoCode) {
+		return common.Address{}, nil
+	}
+	if err != nil {
+		return common.Address{}, errors.Wrapf(err, "read address of %s at block %d", name, block)
+	}
+	return address, nil
+}
+
+// SetDeployments makes ResolveAllByName return, besides the registry
+// address, every deployment active at or after block from, and every one
+// with ActiveFrom 0. It replaces the deployments set before.
+func (r *ContractResolver) SetDeployments(deployments []Deployment, from uint64) {
+	byName := make(map[string][]Deployment)
+	for _, d := range deployments {
+		byName[d.Name] = append(byName[d.Name], d)
+	}
+
+	active := make(map[string][]common.Address, len(byName))
+	for name, named := range byName {
+		slices.SortFunc(named, func(a, b Deployment) int { return cmp.Compare(a.ActiveFrom, b.ActiveFrom) })
+		for i, d := range named {
+			// A deployment is active until the next one starts. Those with
+			// ActiveFrom 0 sort first and are kept, as their blocks are
+			// unknown.
+			if d.ActiveFrom != 0 && i+1 < len(named) && named[i+1].ActiveFrom <= from {
+				continue
+			}
+			active[name] = append(active[name], d.Address)
+		}
+	}
+
+	r.mu.Lock()
+	r.deployments = active
+	r.mu.Unlock()
+}
@@ -2,8 +2,10 @@ package contracts
 
 import (
 	"context"
+	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts/contractregistry"
 
@@ -17,7 +19,18 @@ var contractRegistryAddress = common.HexToAddress("0xaD67FE66660Fb8dFE9d6b1b4240
 type ContractResolver struct {
 	registry *contractregistry.ContractRegistryCaller
 	cache    map[string]common.Address
-	mu       sync.RWMutex
+	// retired holds, per name, the addresses the registry replaced less than
+	// a grace period ago (see Refresh).
+	retired map[string][]retiredAddress
+	// deployments holds, per name, the earlier addresses active within the
+	// indexed range (see SetDeployments).
+	deployments map[string][]common.Address
+	mu          sync.RWMutex
+}
+
+type retiredAddress struct {
+	address common.Address
+	until   time.Time
 }
 
 func NewContractResolver(rpcClient bind.ContractCaller) (*ContractResolver, error) {
@@ -29,6 +42,7 @@ func NewContractResolver(rpcClient bind.ContractCaller) (*ContractResolver, erro
 	return &ContractResolver{
 		registry: registry,
 		cache:    make(map[string]common.Address),
+		retired:  make(map[string][]retiredAddress),
 	}, nil
 }
 
@@ -60,13 +74,100 @@ func (r *ContractResolver) ResolveByName(ctx context.Context, contractName strin
 	return address, nil
 }
 
-// ResolveAllByName returns both deployments of a known upgraded FSP contract.
-// For all other contracts it returns the registry address.
+// ResolveAllByName returns the registry address of a contract followed by
+// its earlier deployments that were active within the indexed range (see
+// SetDeployments) and the addresses the registry replaced within their grace
+// period.
 func (r *ContractResolver) ResolveAllByName(ctx context.Context, contractName string) ([]common.Address, error) {
 	address, err := r.ResolveByName(ctx, contractName)
 	if err != nil {
 		return nil, err
 	}
 
-	return addressesForUpgradedContract(strings.TrimSpace(contractName), address), nil
+	name := strings.TrimSpace(contractName)
+	addresses := []common.Address{address}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, deployed := range r.deployments[name] {
+		if !slices.Contains(addresses, deployed) {
+			addresses = append(addresses, deployed)
+		}
+	}
+	for _, retired := range r.retired[name] {
+		if !slices.Contains(addresses, retired.address) {
+			addresses = append(addresses, retired.address)
+		}
+	}
+	return addresses, nil
+}
+
+// Names returns the resolved contract names, sorted.
+func (r *ContractResolver) Names() []string {
+	r.mu.RLock()
+	names := make([]string, 0, len(r.cache))
+	for name := range r.cache {
+		names = append(names, name)
+	}
+	r.mu.RUnlock()
+
+	slices.Sort(names)
+	return names
+}
+
+// Refresh re-reads the registry address of every resolved name in one call.
+// An address the registry replaced stays in ResolveAllByName's result for
+// grace, so the events of transactions still sent to the old deployment are
+// collected. changed reports whether the result of ResolveAllByName changed
+// for any name, by a replacement or by the end of a grace period.
+func (r *ContractResolver) Refresh(ctx context.Context, grace time.Duration) (changed bool, err error) {
+	names := r.Names()
+	if len(names) == 0 {
+		return false, nil
+	}
+
+	addresses, err := r.registry.GetContractAddressesByName(&bind.CallOpts{Context: ctx}, names)
+	if err != nil {
+		return false, errors.Wrap(err, "refresh contract addresses")
+	}
+	if len(addresses) != len(names) {
+		return false, errors.Errorf("refresh contract addresses: got %d addresses for %d names", len(addresses), len(names))
+	}
+
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, retired := range r.retired {
+		kept := slices.DeleteFunc(retired, func(a retiredAddress) bool { return !now.Before(a.until) })
+		if len(kept) != len(retired) {
+			changed = true
+		}
+		if len(kept) == 0 {
+			delete(r.retired, name)
+		} else {
+			r.retired[name] = kept
+		}
+	}
+
+	for i, name := range names {
+		address, current := addresses[i], r.cache[name]
+		// A name removed from the registry keeps its last address.
+		if address == (common.Address{}) || address == current {
+			continue
+		}
+		changed = true
+		r.cache[name] = address
+		retired := slices.DeleteFunc(r.retired[name], func(a retiredAddress) bool { return a.address == address })
+		if grace > 0 {
+			retired = append(retired, retiredAddress{address: current, until: now.Add(grace)})
+		}
+		if len(retired) == 0 {
+			delete(r.retired, name)
+		} else {
+			r.retired[name] = retired
+		}
+	}
+
+	return changed, nil
 }
@@ -0,0 +1,179 @@
+package contracts
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts/contractregistry"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry answers the ContractRegistry name lookups from a map, and
+// those at past blocks from the deployments in history.
+type fakeRegistry struct {
+	t         *testing.T
+	addresses map[string]common.Address
+	history   []Deployment
+	pastCalls int
+}
+
+func (f *fakeRegistry) addressAt(name string, block *big.Int) common.Address {
+	if block == nil {
+		return f.addresses[name]
+	}
+	f.pastCalls++
+	var address common.Address
+	for _, d := range f.history {
+		if d.Name == name && d.ActiveFrom <= block.Uint64() {
+			address = d.Address
+		}
+	}
+	return address
+}
+
+func (f *fakeRegistry) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (f *fakeRegistry) CallContract(_ context.Context, call ethereum.CallMsg, block *big.Int) ([]byte, error) {
+	parsed, err := contractregistry.ContractRegistryMetaData.GetAbi()
+	require.NoError(f.t, err)
+	method, err := parsed.MethodById(call.Data[:4])
+	require.NoError(f.t, err)
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	require.NoError(f.t, err)
+
+	switch method.Name {
+	case "getContractAddressByName":
+		return method.Outputs.Pack(f.addressAt(args[0].(string), block))
+	case "getContractAddressesByName":
+		var addresses []common.Address
+		for _, name := range args[0].([]string) {
+			addresses = append(addresses, f.addresses[name])
+		}
+		return method.Outputs.Pack(addresses)
+	}
+	f.t.Fatalf("unexpected call to %s", method.Name)
+	return nil, nil
+}
+
+func TestResolverRefresh(t *testing.T) {
+	ctx := context.Background()
+	first := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	second := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+
+	registry := &fakeRegistry{t: t, addresses: map[string]common.Address{"Submission": first, "Relay": other}}
+	resolver, err := NewContractResolver(registry)
+	require.NoError(t, err)
+
+	changed, err := resolver.Refresh(ctx, time.Hour)
+	require.NoError(t, err)
+	require.False(t, changed, "nothing resolved yet")
+
+	_, err = resolver.ResolveByName(ctx, "Submission")
+	require.NoError(t, err)
+	_, err = resolver.ResolveByName(ctx, "Relay")
+	require.NoError(t, err)
+
+	changed, err = resolver.Refresh(ctx, time.Hour)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	registry.addresses["Submission"] = second
+	changed, err = resolver.Refresh(ctx, time.Hour)
+	require.NoError(t, err)
+	require.True(t, changed)
+
+	address, err := resolver.ResolveByName(ctx, "Submission")
+	require.NoError(t, err)
+	require.Equal(t, second, address)
+	all, err := resolver.ResolveAllByName(ctx, "Submission")
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{second, first}, all, "the replaced address is kept for the grace period")
+	all, err = resolver.ResolveAllByName(ctx, "Relay")
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{other}, all)
+
+	// The grace period ends.
+	resolver.retired["Submission"][0].until = time.Now()
+	changed, err = resolver.Refresh(ctx, time.Hour)
+	require.NoError(t, err)
+	require.True(t, changed)
+	all, err = resolver.ResolveAllByName(ctx, "Submission")
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{second}, all)
+
+	// A name dropped from the registry keeps its last address.
+	delete(registry.addresses, "Relay")
+	changed, err = resolver.Refresh(ctx, time.Hour)
+	require.NoError(t, err)
+	require.False(t, changed)
+	address, err = resolver.ResolveByName(ctx, "Relay")
+	require.NoError(t, err)
+	require.Equal(t, other, address)
+}
+
+func TestDiscoverDeployments(t *testing.T) {
+	ctx := context.Background()
+	first := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	second := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	third := common.HexToAddress("0x00000000000000000000000000000000000000a3")
+
+	registry := &fakeRegistry{t: t, history: []Deployment{
+		{Name: "VoterRegistry", Address: first, ActiveFrom: 100},
+		{Name: "VoterRegistry", Address: second, ActiveFrom: 5000},
+		{Name: "VoterRegistry", Address: third, ActiveFrom: 5001},
+	}}
+	resolver, err := NewContractResolver(registry)
+	require.NoError(t, err)
+
+	deployments, err := resolver.DiscoverDeployments(ctx, "VoterRegistry", 0, 1_000_000)
+	require.NoError(t, err)
+	require.Equal(t, registry.history, deployments, "the registry had no address before block 100")
+	require.Less(t, registry.pastCalls, 100, "changes are found by bisection")
+
+	deployments, err = resolver.DiscoverDeployments(ctx, "VoterRegistry", 4000, 4500)
+	require.NoError(t, err)
+	require.Equal(t, []Deployment{{Name: "VoterRegistry", Address: first, ActiveFrom: 4000}}, deployments)
+
+	deployments, err = resolver.DiscoverDeployments(ctx, "Relay", 0, 1000)
+	require.NoError(t, err)
+	require.Empty(t, deployments)
+}
+
+func TestSetDeployments(t *testing.T) {
+	ctx := context.Background()
+	first := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	second := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	third := common.HexToAddress("0x00000000000000000000000000000000000000a3")
+
+	registry := &fakeRegistry{t: t, addresses: map[string]common.Address{"VoterRegistry": third}}
+	resolver, err := NewContractResolver(registry)
+	require.NoError(t, err)
+	deployments := []Deployment{
+		{Name: "VoterRegistry", Address: third, ActiveFrom: 9000},
+		{Name: "VoterRegistry", Address: first, ActiveFrom: 100},
+		{Name: "VoterRegistry", Address: second, ActiveFrom: 5000},
+	}
+
+	resolver.SetDeployments(deployments, 6000)
+	all, err := resolver.ResolveAllByName(ctx, "VoterRegistry")
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{third, second}, all, "first was replaced before the range")
+
+	resolver.SetDeployments(deployments, 5000)
+	all, err = resolver.ResolveAllByName(ctx, "VoterRegistry")
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{third, second}, all)
+
+	resolver.SetDeployments(deployments, 4999)
+	all, err = resolver.ResolveAllByName(ctx, "VoterRegistry")
+	require.NoError(t, err)
+	require.Equal(t, []common.Address{third, first, second}, all)
+}
@@ -1,20 +1,34 @@
 package contracts
 
 import (
+	"context"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/stretchr/testify/require"
 )
 
-func TestAddressesForUpgradedContract(t *testing.T) {
-	oldAddress := common.HexToAddress("0x2580101692366e2f331e891180d9ffdF861Fce83")
-	newAddress := common.HexToAddress("0xA480457953Af3583E54DCd630b219353B8FC9Af7")
+func TestKnownDeployments(t *testing.T) {
+	ctx := context.Background()
+	for _, c := range fspUpgradedContracts {
+		for _, current := range []common.Address{c.old, c.new} {
+			registry := &fakeRegistry{t: t, addresses: map[string]common.Address{c.name: current}}
+			resolver, err := NewContractResolver(registry)
+			require.NoError(t, err)
 
-	require.Equal(t, []common.Address{oldAddress, newAddress}, addressesForUpgradedContract("VoterRegistry", oldAddress))
-	require.Equal(t, []common.Address{oldAddress, newAddress}, addressesForUpgradedContract("VoterRegistry", newAddress))
+			// Far past any upgrade, and with a later deployment discovered
+			// on top of the seed.
+			later := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+			deployments := append(KnownDeployments(c.name, current), Deployment{Name: c.name, Address: later, ActiveFrom: 100})
+			resolver.SetDeployments(deployments, 1_000_000)
 
-	unrelated := common.HexToAddress("0x0000000000000000000000000000000000000001")
-	require.Equal(t, []common.Address{unrelated}, addressesForUpgradedContract("VoterRegistry", unrelated))
-	require.Equal(t, []common.Address{oldAddress}, addressesForUpgradedContract("OtherContract", oldAddress))
+			all, err := resolver.ResolveAllByName(ctx, c.name)
+			require.NoError(t, err)
+			require.ElementsMatch(t, []common.Address{c.old, c.new, later}, all, "%s at %s", c.name, current)
+		}
+	}
+
+	unrelated := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	require.Empty(t, KnownDeployments("VoterRegistry", unrelated))
+	require.Empty(t, KnownDeployments("OtherContract", fspUpgradedContracts[0].old))
 }
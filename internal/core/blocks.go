@@ -17,6 +17,9 @@ import (
 
 type blockBatch struct {
 	blocks []*chain.Block
+	// traces holds the call traces of each block when indexer.trace_calls
+	// is set, nil otherwise.
+	traces [][]chain.TransactionTrace
 	mu     sync.RWMutex
 }
 
@@ -82,64 +85,115 @@ func (ci *Engine) fetchLastBlockIndex(ctx context.Context) (uint64, uint64, erro
 	return latestConfirmedNumber, latestConfirmedHeader.Time(), nil
 }
 
+// fetchBlockTimestamp looks the timestamp up in the client's header cache,
+// then in the blocks table when indexer.timestamps_from_db is set, and only
+// then asks the node for the header.
 func (ci *Engine) fetchBlockTimestamp(ctx context.Context, index uint64) (uint64, error) {
-	lastBlock, err := ci.fetchBlockHeader(ctx, &index)
+	timestamp, err := boff.RetryWithMaxElapsed(
+		ctx,
+		func() (uint64, error) {
+			ctx, cancelFunc := context.WithTimeout(ctx, config.RPCTimeout)
+			defer cancelFunc()
+
+			return ci.client.BlockTimestamp(ctx, index)
+		},
+		"fetchBlockTimestamp",
+	)
 	if err != nil {
-		return 0, errors.Wrap(err, "fetchBlockHeader")
+		return 0, errors.Wrap(err, "fetchBlockTimestamp")
 	}
 
-	return lastBlock.Time(), nil
+	return timestamp, nil
 }
 
 func (ci *Engine) processBlocks(
 	bBatch *blockBatch, txBatch *transactionsBatch,
-) {
+) error {
 	for i := range bBatch.blocks {
-		ci.processBlockBatch(bBatch, txBatch, uint64(i))
+		if err := ci.processBlockBatch(bBatch, txBatch, uint64(i)); err != nil {
+			return err
+		}
 	}
+
+	return nil
 }
 
 func (ci *Engine) processBlockBatch(
 	bBatch *blockBatch, txBatch *transactionsBatch, i uint64,
-) {
+) error {
 	bBatch.mu.RLock()
 	defer bBatch.mu.RUnlock()
 
 	block := bBatch.blocks[i]
+	var traces []chain.TransactionTrace
+	if bBatch.traces != nil {
+		traces = bBatch.traces[i]
+	}
 
 	for txIndex, tx := range block.Transactions() {
-		if tx.To() == nil {
-			continue
+		policy, check, err := ci.matchCall(tx.FromAddress, tx.To(), tx.Data())
+		if err != nil {
+			return errors.Wrapf(err, "sender of transaction %s", tx.Hash())
 		}
-
-		txData := tx.Data()
-		if len(txData) < 4 {
-			continue
+		if !check {
+			// Calls and plain transfers are told apart by their calldata,
+			// so at most one of the two matchers recovers the sender.
+			policy, check, err = ci.matchTransfer(tx.FromAddress, tx.To(), tx.Data(), tx.Value())
+			if err != nil {
+				return errors.Wrapf(err, "sender of transaction %s", tx.Hash())
+			}
 		}
 
-		var funcSig functionSignature
-		copy(funcSig[:], txData[:4])
-
-		contractAddress := tx.To()
-		check := false
-		var policy transactionsPolicy
-
-		for _, address := range []common.Address{*contractAddress, undefinedAddress} {
-			if val, ok := ci.transactions[address]; ok {
-				for _, sig := range []functionSignature{funcSig, undefinedFuncSig} {
-					if pol, ok := val[sig]; ok {
-						check = true
-						policy.status = policy.status || pol.status
-						policy.collectEvents = policy.collectEvents || pol.collectEvents
-					}
-				}
+		// A transaction whose internal calls match a filter is collected
+		// with them, under the policy of the filters they matched.
+		var calls []internalCall
+		if traces != nil {
+			var callsPolicy transactionsPolicy
+			calls, callsPolicy = ci.matchInternalCalls(traces[txIndex].Result)
+			if len(calls) > 0 {
+				check = true
+				policy = policy.merge(callsPolicy)
 			}
 		}
 
 		if check {
-			txBatch.Add(tx, block, uint64(txIndex), nil, policy)
+			txBatch.Add(tx, block, uint64(txIndex), nil, policy, calls)
+		}
+	}
+
+	return nil
+}
+
+// matchCall returns the merged policy of the collect_transactions filters
+// matching a call to the address to with the given calldata. sender returns
+// the caller; it is only called when some filter is restricted to senders.
+func (ci *Engine) matchCall(
+	sender func() (common.Address, error), to *common.Address, input []byte,
+) (transactionsPolicy, bool, error) {
+	if to == nil || len(input) < 4 {
+		return transactionsPolicy{}, false, nil
+	}
+
+	var funcSig functionSignature
+	copy(funcSig[:], input[:4])
+
+	policy, check := ci.transactions.any.match(*to, funcSig)
+	if len(ci.transactions.senders) == 0 {
+		return policy, check, nil
+	}
+
+	from, err := sender()
+	if err != nil {
+		return transactionsPolicy{}, false, err
+	}
+	if table, ok := ci.transactions.senders[from]; ok {
+		if pol, ok := table.match(*to, funcSig); ok {
+			check = true
+			policy = policy.merge(pol)
 		}
 	}
+
+	return policy, check, nil
 }
 
 func (ci *Engine) convertBlocksToDB(bBatch *blockBatch) []*database.Block {
@@ -0,0 +1,69 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchCallSenders(t *testing.T) {
+	contract := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	voter := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	stranger := common.HexToAddress("0x00000000000000000000000000000000000000f2")
+
+	txs, err := buildTransactionPolicies([]config.TransactionInfo{
+		{ContractAddress: contract.Hex(), FuncSig: "12345678"},
+		{FromAddresses: []string{voter.Hex()}, FuncSig: undefined, Status: true},
+		{ContractAddress: contract.Hex(), FuncSig: undefined, FromAddress: voter.Hex(), CollectEvents: true},
+	})
+	require.NoError(t, err)
+	ci := &Engine{transactions: txs}
+
+	calls := 0
+	from := func(address common.Address) func() (common.Address, error) {
+		return func() (common.Address, error) {
+			calls++
+			return address, nil
+		}
+	}
+
+	// Sender and recipient filters combine, with one sender lookup.
+	policy, ok, err := ci.matchCall(from(voter), &contract, []byte{0x12, 0x34, 0x56, 0x78})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, transactionsPolicy{status: true, collectEvents: true}, policy)
+	require.Equal(t, 1, calls)
+
+	// The voter's calls to any contract match.
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	policy, ok, err = ci.matchCall(from(voter), &other, []byte{9, 9, 9, 9})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, transactionsPolicy{status: true}, policy)
+
+	_, ok, err = ci.matchCall(from(stranger), &other, []byte{9, 9, 9, 9})
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	_, _, err = ci.matchCall(func() (common.Address, error) { return common.Address{}, errors.New("bad signature") }, &other, []byte{9, 9, 9, 9})
+	require.Error(t, err)
+
+	// Without sender filters the sender is never recovered.
+	txs, err = buildTransactionPolicies([]config.TransactionInfo{{ContractAddress: contract.Hex(), FuncSig: undefined}})
+	require.NoError(t, err)
+	ci = &Engine{transactions: txs}
+	calls = 0
+	_, ok, err = ci.matchCall(from(voter), &contract, []byte{1, 2, 3, 4})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Zero(t, calls)
+}
+
+func TestBuildTransactionPoliciesRejectsBadSender(t *testing.T) {
+	_, err := buildTransactionPolicies([]config.TransactionInfo{{FromAddress: "0x1234", FuncSig: undefined}})
+	require.Error(t, err)
+}
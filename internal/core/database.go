@@ -1,9 +1,16 @@
 package core
 
 import (
+	"context"
+	"time"
+
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/tracing"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -12,9 +19,39 @@ type databaseStructData struct {
 	Blocks            []*database.Block
 	Transactions      []*database.Transaction
 	Logs              []*database.Log
+	InternalCalls     []*database.InternalCall
 	LogHashIndexCheck map[string]bool
 }
 
+// SavedRows are the rows of one write, handed to the save hooks.
+type SavedRows struct {
+	Transactions []*database.Transaction
+	Logs         []*database.Log
+}
+
+// SaveHook derives further rows from the rows of a write, e.g. the decoded
+// FSP tables. It runs inside the write's database transaction, so what it
+// writes commits or rolls back with the rows. Rows may have been written
+// before (writes are idempotent), so hooks must be too.
+type SaveHook func(tx *gorm.DB, rows SavedRows) error
+
+// AddSaveHook registers a hook run on every batch write. It must be called
+// before indexing starts.
+func (ci *Engine) AddSaveHook(hook SaveHook) {
+	ci.saveHooks = append(ci.saveHooks, hook)
+}
+
+// RunSaveHooks runs the save hooks on rows written outside of the engine's
+// batches, such as the FSP event backfill, inside that write's transaction.
+func (ci *Engine) RunSaveHooks(tx *gorm.DB, rows SavedRows) error {
+	for _, hook := range ci.saveHooks {
+		if err := hook(tx, rows); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func newDatabaseStructData() *databaseStructData {
 	return &databaseStructData{
 		LogHashIndexCheck: make(map[string]bool),
@@ -22,9 +59,19 @@ func newDatabaseStructData() *databaseStructData {
 }
 
 func (ci *Engine) saveData(
-	data *databaseStructData, lastDBIndex, lastDBTimestamp uint64,
-) error {
-	err := ci.db.Transaction(func(tx *gorm.DB) error {
+	ctx context.Context, data *databaseStructData, lastDBIndex, lastDBTimestamp uint64,
+) (err error) {
+	ctx, span := tracing.Start(
+		ctx, "core.saveData",
+		attribute.Int("blocks", len(data.Blocks)),
+		attribute.Int("transactions", len(data.Transactions)),
+		attribute.Int("logs", len(data.Logs)),
+		attribute.Int("internal_calls", len(data.InternalCalls)),
+	)
+	defer func() { tracing.End(span, err) }()
+
+	saveStart := time.Now()
+	err = ci.db.Transaction(func(tx *gorm.DB) error {
 		if len(data.Blocks) != 0 {
 			err := tx.Clauses(clause.Insert{Modifier: "IGNORE"}).
 				CreateInBatches(data.Blocks, database.DBTransactionBatchesSize).
@@ -54,12 +101,35 @@ func (ci *Engine) saveData(
 			}
 		}
 
+		if len(data.InternalCalls) != 0 {
+			err := tx.Clauses(clause.Insert{Modifier: "IGNORE"}).
+				CreateInBatches(data.InternalCalls, database.DBTransactionBatchesSize).
+				Error
+			if err != nil {
+				return errors.Wrap(err, "saveData: CreateInBatches3")
+			}
+		}
+
+		rows := SavedRows{Transactions: data.Transactions, Logs: data.Logs}
+		if err := ci.RunSaveHooks(tx, rows); err != nil {
+			return errors.Wrap(err, "saveData: save hooks")
+		}
+
 		return nil
 	})
 	if err != nil {
 		return err
 	}
 
+	logging.From(ctx, logging.Database).Debugw(
+		"Saved batch",
+		"blocks", len(data.Blocks),
+		"transactions", len(data.Transactions),
+		"logs", len(data.Logs),
+		"internal_calls", len(data.InternalCalls),
+		"duration_ms", time.Since(saveStart).Milliseconds(),
+	)
+
 	// Advance states only after the data transaction has committed, so they
 	// understate rather than overstate coverage. INSERT IGNORE makes the data
 	// writes idempotent, so a crash between that commit and these writes just
@@ -70,9 +140,16 @@ func (ci *Engine) saveData(
 	// re-indexed batch, and the pair must never be split (see its doc comment).
 	first := lowestBlock(data.Blocks)
 	if first == nil {
-		return database.UpdateState(ci.db, database.LastIndexed, lastDBIndex, lastDBTimestamp)
+		err = database.UpdateState(ci.db, database.LastIndexed, lastDBIndex, lastDBTimestamp)
+	} else {
+		err = database.WriteCoverageStates(ci.db, lastDBIndex, lastDBTimestamp, first.Number, first.Timestamp)
+	}
+	if err != nil {
+		return err
 	}
-	return database.WriteCoverageStates(ci.db, lastDBIndex, lastDBTimestamp, first.Number, first.Timestamp)
+
+	ready.RecordCommit(ctx, lastDBIndex)
+	return nil
 }
 
 func lowestBlock(blocks []*database.Block) *database.Block {
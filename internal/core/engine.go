@@ -5,23 +5,29 @@ import (
 	"encoding/hex"
 	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/admin"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/diagnostics"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/tracing"
 
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
 	"golang.org/x/sync/errgroup"
 	"gorm.io/gorm"
 )
 
 const (
-	nullTopic = "NULL"
+	nullTopic = database.NullTopic
 	numTopics = 4
 	undefined = "undefined"
 )
@@ -32,11 +38,23 @@ var (
 )
 
 type Engine struct {
-	db               *gorm.DB
-	params           config.IndexerConfig
-	transactions     map[common.Address]map[functionSignature]transactionsPolicy
+	db           *gorm.DB
+	params       config.IndexerConfig
+	transactions transactionFilters
+	transfers    []transferFilter
+	// logs are the collect_logs filters currently applied; params.CollectLogs
+	// keeps those of startup.
+	logs             []config.LogInfo
 	client           *chain.Client
 	contractResolver *contracts.ContractResolver
+	saveHooks        []SaveHook
+	contractsHooks   []ContractsHook
+	// contractsChanged is set by WatchContractRegistry when a contract was
+	// redeployed, and cleared when the filters are rebuilt between batches.
+	contractsChanged atomic.Bool
+	// transactionID is the ID of the next transaction row, loaded from the
+	// database when indexing starts (loadTransactionID).
+	transactionID atomic.Uint64
 }
 
 type transactionsPolicy struct {
@@ -44,6 +62,14 @@ type transactionsPolicy struct {
 	collectEvents bool
 }
 
+// merge combines the policies of several filters matching one transaction.
+func (p transactionsPolicy) merge(other transactionsPolicy) transactionsPolicy {
+	return transactionsPolicy{
+		status:        p.status || other.status,
+		collectEvents: p.collectEvents || other.collectEvents,
+	}
+}
+
 type functionSignature [4]byte
 
 func NewEngine(
@@ -56,6 +82,10 @@ func NewEngine(
 	if err != nil {
 		return nil, err
 	}
+	transfers, err := buildTransferFilters(cfg.Indexer.CollectTransfers)
+	if err != nil {
+		return nil, err
+	}
 	if err := validateCollectLogs(cfg.Indexer.CollectLogs); err != nil {
 		return nil, err
 	}
@@ -66,10 +96,16 @@ func NewEngine(
 	params := applyIndexerDefaults(cfg.Indexer)
 	diagnostics.LogIndexerPolicy(params)
 
+	if params.TimestampsFromDB {
+		client.SetTimestampSource(database.BlockTimestamps(db))
+	}
+
 	return &Engine{
 		db:               db,
 		params:           params,
 		transactions:     txs,
+		transfers:        transfers,
+		logs:             params.CollectLogs,
 		client:           client,
 		contractResolver: contractResolver,
 	}, nil
@@ -96,35 +132,97 @@ func applyIndexerDefaults(params config.IndexerConfig) config.IndexerConfig {
 		params.NewBlockCheckMillis = 100
 	}
 
+	if params.RegistryCheckSeconds == 0 {
+		params.RegistryCheckSeconds = defaultRegistryCheckSeconds
+	}
+
+	if params.RetiredContractGraceSeconds == 0 {
+		params.RetiredContractGraceSeconds = defaultRetiredContractGraceSeconds
+	}
+
 	return params
 }
 
-func buildTransactionPolicies(txInfo []config.TransactionInfo) (map[common.Address]map[functionSignature]transactionsPolicy, error) {
-	transactions := make(map[common.Address]map[functionSignature]transactionsPolicy)
+// transactionFilters are the collect_transactions filters. Filters restricted
+// to senders are kept apart by sender, so a transaction's sender is only
+// recovered when some filter needs it, and then once for all of them.
+type transactionFilters struct {
+	any     policyTable
+	senders map[common.Address]policyTable
+}
+
+// policyTable maps a recipient and a function selector, either of which may
+// be undefined, to the policy of the filters on them.
+type policyTable map[common.Address]map[functionSignature]transactionsPolicy
+
+func (t policyTable) add(address common.Address, funcSig functionSignature, policy transactionsPolicy) {
+	if _, ok := t[address]; !ok {
+		t[address] = make(map[functionSignature]transactionsPolicy)
+	}
+	t[address][funcSig] = t[address][funcSig].merge(policy)
+}
+
+// match returns the merged policy of the entries matching a call to the
+// address to with the selector funcSig, including the undefined ones.
+func (t policyTable) match(to common.Address, funcSig functionSignature) (transactionsPolicy, bool) {
+	var policy transactionsPolicy
+	check := false
+	for _, address := range []common.Address{to, undefinedAddress} {
+		if val, ok := t[address]; ok {
+			for _, sig := range []functionSignature{funcSig, undefinedFuncSig} {
+				if pol, ok := val[sig]; ok {
+					check = true
+					policy = policy.merge(pol)
+				}
+			}
+		}
+	}
+
+	return policy, check
+}
+
+func buildTransactionPolicies(txInfo []config.TransactionInfo) (transactionFilters, error) {
+	filters := transactionFilters{any: make(policyTable), senders: make(map[common.Address]policyTable)}
 
 	for i := range txInfo {
 		transaction := &txInfo[i]
-		contractAddress, err := parseTransactionAddress(transaction.ContractAddress)
-		if err != nil {
-			return nil, fmt.Errorf("parsing address %s: %w", transaction.ContractAddress, err)
+		address := transaction.ContractAddress
+		if transaction.AnyContract() {
+			address = undefined
 		}
-
-		if _, ok := transactions[contractAddress]; !ok {
-			transactions[contractAddress] = make(map[functionSignature]transactionsPolicy)
+		contractAddress, err := parseTransactionAddress(address)
+		if err != nil {
+			return transactionFilters{}, fmt.Errorf("parsing address %s: %w", transaction.ContractAddress, err)
 		}
 
 		funcSig, err := parseFuncSig(transaction.FuncSig)
 		if err != nil {
-			return nil, fmt.Errorf("parsing func sig %s: %w", transaction.FuncSig, err)
+			return transactionFilters{}, fmt.Errorf("parsing func sig %s: %w", transaction.FuncSig, err)
 		}
 
-		transactions[contractAddress][funcSig] = transactionsPolicy{
+		policy := transactionsPolicy{
 			status:        transaction.Status,
 			collectEvents: transaction.CollectEvents,
 		}
+
+		senders := transaction.Senders()
+		if len(senders) == 0 {
+			filters.any.add(contractAddress, funcSig, policy)
+			continue
+		}
+		for _, sender := range senders {
+			if !common.IsHexAddress(sender) {
+				return transactionFilters{}, fmt.Errorf("parsing from address %s: not an address", sender)
+			}
+			from := common.HexToAddress(sender)
+			if _, ok := filters.senders[from]; !ok {
+				filters.senders[from] = make(policyTable)
+			}
+			filters.senders[from].add(contractAddress, funcSig, policy)
+		}
 	}
 
-	return transactions, nil
+	return filters, nil
 }
 
 func parseFuncSig(funcSig string) (functionSignature, error) {
@@ -166,17 +264,29 @@ func parseTransactionAddress(address string) (common.Address, error) {
 }
 
 func (ci *Engine) IndexHistory(ctx context.Context, startIndex uint64) (uint64, error) {
+	if err := ci.loadTransactionID(); err != nil {
+		return 0, err
+	}
+
 	ixRange, err := ci.getIndexRange(ctx, startIndex)
 	if err != nil {
 		return 0, err
 	}
 
-	logger.Infof("Starting history indexing: from=%d, to=%d", ixRange.start, ixRange.end)
+	logging.From(ctx, logging.Engine).Infow("Starting history indexing", "from", ixRange.start, "to", ixRange.end)
+	status.SetPhase(ctx, status.PhaseCatchup)
+	status.StartCatchup(ctx, ixRange.start, ixRange.end)
 
 	for i := ixRange.start; i <= ixRange.end; i = i + ci.params.BatchSize {
-		if err := ci.indexBatch(ctx, i, ixRange); err != nil {
-			return 0, errors.Wrapf(err, "indexBatch: from=%d, to=%d", i, min(i+ci.params.BatchSize-1, ixRange.end))
+		ready.Heartbeat(ctx)
+		ci.applyContractChanges(ctx)
+		batchEnd := min(i+ci.params.BatchSize-1, ixRange.end)
+		// Every log line of the batch, down to its RPC calls and the
+		// commit, carries the same correlation ID.
+		if err := ci.indexBatch(logging.WithCorrelationID(ctx), i, ixRange); err != nil {
+			return 0, errors.Wrapf(err, "indexBatch: from=%d, to=%d", i, batchEnd)
 		}
+		status.RecordCatchup(ctx, batchEnd, ixRange.end)
 
 		// in the second to last run of the loop update lastIndex to get the blocks
 		// that were produced during the run of the algorithm
@@ -191,12 +301,32 @@ func (ci *Engine) IndexHistory(ctx context.Context, startIndex uint64) (uint64,
 	return ixRange.end, nil
 }
 
+// loadTransactionID continues transaction IDs after the highest stored one.
+// It runs at the start of every indexing pass, so IDs handed out to a batch
+// that failed to commit are reused.
+func (ci *Engine) loadTransactionID() error {
+	id, err := database.NextTransactionID(ci.db)
+	if err != nil {
+		return err
+	}
+	ci.transactionID.Store(id)
+	return nil
+}
+
 func (ci *Engine) indexBatch(
 	ctx context.Context, batchIx uint64, ixRange *indexRange,
-) error {
+) (err error) {
 	batchStart := time.Now()
 	lastBlockNumInRound := min(batchIx+ci.params.BatchSize-1, ixRange.end)
 
+	ctx, span := tracing.Start(
+		ctx, "core.indexBatch",
+		tracing.Block("from", batchIx),
+		tracing.Block("to", lastBlockNumInRound),
+		attribute.String(logging.CorrelationIDKey, logging.CorrelationID(ctx)),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	// Blocks (and the receipts derived from them) and logs are independent RPC
 	// streams: log queries need only the block range, not the fetched bodies.
 	// Fetch both concurrently and join before processing, so the handful of
@@ -218,7 +348,10 @@ func (ci *Engine) indexBatch(
 			return err
 		}
 
-		txBatch = ci.processBlocksBatch(bBatch)
+		txBatch, err = ci.processBlocksBatch(egCtx, bBatch)
+		if err != nil {
+			return err
+		}
 
 		return ci.processTransactionsBatch(egCtx, txBatch)
 	})
@@ -235,6 +368,7 @@ func (ci *Engine) indexBatch(
 
 	lastForDBTimestamp := bBatch.blocks[min(ci.params.BatchSize-1, ixRange.end-batchIx)].Time()
 	return ci.processAndSave(
+		ctx,
 		bBatch,
 		txBatch,
 		logsBatch,
@@ -250,6 +384,9 @@ func (ci *Engine) obtainBlocksBatch(ctx context.Context, firstBlockNumber uint64
 
 	batchSize := lastBlockNumInRound + 1 - firstBlockNumber
 	bBatch := newBlockBatch(batchSize)
+	if ci.params.TraceCalls {
+		bBatch.traces = make([][]chain.TransactionTrace, batchSize)
+	}
 
 	// SetLimit bounds goroutine fan-out so we don't spawn one goroutine per
 	// block up front; the real RPC concurrency cap is enforced globally in
@@ -270,6 +407,14 @@ func (ci *Engine) obtainBlocksBatch(ctx context.Context, firstBlockNumber uint64
 			// collision.
 			bBatch.blocks[num-firstBlockNumber] = block
 
+			if bBatch.traces != nil {
+				traces, err := ci.fetchBlockTrace(ctx, block)
+				if err != nil {
+					return err
+				}
+				bBatch.traces[num-firstBlockNumber] = traces
+			}
+
 			return nil
 		})
 	}
@@ -278,25 +423,30 @@ func (ci *Engine) obtainBlocksBatch(ctx context.Context, firstBlockNumber uint64
 		return nil, err
 	}
 
-	logger.Debugf(
-		"Fetched blocks: from=%d, to=%d, duration_ms=%d",
-		firstBlockNumber, lastBlockNumInRound, time.Since(startTime).Milliseconds(),
+	logging.From(ctx, logging.Engine).Debugw(
+		"Fetched blocks",
+		"from", firstBlockNumber,
+		"to", lastBlockNumInRound,
+		"duration_ms", time.Since(startTime).Milliseconds(),
 	)
 
 	return bBatch, nil
 }
 
-func (ci *Engine) processBlocksBatch(bBatch *blockBatch) *transactionsBatch {
+func (ci *Engine) processBlocksBatch(ctx context.Context, bBatch *blockBatch) (*transactionsBatch, error) {
 	startTime := time.Now()
 	txBatch := new(transactionsBatch)
 
-	ci.processBlocks(bBatch, txBatch)
-	logger.Debugf(
-		"Extracted transactions: count=%d, duration_ms=%d",
-		len(txBatch.transactions), time.Since(startTime).Milliseconds(),
+	if err := ci.processBlocks(bBatch, txBatch); err != nil {
+		return nil, err
+	}
+	logging.From(ctx, logging.Engine).Debugw(
+		"Extracted transactions",
+		"count", len(txBatch.transactions),
+		"duration_ms", time.Since(startTime).Milliseconds(),
 	)
 
-	return txBatch
+	return txBatch, nil
 }
 
 func (ci *Engine) processTransactionsBatch(
@@ -320,9 +470,10 @@ func (ci *Engine) processTransactionsBatch(
 		return err
 	}
 
-	logger.Debugf(
-		"Checked receipts: count=%d, duration_ms=%d",
-		countReceipts(txBatch), time.Since(startTime).Milliseconds(),
+	logging.From(ctx, logging.Engine).Debugw(
+		"Checked receipts",
+		"count", countReceipts(txBatch),
+		"duration_ms", time.Since(startTime).Milliseconds(),
 	)
 
 	return nil
@@ -337,7 +488,7 @@ func (ci *Engine) obtainLogsBatch(
 	// Fetch logs sequentially, one filter at a time. requestLogs walks
 	// [batchIx, lastBlockNumInRound] stepping by LogRange, so LogRange is simply
 	// the max number of blocks per eth_getLogs request.
-	for _, logInfo := range ci.params.CollectLogs {
+	for _, logInfo := range ci.logs {
 		if err := ci.requestLogs(
 			ctx,
 			lgBatch,
@@ -350,9 +501,10 @@ func (ci *Engine) obtainLogsBatch(
 		}
 	}
 
-	logger.Debugf(
-		"Fetched logs: count=%d, duration_ms=%d",
-		len(lgBatch.logs), time.Since(startTime).Milliseconds(),
+	logging.From(ctx, logging.Engine).Debugw(
+		"Fetched logs",
+		"count", len(lgBatch.logs),
+		"duration_ms", time.Since(startTime).Milliseconds(),
 	)
 
 	return lgBatch, nil
@@ -371,7 +523,7 @@ func (ci *Engine) getIndexRange(
 		return nil, errors.Wrap(err, "ci.fetchLastBlockIndex")
 	}
 
-	if err := database.UpdateState(ci.db, database.ChainTip, lastChainIndex, lastChainTimestamp); err != nil {
+	if err := ci.saveChainTip(ctx, lastChainIndex, lastChainTimestamp); err != nil {
 		return nil, errors.Wrap(err, "database.UpdateState(LastChainIndexState)")
 	}
 
@@ -380,6 +532,16 @@ func (ci *Engine) getIndexRange(
 	return &indexRange{start: startIndex, end: lastIndex}, nil
 }
 
+// saveChainTip stores the latest confirmed chain block and reports it to the
+// readiness probe.
+func (ci *Engine) saveChainTip(ctx context.Context, block, timestamp uint64) error {
+	if err := database.UpdateState(ci.db, database.ChainTip, block, timestamp); err != nil {
+		return err
+	}
+	ready.RecordChainTip(ctx, block)
+	return nil
+}
+
 func (ci *Engine) updateLastIndexContinuous(
 	ctx context.Context, ixRange *indexRange,
 ) (*indexRange, error) {
@@ -388,7 +550,7 @@ func (ci *Engine) updateLastIndexContinuous(
 		return nil, errors.Wrap(err, "ci.fetchLastBlockIndex")
 	}
 
-	if err := database.UpdateState(ci.db, database.ChainTip, lastIndex, lastChainTimestamp); err != nil {
+	if err := ci.saveChainTip(ctx, lastIndex, lastChainTimestamp); err != nil {
 		return nil, errors.Wrap(err, "database.UpdateState")
 	}
 
@@ -396,6 +558,7 @@ func (ci *Engine) updateLastIndexContinuous(
 }
 
 func (ci *Engine) processAndSave(
+	ctx context.Context,
 	bBatch *blockBatch,
 	txBatch *transactionsBatch,
 	lgBatch *logsBatch,
@@ -414,26 +577,20 @@ func (ci *Engine) processAndSave(
 		return errors.Wrap(err, "ci.processLogs")
 	}
 
-	saveStart := time.Now()
-	if err := ci.saveData(data, lastDBIndex, lastDBTimestamp); err != nil {
+	if err := ci.saveData(ctx, data, lastDBIndex, lastDBTimestamp); err != nil {
 		return errors.Wrap(err, "ci.saveData")
 	}
 
-	logger.Debugf(
-		"Saved batch: transactions=%d, logs=%d, duration_ms=%d",
-		len(data.Transactions), len(data.Logs),
-		time.Since(saveStart).Milliseconds(),
-	)
-
-	logger.Infof(
-		"Processed batch: from=%d, to=%d, blocks=%d, transactions=%d, receipt_logs=%d, filter_logs=%d, duration_ms=%d",
-		firstBlockNum,
-		lastDBIndex,
-		len(data.Blocks),
-		len(txBatch.transactions),
-		numLogsFromReceipts,
-		len(data.Logs)-numLogsFromReceipts,
-		time.Since(batchStart).Milliseconds(),
+	logging.From(ctx, logging.Engine).Infow(
+		"Processed batch",
+		"from", firstBlockNum,
+		"to", lastDBIndex,
+		"blocks", len(data.Blocks),
+		"transactions", len(txBatch.transactions),
+		"receipt_logs", numLogsFromReceipts,
+		"filter_logs", len(data.Logs)-numLogsFromReceipts,
+		"internal_calls", len(data.InternalCalls),
+		"duration_ms", time.Since(batchStart).Milliseconds(),
 	)
 
 	return nil
@@ -451,30 +608,39 @@ func (ci *Engine) updateLastIndexHistory(
 		return nil, errors.Wrap(err, "ci.fetchLastBlockIndex")
 	}
 
-	if err := database.UpdateState(ci.db, database.ChainTip, lastChainIndex, lastChainTimestamp); err != nil {
+	if err := ci.saveChainTip(ctx, lastChainIndex, lastChainTimestamp); err != nil {
 		return nil, errors.Wrap(err, "database.UpdateState")
 	}
 
 	if lastChainIndex > ixRange.end && ci.params.StopIndex > ixRange.end {
 		ixRange.end = min(lastChainIndex, ci.params.StopIndex)
-		logger.Infof("Extending history range: last_block=%d", ixRange.end)
+		logging.From(ctx, logging.Engine).Infow("Extending history range", "last_block", ixRange.end)
 	}
 
 	return ixRange, nil
 }
 
 func (ci *Engine) IndexContinuous(ctx context.Context, startIndex uint64) error {
+	if err := ci.loadTransactionID(); err != nil {
+		return err
+	}
+
 	ixRange, err := ci.getIndexRange(ctx, startIndex)
 	if err != nil {
 		return errors.Wrap(err, "ci.getIndexRange")
 	}
 
-	logger.Infof("Starting continuous indexing: from=%d", ixRange.start)
+	logging.From(ctx, logging.Engine).Infow("Starting continuous indexing", "from", ixRange.start)
 
 	// Request blocks one by one
 	blockNum := ixRange.start
 	lastProcessedBlockTime := [2]time.Time{time.Now(), time.Now()}
 	for blockNum <= ci.params.StopIndex {
+		if err := admin.WaitWhilePaused(ctx, blockNum); err != nil {
+			return err
+		}
+		ready.Heartbeat(ctx)
+		ci.applyContractChanges(ctx)
 		if blockNum > ixRange.end {
 			time.Sleep(time.Millisecond * time.Duration(ci.params.NewBlockCheckMillis))
 
@@ -486,14 +652,14 @@ func (ci *Engine) IndexContinuous(ctx context.Context, startIndex uint64) error
 			elapsed := time.Since(lastProcessedBlockTime[0]).Seconds()
 			delay := ci.params.NoNewBlocksDelayWarning
 			if delay != 0 && elapsed > delay {
-				logger.Warnf("No new blocks: elapsed_seconds=%.2f", time.Since(lastProcessedBlockTime[1]).Seconds())
+				logging.From(ctx, logging.Engine).Warnf("No new blocks: elapsed_seconds=%.2f", time.Since(lastProcessedBlockTime[1]).Seconds())
 				lastProcessedBlockTime[0] = time.Now()
 			}
 
 			continue
 		}
 
-		err = ci.indexContinuousIteration(ctx, blockNum)
+		err = ci.indexContinuousIteration(logging.WithCorrelationID(ctx), blockNum)
 		if err != nil {
 			return err
 		}
@@ -502,21 +668,37 @@ func (ci *Engine) IndexContinuous(ctx context.Context, startIndex uint64) error
 		blockNum++
 	}
 
-	logger.Debugf("Stopping continuous indexing: block=%d", blockNum)
+	logging.From(ctx, logging.Engine).Debugw("Stopping continuous indexing", "block", blockNum)
 
 	return nil
 }
 
-func (ci *Engine) indexContinuousIteration(ctx context.Context, index uint64) error {
+func (ci *Engine) indexContinuousIteration(ctx context.Context, index uint64) (err error) {
+	ctx, span := tracing.Start(
+		ctx, "core.indexContinuousIteration",
+		tracing.Block("block", index),
+		attribute.String(logging.CorrelationIDKey, logging.CorrelationID(ctx)),
+	)
+	defer func() { tracing.End(span, err) }()
+
 	block, err := ci.fetchBlock(ctx, &index)
 	if err != nil {
 		return errors.Wrapf(err, "fetchBlock: block=%d", index)
 	}
 
 	bBatch := &blockBatch{blocks: []*chain.Block{block}}
+	if ci.params.TraceCalls {
+		traces, err := ci.fetchBlockTrace(ctx, block)
+		if err != nil {
+			return err
+		}
+		bBatch.traces = [][]chain.TransactionTrace{traces}
+	}
 
 	txBatch := new(transactionsBatch)
-	ci.processBlocks(bBatch, txBatch)
+	if err := ci.processBlocks(bBatch, txBatch); err != nil {
+		return errors.Wrapf(err, "processBlocks: block=%d", index)
+	}
 
 	err = ci.getTransactionsReceipt(ctx, txBatch, 0, len(txBatch.transactions))
 	if err != nil {
@@ -524,7 +706,7 @@ func (ci *Engine) indexContinuousIteration(ctx context.Context, index uint64) er
 	}
 
 	logsBatch := new(logsBatch)
-	for _, logInfo := range ci.params.CollectLogs {
+	for _, logInfo := range ci.logs {
 		err = ci.requestLogs(ctx, logsBatch, logInfo, index, index+1, index)
 		if err != nil {
 			return errors.Wrapf(err, "requestLogs: block=%d", index)
@@ -544,12 +726,12 @@ func (ci *Engine) indexContinuousIteration(ctx context.Context, index uint64) er
 	}
 
 	indexTimestamp := bBatch.blocks[0].Time()
-	if err := ci.saveData(data, index, indexTimestamp); err != nil {
+	if err := ci.saveData(ctx, data, index, indexTimestamp); err != nil {
 		return errors.Wrapf(err, "saveData: block=%d", index)
 	}
 
 	if index%1000 == 0 {
-		logger.Infof("Continuous progress: block=%d", index)
+		logging.From(ctx, logging.Engine).Infow("Continuous progress", "block", index)
 	}
 
 	return nil
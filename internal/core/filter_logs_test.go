@@ -0,0 +1,91 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+
+	"github.com/ava-labs/coreth/interfaces"
+	"github.com/stretchr/testify/require"
+)
+
+// logsNode is a JSON-RPC node refusing eth_getLogs over more than maxRange
+// blocks. It records the ranges it answered.
+func logsNode(t *testing.T, maxRange uint64) (*chain.Client, func() [][2]uint64) {
+	t.Helper()
+	var (
+		mu       sync.Mutex
+		answered [][2]uint64
+	)
+	node := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Params []struct {
+				FromBlock string `json:"fromBlock"`
+				ToBlock   string `json:"toBlock"`
+			} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		from, err := strconv.ParseUint(req.Params[0].FromBlock, 0, 64)
+		require.NoError(t, err)
+		to, err := strconv.ParseUint(req.Params[0].ToBlock, 0, 64)
+		require.NoError(t, err)
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		if to-from+1 > maxRange {
+			resp["error"] = map[string]interface{}{"code": -32005, "message": "query returned more than 10000 results"}
+		} else {
+			resp["result"] = []interface{}{}
+			mu.Lock()
+			answered = append(answered, [2]uint64{from, to})
+			mu.Unlock()
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	t.Cleanup(node.Close)
+
+	nodeURL, err := url.Parse(node.URL)
+	require.NoError(t, err)
+	client, err := chain.DialRPCNode(nodeURL, chain.ChainTypeAvax, chain.Limits{Concurrency: 1})
+	require.NoError(t, err)
+
+	return client, func() [][2]uint64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return answered
+	}
+}
+
+func TestFilterLogsSplitsRangeTooLarge(t *testing.T) {
+	client, answered := logsNode(t, 10)
+	ci := &Engine{client: client}
+
+	_, err := ci.FilterLogs(context.Background(), interfaces.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   big.NewInt(40),
+	}, "test")
+	require.NoError(t, err)
+
+	// Halved until each part fits, and fetched in block order.
+	require.Equal(t, [][2]uint64{{1, 10}, {11, 20}, {21, 30}, {31, 40}}, answered())
+}
+
+func TestFilterLogsGivesUpOnSingleBlock(t *testing.T) {
+	client, _ := logsNode(t, 0)
+	ci := &Engine{client: client}
+
+	_, err := ci.FilterLogs(context.Background(), interfaces.FilterQuery{
+		FromBlock: big.NewInt(5),
+		ToBlock:   big.NewInt(6),
+	}, "test")
+	require.Error(t, err)
+	require.Equal(t, chain.NeedsSmallerRange, chain.Classify(err))
+}
@@ -9,8 +9,10 @@ import (
 	"sync"
 
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/boff"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
 
 	"github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/interfaces"
@@ -109,7 +111,15 @@ func (ci *Engine) fetchLogsChunk(
 		Topics:    topic,
 	}
 
-	return boff.RetryWithMaxElapsed(
+	return ci.FilterLogs(ctx, query, "fetchLogsChunk")
+}
+
+// FilterLogs fetches the logs matching query, which must have both block
+// bounds set, retrying transient errors. While the node answers that the
+// block range or the result set is too large, it splits the range in halves,
+// down to single blocks.
+func (ci *Engine) FilterLogs(ctx context.Context, query interfaces.FilterQuery, name string) ([]types.Log, error) {
+	logs, err := boff.RetryWithMaxElapsed(
 		ctx,
 		func() ([]types.Log, error) {
 			ctx, cancelFunc := context.WithTimeout(ctx, config.RPCTimeout)
@@ -117,8 +127,29 @@ func (ci *Engine) fetchLogsChunk(
 
 			return ci.client.FilterLogs(ctx, query)
 		},
-		"fetchLogsChunk",
+		name,
 	)
+	from, to := query.FromBlock.Uint64(), query.ToBlock.Uint64()
+	if err == nil || chain.Classify(err) != chain.NeedsSmallerRange || from >= to {
+		return logs, err
+	}
+
+	mid := from + (to-from)/2
+	logging.From(ctx, logging.Engine).Debugw("Splitting eth_getLogs range", "op", name, "from", from, "to", to)
+
+	lower, upper := query, query
+	lower.ToBlock = new(big.Int).SetUint64(mid)
+	upper.FromBlock = new(big.Int).SetUint64(mid + 1)
+
+	logs, err = ci.FilterLogs(ctx, lower, name)
+	if err != nil {
+		return nil, err
+	}
+	rest, err := ci.FilterLogs(ctx, upper, name)
+	if err != nil {
+		return nil, err
+	}
+	return append(logs, rest...), nil
 }
 
 func (ci *Engine) processLogs(
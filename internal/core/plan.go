@@ -0,0 +1,139 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	RangeKindFull      = "full"
+	RangeKindFspEvents = "fsp_events"
+)
+
+// RangePlan is one block range startup would index. EstimatedRPCCalls
+// counts the calls known up front: one block fetch per block and the
+// eth_getLogs requests of every log filter; receipts of matched transactions,
+// traces of blocks with transactions (indexer.trace_calls) and the
+// timestamps of backfilled event blocks come on top.
+type RangePlan struct {
+	Kind              string `json:"kind"`
+	From              uint64 `json:"from"`
+	To                uint64 `json:"to"`
+	Blocks            uint64 `json:"blocks"`
+	EstimatedRPCCalls uint64 `json:"estimated_rpc_calls"`
+}
+
+// StartupPlan is what startup would do, computed from the node and the
+// database without writing to either.
+type StartupPlan struct {
+	Mode                     string      `json:"mode"`
+	LatestConfirmed          uint64      `json:"latest_confirmed"`
+	LatestConfirmedTimestamp uint64      `json:"latest_confirmed_timestamp"`
+	Ranges                   []RangePlan `json:"ranges"`
+	// ContinuousFrom is where continuous indexing would start once the
+	// ranges are indexed.
+	ContinuousFrom uint64 `json:"continuous_from"`
+	// RetentionBoundary is the timestamp the first history-drop iteration
+	// would delete below; 0 deletes nothing. RetentionEnabled is false when
+	// history drop is off.
+	RetentionEnabled  bool     `json:"retention_enabled"`
+	RetentionBoundary uint64   `json:"retention_boundary"`
+	Notes             []string `json:"notes,omitempty"`
+}
+
+// Notef adds a line of context to the plan.
+func (p *StartupPlan) Notef(format string, args ...interface{}) {
+	p.Notes = append(p.Notes, fmt.Sprintf(format, args...))
+}
+
+// Print writes the plan in human-readable form.
+func (p *StartupPlan) Print(w io.Writer) {
+	fmt.Fprintf(w, "Startup plan (%s mode)\n", p.Mode)
+	fmt.Fprintf(w, "  latest confirmed block: %d (%s)\n", p.LatestConfirmed, formatTimestamp(p.LatestConfirmedTimestamp))
+
+	var blocks, calls uint64
+	if len(p.Ranges) == 0 {
+		fmt.Fprintln(w, "  nothing to index before continuous indexing")
+	}
+	for _, r := range p.Ranges {
+		fmt.Fprintf(
+			w, "  %-10s blocks %d-%d: %d blocks, ~%d RPC calls\n",
+			r.Kind, r.From, r.To, r.Blocks, r.EstimatedRPCCalls,
+		)
+		blocks += r.Blocks
+		calls += r.EstimatedRPCCalls
+	}
+	if len(p.Ranges) > 1 {
+		fmt.Fprintf(w, "  total: %d blocks, ~%d RPC calls\n", blocks, calls)
+	}
+	fmt.Fprintf(w, "  continuous indexing from block %d\n", p.ContinuousFrom)
+
+	switch {
+	case !p.RetentionEnabled:
+		fmt.Fprintln(w, "  history drop: disabled")
+	case p.RetentionBoundary == 0:
+		fmt.Fprintln(w, "  first history drop: deletes nothing yet")
+	default:
+		fmt.Fprintf(
+			w, "  first history drop: deletes rows with timestamp < %d (%s)\n",
+			p.RetentionBoundary, formatTimestamp(p.RetentionBoundary),
+		)
+	}
+
+	for _, note := range p.Notes {
+		fmt.Fprintf(w, "  note: %s\n", note)
+	}
+}
+
+func formatTimestamp(ts uint64) string {
+	return time.Unix(int64(ts), 0).UTC().Format(time.RFC3339)
+}
+
+// PlanIndexRange is the range IndexHistory would index from startIndex,
+// without recording the chain tip: nil when startIndex is already above the
+// confirmed tip (or stop index), together with the last index IndexHistory
+// would return.
+func (ci *Engine) PlanIndexRange(ctx context.Context, startIndex uint64) (*RangePlan, uint64, error) {
+	lastChainIndex, _, err := ci.fetchLastBlockIndex(ctx)
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "ci.fetchLastBlockIndex")
+	}
+
+	end := min(lastChainIndex, ci.params.StopIndex)
+	if startIndex > end {
+		return nil, end, nil
+	}
+	r := ci.EstimateFullRange(startIndex, end)
+	return &r, end, nil
+}
+
+// EstimateFullRange estimates full indexing of [from, to] in batches of
+// BatchSize: a block fetch per block, and per batch and log filter one
+// eth_getLogs request per LogRange blocks.
+func (ci *Engine) EstimateFullRange(from, to uint64) RangePlan {
+	blocks := to - from + 1
+
+	fullBatches, rest := blocks/ci.params.BatchSize, blocks%ci.params.BatchSize
+	logCalls := fullBatches * ceilDiv(ci.params.BatchSize, ci.params.LogRange)
+	if rest > 0 {
+		logCalls += ceilDiv(rest, ci.params.LogRange)
+	}
+	logCalls *= uint64(len(ci.params.CollectLogs))
+
+	return RangePlan{Kind: RangeKindFull, From: from, To: to, Blocks: blocks, EstimatedRPCCalls: blocks + logCalls}
+}
+
+// EstimateLogRange estimates a log-only scan of [from, to]: one eth_getLogs
+// request per LogRange blocks.
+func (ci *Engine) EstimateLogRange(kind string, from, to uint64) RangePlan {
+	blocks := to - from + 1
+	return RangePlan{Kind: kind, From: from, To: to, Blocks: blocks, EstimatedRPCCalls: ceilDiv(blocks, ci.params.LogRange)}
+}
+
+func ceilDiv(a, b uint64) uint64 {
+	return (a + b - 1) / b
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateRange(t *testing.T) {
+	ci := &Engine{params: applyIndexerDefaults(config.IndexerConfig{
+		BatchSize:   100,
+		LogRange:    30,
+		CollectLogs: []config.LogInfo{{}, {}},
+	})}
+
+	// 250 blocks: two full batches of 4 log requests each and a batch of 50
+	// blocks with 2, for each of the two log filters.
+	r := ci.EstimateFullRange(1000, 1249)
+	require.Equal(t, RangePlan{
+		Kind: RangeKindFull, From: 1000, To: 1249, Blocks: 250, EstimatedRPCCalls: 250 + 2*(4+4+2),
+	}, r)
+
+	r = ci.EstimateFullRange(5, 5)
+	require.Equal(t, uint64(1+2), r.EstimatedRPCCalls)
+
+	r = ci.EstimateLogRange(RangeKindFspEvents, 1, 61)
+	require.Equal(t, uint64(61), r.Blocks)
+	require.Equal(t, uint64(3), r.EstimatedRPCCalls)
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRegistryCheckSeconds        = 300
+	defaultRetiredContractGraceSeconds = 3600
+)
+
+// ContractsHook updates state derived from resolved contract addresses, e.g.
+// the FSP decoders, after a contract was redeployed. It runs between batches,
+// after the engine's filters were rebuilt; a failed hook is run again before
+// the next batch.
+type ContractsHook func(ctx context.Context) error
+
+// AddContractsHook registers a hook run when the resolved contract addresses
+// change. It must be called before indexing starts.
+func (ci *Engine) AddContractsHook(hook ContractsHook) {
+	ci.contractsHooks = append(ci.contractsHooks, hook)
+}
+
+// SyncContractDeployments discovers the contract deployments active from
+// block from, or from the first indexed block if lower, to the confirmed tip
+// (see database.SyncContractDeployments), and rebuilds the filters with them.
+// Like the batches, it runs on the indexing goroutine.
+func (ci *Engine) SyncContractDeployments(ctx context.Context, from uint64) error {
+	states, err := database.GetStates(ci.db.WithContext(ctx), database.BlockFloor, database.LogFloor)
+	if err != nil {
+		return errors.Wrap(err, "database.GetStates")
+	}
+	for _, floor := range states {
+		if database.IsSet(floor) && floor.Index < from {
+			from = floor.Index
+		}
+	}
+
+	to, _, err := ci.fetchLastBlockIndex(ctx)
+	if err != nil {
+		return errors.Wrap(err, "ci.fetchLastBlockIndex")
+	}
+	if from > to {
+		from = to
+	}
+
+	if err := database.SyncContractDeployments(ctx, ci.db, ci.contractResolver, from, to); err != nil {
+		return err
+	}
+	ci.contractsChanged.Store(true)
+	ci.applyContractChanges(ctx)
+	return nil
+}
+
+// WatchContractRegistry polls the ContractRegistry for redeployed contracts
+// every registry_check_seconds until ctx is done. A redeployment is applied
+// by the indexing loop before its next batch, so a batch is always filtered
+// by one set of addresses.
+func (ci *Engine) WatchContractRegistry(ctx context.Context) {
+	interval := time.Duration(ci.params.RegistryCheckSeconds) * time.Second
+	grace := time.Duration(ci.params.RetiredContractGraceSeconds) * time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		changed, err := ci.contractResolver.Refresh(ctx, grace)
+		if err != nil {
+			logging.From(ctx, logging.Engine).Warnw("ContractRegistry check error", "error", err)
+			continue
+		}
+		if changed {
+			logging.From(ctx, logging.Engine).Infow("Contract addresses changed, updating filters before the next batch")
+			ci.contractsChanged.Store(true)
+		}
+	}
+}
+
+// applyContractChanges rebuilds the transaction and log filters from the
+// configured ones after WatchContractRegistry saw a change. The names are
+// cached by the resolver, so this makes no RPC calls. On failure the current
+// filters are kept and the rebuild is retried before the next batch.
+func (ci *Engine) applyContractChanges(ctx context.Context) {
+	if !ci.contractsChanged.Swap(false) {
+		return
+	}
+
+	indexer := ci.params
+	err := config.ResolveIndexerAddresses(ctx, &indexer, ci.contractResolver)
+	if err == nil {
+		err = validateCollectLogs(indexer.CollectLogs)
+	}
+	var txs transactionFilters
+	if err == nil {
+		txs, err = buildTransactionPolicies(indexer.CollectTransactions)
+	}
+	if err != nil {
+		logging.From(ctx, logging.Engine).Warnw("Could not rebuild filters for changed contract addresses", "error", err)
+		ci.contractsChanged.Store(true)
+		return
+	}
+
+	ci.transactions = txs
+	ci.logs = indexer.CollectLogs
+	logging.From(ctx, logging.Engine).Infow(
+		"Applied changed contract addresses",
+		"collect_transactions", len(indexer.CollectTransactions),
+		"collect_logs", len(indexer.CollectLogs),
+	)
+
+	for _, hook := range ci.contractsHooks {
+		if err := hook(ctx); err != nil {
+			logging.From(ctx, logging.Engine).Warnw("Contracts hook error", "error", err)
+			ci.contractsChanged.Store(true)
+		}
+	}
+}
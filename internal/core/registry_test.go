@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts/contractregistry"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// registryCaller answers the ContractRegistry name lookups from a map.
+type registryCaller map[string]common.Address
+
+func (r registryCaller) CodeAt(context.Context, common.Address, *big.Int) ([]byte, error) {
+	return []byte{1}, nil
+}
+
+func (r registryCaller) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	parsed, err := contractregistry.ContractRegistryMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	method, err := parsed.MethodById(call.Data[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	if method.Name == "getContractAddressByName" {
+		return method.Outputs.Pack(r[args[0].(string)])
+	}
+	var addresses []common.Address
+	for _, name := range args[0].([]string) {
+		addresses = append(addresses, r[name])
+	}
+	return method.Outputs.Pack(addresses)
+}
+
+func TestApplyContractChanges(t *testing.T) {
+	ctx := context.Background()
+	first := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	second := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	submitSig, err := parseFuncSig("6c532fae")
+	require.NoError(t, err)
+
+	registry := registryCaller{"Submission": first}
+	resolver, err := contracts.NewContractResolver(registry)
+	require.NoError(t, err)
+
+	cfg := &config.Config{Indexer: config.IndexerConfig{
+		CollectTransactions: []config.TransactionInfo{{ContractName: "Submission", FuncSig: "6c532fae"}},
+		CollectLogs:         []config.LogInfo{{ContractName: "Submission", Topic: undefined}},
+	}}
+	require.NoError(t, config.ResolveContractAddresses(ctx, cfg, resolver))
+	ci, err := NewEngine(cfg, nil, nil, resolver)
+	require.NoError(t, err)
+	hooks := 0
+	ci.AddContractsHook(func(context.Context) error { hooks++; return nil })
+
+	ci.applyContractChanges(ctx)
+	require.Zero(t, hooks, "nothing changed")
+
+	registry["Submission"] = second
+	changed, err := resolver.Refresh(ctx, time.Hour)
+	require.NoError(t, err)
+	require.True(t, changed)
+	ci.contractsChanged.Store(true)
+	ci.applyContractChanges(ctx)
+
+	require.Equal(t, 1, hooks)
+	require.False(t, ci.contractsChanged.Load())
+	require.Len(t, ci.logs, 2)
+	require.Equal(t, second.Hex(), ci.logs[0].ContractAddress)
+	require.Equal(t, first.Hex(), ci.logs[1].ContractAddress, "the replaced address is collected for the grace period")
+	for _, address := range []common.Address{first, second} {
+		_, ok := ci.transactions.any.match(address, submitSig)
+		require.True(t, ok)
+	}
+	require.Len(t, ci.params.CollectLogs, 1, "params keep the startup filters")
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/boff"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+)
+
+// internalCall is a call frame of a transaction's trace matching a
+// collect_transactions filter, with its place in the call tree.
+type internalCall struct {
+	frame *chain.CallFrame
+	depth uint64
+	index uint64
+	// reverted is set when the frame or any frame it was called from
+	// failed, undoing its effects.
+	reverted bool
+}
+
+// tracedCallTypes are the callTracer frame types matched against the
+// filters; CREATE frames carry init code rather than calldata.
+var tracedCallTypes = map[string]bool{
+	"CALL":         true,
+	"CALLCODE":     true,
+	"DELEGATECALL": true,
+	"STATICCALL":   true,
+}
+
+// fetchBlockTrace returns the call traces of the transactions of a block, in
+// block order, or nil for a block without transactions.
+func (ci *Engine) fetchBlockTrace(ctx context.Context, block *chain.Block) ([]chain.TransactionTrace, error) {
+	txs := block.Transactions()
+	if len(txs) == 0 {
+		return nil, nil
+	}
+
+	traces, err := boff.RetryWithMaxElapsed(
+		ctx,
+		func() ([]chain.TransactionTrace, error) {
+			ctx, cancelFunc := context.WithTimeout(ctx, config.RPCTimeout)
+			defer cancelFunc()
+
+			return ci.client.TraceBlockByNumber(ctx, block.Number())
+		},
+		"fetchBlockTrace",
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "fetchBlockTrace: block=%s", block.Number())
+	}
+
+	if len(traces) != len(txs) {
+		return nil, errors.Errorf("block %s: %d traces for %d transactions", block.Number(), len(traces), len(txs))
+	}
+	for i := range traces {
+		// Older nodes leave txHash out; the order is the block order either way.
+		if traces[i].TxHash != (common.Hash{}) && traces[i].TxHash != txs[i].Hash() {
+			return nil, errors.Errorf("block %s: trace %d is of transaction %s", block.Number(), i, traces[i].TxHash)
+		}
+	}
+
+	return traces, nil
+}
+
+// matchInternalCalls walks the calls made during a transaction depth first
+// and returns those matching a filter, with the merged policy of the filters
+// they matched. The top-level frame is the transaction itself and is not
+// returned.
+func (ci *Engine) matchInternalCalls(frame *chain.CallFrame) ([]internalCall, transactionsPolicy) {
+	var (
+		calls  []internalCall
+		policy transactionsPolicy
+		index  uint64
+	)
+
+	var walk func(frames []chain.CallFrame, depth uint64, parentReverted bool)
+	walk = func(frames []chain.CallFrame, depth uint64, parentReverted bool) {
+		for i := range frames {
+			f := &frames[i]
+			index++
+			reverted := parentReverted || f.Reverted()
+			if tracedCallTypes[f.Type] {
+				sender := func() (common.Address, error) { return f.From, nil }
+				if pol, ok, _ := ci.matchCall(sender, f.To, f.Input); ok {
+					calls = append(calls, internalCall{frame: f, depth: depth, index: index, reverted: reverted})
+					policy = policy.merge(pol)
+				}
+			}
+			walk(f.Calls, depth+1, reverted)
+		}
+	}
+	walk(frame.Calls, 1, frame.Reverted())
+
+	return calls, policy
+}
+
+func buildDBInternalCall(dbTx *database.Transaction, call internalCall) *database.InternalCall {
+	f := call.frame
+	input := hex.EncodeToString(f.Input)
+
+	value := "0"
+	if f.Value != nil {
+		value = f.Value.ToInt().Text(16)
+	}
+
+	callErr := f.Error
+	if f.RevertReason != "" {
+		callErr += ": " + f.RevertReason
+	}
+
+	return &database.InternalCall{
+		TransactionID:   dbTx.ID,
+		TransactionHash: dbTx.Hash,
+		CallIndex:       call.index,
+		Depth:           call.depth,
+		CallType:        f.Type,
+		FunctionSig:     input[:8],
+		FromAddress:     strings.ToLower(f.From.Hex()[2:]),
+		ToAddress:       strings.ToLower(f.To.Hex()[2:]),
+		Input:           input,
+		Output:          hex.EncodeToString(f.Output),
+		Value:           value,
+		Reverted:        call.reverted,
+		Error:           callErr,
+		BlockNumber:     dbTx.BlockNumber,
+		Timestamp:       dbTx.Timestamp,
+	}
+}
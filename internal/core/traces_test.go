@@ -0,0 +1,102 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchInternalCalls(t *testing.T) {
+	watched := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	txs, err := buildTransactionPolicies([]config.TransactionInfo{
+		{ContractAddress: watched.Hex(), FuncSig: "0x12345678", Status: true},
+		{ContractAddress: undefined, FuncSig: "0xabcdef01", CollectEvents: true},
+	})
+	require.NoError(t, err)
+	ci := &Engine{transactions: txs}
+
+	// tx -> other.f() -> watched.12345678() (reverted)
+	//                 -> other.abcdef01() via DELEGATECALL
+	//    -> CREATE of watched, never matched
+	top := &chain.CallFrame{
+		Type: "CALL", To: &other, Input: []byte{0, 0, 0, 0},
+		Calls: []chain.CallFrame{
+			{
+				Type: "CALL", To: &other, Input: []byte{1, 1, 1, 1},
+				Calls: []chain.CallFrame{
+					{Type: "CALL", To: &watched, Input: []byte{0x12, 0x34, 0x56, 0x78, 9}, Error: "execution reverted"},
+					{Type: "DELEGATECALL", To: &other, Input: []byte{0xab, 0xcd, 0xef, 0x01}},
+				},
+			},
+			{Type: "CREATE", To: &watched, Input: []byte{0x12, 0x34, 0x56, 0x78}},
+		},
+	}
+
+	calls, policy := ci.matchInternalCalls(top)
+	require.Len(t, calls, 2)
+	require.Equal(t, uint64(2), calls[0].depth)
+	require.Equal(t, uint64(2), calls[0].index)
+	require.Equal(t, uint64(3), calls[1].index)
+	require.Equal(t, transactionsPolicy{status: true, collectEvents: true}, policy)
+
+	dbTx := &database.Transaction{Hash: "ff", BlockNumber: 5, Timestamp: 50}
+	dbTx.ID = 9
+	row := buildDBInternalCall(dbTx, calls[0])
+	require.Equal(t, uint64(9), row.TransactionID)
+	require.Equal(t, "12345678", row.FunctionSig)
+	require.Equal(t, "1234567809", row.Input)
+	require.Equal(t, "00000000000000000000000000000000000000aa", row.ToAddress)
+	require.True(t, row.Reverted)
+	require.Equal(t, uint64(50), row.Timestamp)
+	require.False(t, buildDBInternalCall(dbTx, calls[1]).Reverted)
+}
+
+func TestMatchInternalCallsUnderRevertedFrame(t *testing.T) {
+	watched := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	other := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+
+	txs, err := buildTransactionPolicies([]config.TransactionInfo{
+		{ContractAddress: watched.Hex(), FuncSig: "0x12345678"},
+	})
+	require.NoError(t, err)
+	ci := &Engine{transactions: txs}
+
+	// tx -> other.f() (reverted) -> other.g() -> watched.12345678()
+	//    -> watched.12345678()
+	call := chain.CallFrame{Type: "CALL", To: &watched, Input: []byte{0x12, 0x34, 0x56, 0x78}}
+	top := &chain.CallFrame{
+		Type: "CALL", To: &other, Input: []byte{0, 0, 0, 0},
+		Calls: []chain.CallFrame{
+			{
+				Type: "CALL", To: &other, Input: []byte{1, 1, 1, 1}, Error: "execution reverted",
+				Calls: []chain.CallFrame{
+					{Type: "CALL", To: &other, Input: []byte{2, 2, 2, 2}, Calls: []chain.CallFrame{call}},
+				},
+			},
+			call,
+		},
+	}
+
+	calls, _ := ci.matchInternalCalls(top)
+	require.Len(t, calls, 2)
+	require.Equal(t, uint64(3), calls[0].depth)
+	require.True(t, calls[0].reverted, "a call nested in a reverted frame is undone with it")
+	require.Empty(t, calls[0].frame.Error, "the node reports no error of its own")
+	require.False(t, calls[1].reverted)
+
+	// A reverted transaction undoes every call made during it.
+	top.Error = "execution reverted"
+	calls, _ = ci.matchInternalCalls(top)
+	require.Len(t, calls, 2)
+	require.True(t, calls[1].reverted)
+
+	dbTx := &database.Transaction{Hash: "ff"}
+	require.True(t, buildDBInternalCall(dbTx, calls[1]).Reverted)
+}
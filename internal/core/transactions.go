@@ -23,6 +23,7 @@ type transactionsBatch struct {
 	indices      []uint64
 	receipts     []*chain.Receipt
 	policies     []transactionsPolicy
+	calls        [][]internalCall
 	mu           sync.RWMutex
 }
 
@@ -32,6 +33,7 @@ func (tb *transactionsBatch) Add(
 	index uint64,
 	receipt *chain.Receipt,
 	policy transactionsPolicy,
+	calls []internalCall,
 ) {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
@@ -41,6 +43,7 @@ func (tb *transactionsBatch) Add(
 	tb.indices = append(tb.indices, index)
 	tb.receipts = append(tb.receipts, receipt)
 	tb.policies = append(tb.policies, policy)
+	tb.calls = append(tb.calls, calls)
 }
 
 func countReceipts(txBatch *transactionsBatch) int {
@@ -117,9 +120,13 @@ func (ci *Engine) processTransactions(txBatch *transactionsBatch, data *database
 		if err != nil {
 			return err
 		}
+		dbTx.ID = ci.transactionID.Add(1) - 1
 
 		data.Transactions = append(data.Transactions, dbTx)
-		database.TransactionId.Add(1)
+
+		for _, call := range txBatch.calls[i] {
+			data.InternalCalls = append(data.InternalCalls, buildDBInternalCall(dbTx, call))
+		}
 
 		// if it was chosen to get the logs of the transaction we process it
 		if receipt != nil && policy.collectEvents {
@@ -144,11 +151,11 @@ func buildDBTx(
 	tx *chain.Transaction, receipt *chain.Receipt, block *chain.Block, txIndex uint64,
 ) (*database.Transaction, error) {
 	txData := hex.EncodeToString(tx.Data())
-	// Guard against tx data shorter than a 4-byte selector (e.g. plain transfers)
-	// reaching this code path with an "undefined" func_sig filter.
-	funcSig := txData
-	if len(funcSig) > 8 {
-		funcSig = funcSig[:8]
+	// Plain transfers collected by collect_transfers have calldata shorter
+	// than a 4-byte selector and are stored with an empty func_sig.
+	funcSig := ""
+	if len(txData) >= 8 {
+		funcSig = txData[:8]
 	}
 
 	fromAddress, err := tx.FromAddress() // todo: this is a bit slow
@@ -161,9 +168,14 @@ func buildDBTx(
 		status = receipt.Status()
 	}
 
-	base := database.BaseEntity{ID: database.TransactionId.Load()}
+	// Only contract creations matched by their internal calls have no
+	// recipient.
+	toAddress := ""
+	if to := tx.To(); to != nil {
+		toAddress = strings.ToLower(to.Hex()[2:])
+	}
+
 	return &database.Transaction{
-		BaseEntity:       base,
 		Hash:             tx.Hash().Hex()[2:],
 		FunctionSig:      funcSig,
 		Input:            txData,
@@ -171,7 +183,7 @@ func buildDBTx(
 		BlockHash:        block.Hash().Hex()[2:],
 		TransactionIndex: txIndex,
 		FromAddress:      strings.ToLower(fromAddress.Hex()[2:]),
-		ToAddress:        strings.ToLower(tx.To().Hex()[2:]),
+		ToAddress:        toAddress,
 		Status:           status,
 		Value:            tx.Value().Text(16),
 		GasPrice:         tx.GasPrice().String(),
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// transferFilter is a parsed collect_transfers entry; a nil set or minValue
+// matches anything.
+type transferFilter struct {
+	to       map[common.Address]bool
+	from     map[common.Address]bool
+	minValue *big.Int
+	policy   transactionsPolicy
+}
+
+func buildTransferFilters(transfers []config.TransferInfo) ([]transferFilter, error) {
+	filters := make([]transferFilter, 0, len(transfers))
+
+	for i := range transfers {
+		transfer := &transfers[i]
+		filter := transferFilter{
+			policy: transactionsPolicy{
+				status:        transfer.Status,
+				collectEvents: transfer.CollectEvents,
+			},
+		}
+
+		var err error
+		if filter.to, err = parseAddressSet(transfer.Recipients()); err != nil {
+			return nil, fmt.Errorf("collect_transfers to address: %w", err)
+		}
+		if filter.from, err = parseAddressSet(transfer.Senders()); err != nil {
+			return nil, fmt.Errorf("collect_transfers from address: %w", err)
+		}
+
+		if minValue := strings.TrimSpace(transfer.MinValue); minValue != "" {
+			value, ok := new(big.Int).SetString(minValue, 10)
+			if !ok || value.Sign() < 0 {
+				return nil, fmt.Errorf("collect_transfers min_value %q: not a non-negative decimal wei amount", transfer.MinValue)
+			}
+			filter.minValue = value
+		}
+
+		// An entry without conditions would collect every plain transfer of
+		// the chain, which is never what a typo'd entry meant.
+		if filter.to == nil && filter.from == nil && filter.minValue == nil {
+			return nil, fmt.Errorf("collect_transfers entry %d sets none of to_address, from_address and min_value", i)
+		}
+
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+func parseAddressSet(addresses []string) (map[common.Address]bool, error) {
+	if len(addresses) == 0 {
+		return nil, nil
+	}
+
+	set := make(map[common.Address]bool, len(addresses))
+	for _, address := range addresses {
+		if !common.IsHexAddress(address) {
+			return nil, fmt.Errorf("%s is not an address", address)
+		}
+		set[common.HexToAddress(address)] = true
+	}
+	return set, nil
+}
+
+// matchTransfer returns the merged policy of the collect_transfers filters
+// matching a plain transfer of value to the address to. sender returns the
+// sender; it is only called when a filter gets to its senders, and at most
+// once.
+func (ci *Engine) matchTransfer(
+	sender func() (common.Address, error), to *common.Address, input []byte, value *big.Int,
+) (transactionsPolicy, bool, error) {
+	var policy transactionsPolicy
+	if len(ci.transfers) == 0 || to == nil || len(input) >= 4 {
+		return policy, false, nil
+	}
+
+	var (
+		from      common.Address
+		recovered bool
+	)
+	check := false
+	for i := range ci.transfers {
+		filter := &ci.transfers[i]
+		if filter.to != nil && !filter.to[*to] {
+			continue
+		}
+		if filter.minValue != nil && value.Cmp(filter.minValue) < 0 {
+			continue
+		}
+		if filter.from != nil {
+			if !recovered {
+				var err error
+				if from, err = sender(); err != nil {
+					return transactionsPolicy{}, false, err
+				}
+				recovered = true
+			}
+			if !filter.from[from] {
+				continue
+			}
+		}
+
+		check = true
+		policy = policy.merge(filter.policy)
+	}
+
+	return policy, check, nil
+}
@@ -0,0 +1,66 @@
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchTransfer(t *testing.T) {
+	treasury := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	provider := common.HexToAddress("0x00000000000000000000000000000000000000bb")
+	stranger := common.HexToAddress("0x00000000000000000000000000000000000000cc")
+
+	transfers, err := buildTransferFilters([]config.TransferInfo{
+		{ToAddresses: []string{treasury.Hex()}, Status: true},
+		{FromAddress: provider.Hex(), MinValue: "1000", CollectEvents: true},
+	})
+	require.NoError(t, err)
+	ci := &Engine{transfers: transfers}
+
+	calls := 0
+	from := func(address common.Address) func() (common.Address, error) {
+		return func() (common.Address, error) {
+			calls++
+			return address, nil
+		}
+	}
+
+	policy, ok, err := ci.matchTransfer(from(stranger), &treasury, nil, big.NewInt(0))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, transactionsPolicy{status: true}, policy)
+	require.Zero(t, calls, "no sender filter reached, no sender recovered")
+
+	policy, ok, err = ci.matchTransfer(from(provider), &treasury, []byte{1}, big.NewInt(5000))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, transactionsPolicy{status: true, collectEvents: true}, policy)
+	require.Equal(t, 1, calls)
+
+	_, ok, err = ci.matchTransfer(from(provider), &stranger, nil, big.NewInt(999))
+	require.NoError(t, err)
+	require.False(t, ok, "below min_value")
+
+	_, ok, err = ci.matchTransfer(from(provider), &treasury, []byte{1, 2, 3, 4}, big.NewInt(5000))
+	require.NoError(t, err)
+	require.False(t, ok, "calls with a selector are left to collect_transactions")
+}
+
+func TestBuildTransferFiltersRejects(t *testing.T) {
+	for _, transfer := range []config.TransferInfo{
+		{},
+		{Status: true},
+		{ToAddress: "0x1234"},
+		{FromAddresses: []string{"nope"}},
+		{MinValue: "-1"},
+		{MinValue: "1e18"},
+	} {
+		_, err := buildTransferFilters([]config.TransferInfo{transfer})
+		require.Error(t, err, "%+v", transfer)
+	}
+}
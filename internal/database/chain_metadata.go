@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ErrChainMismatch means the database was filled from another chain, or in
+// another mode, than the indexer is configured for. Indexing on would
+// interleave the rows of both, so startup refuses instead.
+var ErrChainMismatch = errors.New("database belongs to another chain or mode")
+
+// ChainMetadata identifies what a database is filled from. It is recorded
+// by the first run against the database and checked by every later one.
+type ChainMetadata struct {
+	BaseEntity
+	ChainID     uint64
+	GenesisHash string `gorm:"type:varchar(64)"`
+	Mode        string `gorm:"type:varchar(20)"`
+	Created     time.Time
+}
+
+// TableName fixes the chain_metadata name, like SchemaMigration's, instead
+// of gorm's pluralization.
+func (ChainMetadata) TableName(namer schema.Namer) string {
+	if ns, ok := namer.(schema.NamingStrategy); ok {
+		return ns.TablePrefix + "chain_metadata"
+	}
+	return "chain_metadata"
+}
+
+// chainMetadataMigration is the migration creating chain_metadata, which
+// ConnectAndInitialize runs ahead of the others so that a database of
+// another chain is refused before its schema is changed.
+const chainMetadataMigration = 7
+
+// ChainIdentity is the chain an indexer is connected to: the chain metadata
+// it expects and a lookup of the node's block hashes, as lowercase hex
+// without 0x prefix.
+type ChainIdentity struct {
+	Metadata  ChainMetadata
+	BlockHash func(ctx context.Context, number uint64) (string, error)
+}
+
+// EnsureChainMetadata records the identity's metadata as the database's
+// chain metadata when none is recorded yet, and otherwise fails with
+// ErrChainMismatch unless the recorded metadata matches it. A database
+// filled before chain metadata was recorded is first checked by the hash of
+// its highest block.
+func EnsureChainMetadata(ctx context.Context, db *gorm.DB, identity *ChainIdentity) error {
+	db = db.WithContext(ctx)
+	want := &identity.Metadata
+
+	var recorded []ChainMetadata
+	if err := db.Order("id ASC").Limit(1).Find(&recorded).Error; err != nil {
+		return errors.Wrap(err, "read chain metadata")
+	}
+	if len(recorded) == 0 {
+		if err := checkStoredBlock(ctx, db, identity); err != nil {
+			return err
+		}
+		row := *want
+		row.Created = time.Now()
+		return errors.Wrap(db.Create(&row).Error, "record chain metadata")
+	}
+
+	got := recorded[0]
+	if got.ChainID != want.ChainID || got.GenesisHash != want.GenesisHash || got.Mode != want.Mode {
+		return errors.Wrapf(
+			ErrChainMismatch,
+			"database is filled from chain ID %d (genesis %s) in %s mode, the indexer is connected to chain ID %d (genesis %s) in %s mode",
+			got.ChainID, got.GenesisHash, got.Mode, want.ChainID, want.GenesisHash, want.Mode,
+		)
+	}
+	return nil
+}
+
+// checkStoredBlock fails with ErrChainMismatch when the highest stored block
+// has another hash on the node. A database without blocks passes.
+func checkStoredBlock(ctx context.Context, db *gorm.DB, identity *ChainIdentity) error {
+	if identity.BlockHash == nil || !db.Migrator().HasTable(&Block{}) {
+		return nil
+	}
+
+	var stored []struct {
+		Number uint64
+		Hash   []byte
+	}
+	err := db.Table(prefixedTable(db, "blocks")).Select("number, hash").Order("number DESC").Limit(1).Scan(&stored).Error
+	if err != nil {
+		return errors.Wrap(err, "read the highest stored block")
+	}
+	if len(stored) == 0 {
+		return nil
+	}
+
+	// Read as raw column bytes, so either storage format can be checked
+	// before EnsureStorageFormat ran.
+	block := stored[0]
+	storedHash := string(block.Hash)
+	if len(block.Hash) == common.HashLength {
+		storedHash = hex.EncodeToString(block.Hash)
+	}
+	nodeHash, err := identity.BlockHash(ctx, block.Number)
+	if err != nil {
+		return errors.Wrapf(err, "read hash of block %d from the node", block.Number)
+	}
+	if nodeHash != storedHash {
+		return errors.Wrapf(
+			ErrChainMismatch,
+			"block %d has hash %s in the database and %s on the node", block.Number, storedHash, nodeHash,
+		)
+	}
+	return nil
+}
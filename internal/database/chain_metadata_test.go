@@ -0,0 +1,64 @@
+package database
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnsureChainMetadata(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDSNEnv)
+	}
+	ctx := context.Background()
+	db := setupScratchDB(t, dsn)
+
+	flare := ChainIdentity{Metadata: ChainMetadata{ChainID: 14, GenesisHash: strings.Repeat("01", 32), Mode: "fsp"}}
+	require.NoError(t, EnsureChainMetadata(ctx, db, &flare), "recorded on first run")
+	require.NoError(t, EnsureChainMetadata(ctx, db, &flare))
+
+	songbird := ChainIdentity{Metadata: ChainMetadata{ChainID: 19, GenesisHash: strings.Repeat("02", 32), Mode: "fsp"}}
+	require.ErrorIs(t, EnsureChainMetadata(ctx, db, &songbird), ErrChainMismatch)
+
+	fullMode := flare
+	fullMode.Metadata.Mode = "full"
+	require.ErrorIs(t, EnsureChainMetadata(ctx, db, &fullMode), ErrChainMismatch)
+
+	var count int64
+	require.NoError(t, db.Model(&ChainMetadata{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
+
+func TestEnsureChainMetadataChecksUnrecordedDatabase(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDSNEnv)
+	}
+	ctx := context.Background()
+	db := setupScratchDB(t, dsn)
+	stored := strings.Repeat("aa", 32)
+	require.NoError(t, db.Create(&Block{Number: 100, Timestamp: 100, Hash: stored}).Error)
+
+	identity := func(hash string) *ChainIdentity {
+		return &ChainIdentity{
+			Metadata: ChainMetadata{ChainID: 14, GenesisHash: strings.Repeat("01", 32), Mode: "full"},
+			BlockHash: func(_ context.Context, number uint64) (string, error) {
+				require.Equal(t, uint64(100), number)
+				return hash, nil
+			},
+		}
+	}
+
+	require.ErrorIs(t, EnsureChainMetadata(ctx, db, identity(strings.Repeat("bb", 32))), ErrChainMismatch)
+	var count int64
+	require.NoError(t, db.Model(&ChainMetadata{}).Count(&count).Error)
+	require.Zero(t, count, "nothing is recorded for a database of another chain")
+
+	require.NoError(t, EnsureChainMetadata(ctx, db, identity(stored)))
+	require.NoError(t, db.Model(&ChainMetadata{}).Count(&count).Error)
+	require.Equal(t, int64(1), count)
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"strings"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SyncContractDeployments discovers the deployments of every contract name
+// resolved so far within the blocks [from, to], stores them in
+// contract_deployments and hands the stored ones to the resolver, so
+// ResolveAllByName returns each address active within the range. The known
+// upgrades of contracts.KnownDeployments are stored first, so they survive
+// a node that cannot serve the state of old blocks. A name whose history
+// cannot be read keeps its stored deployments; without any, the discovery
+// error is returned, as the addresses before the range would be lost.
+func SyncContractDeployments(
+	ctx context.Context, db *gorm.DB, resolver *contracts.ContractResolver, from, to uint64,
+) error {
+	db = db.WithContext(ctx)
+	names := resolver.Names()
+	for _, name := range names {
+		current, err := resolver.ResolveByName(ctx, name)
+		if err != nil {
+			return err
+		}
+		if err := saveDeployments(db, name, contracts.KnownDeployments(name, current)); err != nil {
+			return err
+		}
+
+		discovered, err := resolver.DiscoverDeployments(ctx, name, from, to)
+		if err != nil {
+			var count int64
+			if err := db.Model(&ContractDeployment{}).Where("contract_name = ?", name).Count(&count).Error; err != nil {
+				return errors.Wrapf(err, "count deployments of %s", name)
+			}
+			if count == 0 {
+				return errors.Wrapf(err, "no deployment history of %s: an archive node is needed on the first start", name)
+			}
+			logging.From(ctx, logging.Database).Warnw(
+				"Could not discover contract deployments, using the stored ones",
+				"contract", name, "from", from, "to", to, "stored", count, "error", err,
+			)
+			continue
+		}
+		if err := saveDeployments(db, name, discovered); err != nil {
+			return err
+		}
+	}
+
+	var stored []ContractDeployment
+	if err := db.Where("contract_name IN ?", names).Find(&stored).Error; err != nil {
+		return errors.Wrap(err, "read contract deployments")
+	}
+	deployments := make([]contracts.Deployment, len(stored))
+	for i, row := range stored {
+		deployments[i] = contracts.Deployment{
+			Name:       row.ContractName,
+			Address:    common.HexToAddress(row.Address),
+			ActiveFrom: row.ActiveFromBlock,
+		}
+	}
+	resolver.SetDeployments(deployments, from)
+
+	logging.From(ctx, logging.Database).Infow(
+		"Contract deployments synced",
+		"contracts", len(names), "deployments", len(deployments), "from", from, "to", to,
+	)
+	return nil
+}
+
+// saveDeployments stores deployments of a name. An address stored before
+// keeps its earlier ActiveFromBlock.
+func saveDeployments(db *gorm.DB, name string, deployments []contracts.Deployment) error {
+	if len(deployments) == 0 {
+		return nil
+	}
+	rows := make([]*ContractDeployment, len(deployments))
+	for i, d := range deployments {
+		rows[i] = &ContractDeployment{
+			ContractName:    d.Name,
+			Address:         strings.ToLower(d.Address.Hex()[2:]),
+			ActiveFromBlock: d.ActiveFrom,
+		}
+	}
+	if err := db.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(rows).Error; err != nil {
+		return errors.Wrapf(err, "save deployments of %s", name)
+	}
+	return nil
+}
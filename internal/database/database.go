@@ -3,8 +3,8 @@ package database
 import (
 	"context"
 	"fmt"
-	"sync/atomic"
 
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
 
 	"github.com/go-sql-driver/mysql"
@@ -12,6 +12,7 @@ import (
 	gormMysql "gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
 )
 
 const (
@@ -21,59 +22,129 @@ const (
 )
 
 var (
-	// List entities to auto-migrate
+	// entities are the tables owned by the indexer, dropped together with
+	// schema_version by drop_table_at_start. Their schema is created and
+	// changed by the versioned migrations in migrations.go.
 	entities = []interface{}{
 		State{},
 		Block{},
 		Transaction{},
 		Log{},
+		InternalCall{},
+		SigningPolicy{},
+		SigningPolicyVoter{},
+		VoterRegistration{},
+		RewardOffer{},
+		SubmissionPayload{},
+		RewardEpoch{},
+		VotingRound{},
+		ContractDeployment{},
+		ChainMetadata{},
 	}
-	TransactionId atomic.Uint64
 )
 
-func ConnectAndInitialize(ctx context.Context, cfg *config.DBConfig) (*gorm.DB, error) {
+// ConnectAndInitialize connects to the database and migrates it. With
+// identity set, it first refuses a database filled from another chain or
+// mode (see EnsureChainMetadata), before anything else is changed; tools not
+// connected to the chain pass nil.
+func ConnectAndInitialize(ctx context.Context, cfg *config.DBConfig, identity *ChainIdentity) (*gorm.DB, error) {
 	db, err := connect(ctx, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("ConnectAndInitialize: Connect: %w", err)
 	}
 
 	if cfg.DropTableAtStart {
-		err = db.Migrator().DropTable(entities...)
+		err = db.Migrator().DropTable(append(entities, &SchemaMigration{})...)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// Initialize - auto migrate
-	err = db.AutoMigrate(entities...)
-	if err != nil {
-		return nil, errors.Wrap(err, "ConnectAndInitialize: AutoMigrate")
+	if identity != nil {
+		if err := execMigrationSQL(db.WithContext(ctx), migrations[chainMetadataMigration-1]); err != nil {
+			return nil, errors.Wrap(err, "ConnectAndInitialize: create chain_metadata")
+		}
+		if err := EnsureChainMetadata(ctx, db, identity); err != nil {
+			return nil, errors.Wrap(err, "ConnectAndInitialize: EnsureChainMetadata")
+		}
 	}
 
-	if err := storeTransactionID(db); err != nil {
-		return nil, err
+	if err := Migrate(ctx, db); err != nil {
+		return nil, errors.Wrap(err, "ConnectAndInitialize: Migrate")
 	}
 
+	if err := EnsureStorageFormat(ctx, db, cfg); err != nil {
+		return nil, errors.Wrap(err, "ConnectAndInitialize: EnsureStorageFormat")
+	}
+
+	if err := EnsurePartitioning(ctx, db, cfg); err != nil {
+		return nil, errors.Wrap(err, "ConnectAndInitialize: EnsurePartitioning")
+	}
+
+	return db, nil
+}
+
+// Connect opens the database without migrating it, for read-only tools that
+// must not change the schema of the database they inspect.
+func Connect(ctx context.Context, cfg *config.DBConfig) (*gorm.DB, error) {
+	db, err := connect(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("Connect: %w", err)
+	}
 	return db, nil
 }
 
-func storeTransactionID(db *gorm.DB) (err error) {
+// NextTransactionID returns the ID the next inserted transaction gets: one
+// past the highest stored ID. Transaction IDs are assigned by the indexer so
+// logs can reference their transaction within the same batch insert.
+func NextTransactionID(db *gorm.DB) (uint64, error) {
 	maxIndexTx := new(Transaction)
-	err = db.Last(maxIndexTx).Error
+	err := db.Last(maxIndexTx).Error
 	if err == nil {
-		TransactionId.Store(maxIndexTx.ID + 1)
-		return nil
+		return maxIndexTx.ID + 1, nil
 	}
 
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		TransactionId.Store(1)
-		return nil
+		return 1, nil
 	}
 
-	return errors.Wrap(err, "Failed to obtain ID data from DB")
+	return 0, errors.Wrap(err, "Failed to obtain ID data from DB")
+}
+
+// BlockTimestamps looks block timestamps up in the blocks table, as a
+// chain.TimestampSource that spares the RPC node the headers of blocks
+// already indexed.
+func BlockTimestamps(db *gorm.DB) chain.TimestampSource {
+	return func(ctx context.Context, number uint64) (uint64, bool, error) {
+		var block Block
+		err := db.WithContext(ctx).Select("timestamp").Where("number = ?", number).Take(&block).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, errors.Wrap(err, "block timestamp lookup")
+		}
+		return block.Timestamp, true, nil
+	}
+}
+
+// TablePrefix is the db.table_prefix db was opened with.
+func TablePrefix(db *gorm.DB) string {
+	if namer, ok := db.NamingStrategy.(schema.NamingStrategy); ok {
+		return namer.TablePrefix
+	}
+	return ""
+}
+
+// prefixedTable is the name of an indexer table in db, given its unprefixed
+// name, for the raw SQL that gorm does not name for us.
+func prefixedTable(db *gorm.DB, name string) string {
+	return TablePrefix(db) + name
 }
 
 func connect(ctx context.Context, cfg *config.DBConfig) (*gorm.DB, error) {
+	setStorageFormat(cfg.StorageFormat)
+
 	// Connect to the database
 	dbConfig := mysql.Config{
 		User:                 cfg.Username,
@@ -89,6 +160,7 @@ func connect(ctx context.Context, cfg *config.DBConfig) (*gorm.DB, error) {
 	gormConfig := gorm.Config{
 		Logger:          gormlogger.Default.LogMode(gormLogLevel),
 		CreateBatchSize: DBTransactionBatchesSize,
+		NamingStrategy:  schema.NamingStrategy{TablePrefix: cfg.TablePrefix},
 	}
 
 	db, err := gorm.Open(gormMysql.Open(dbConfig.FormatDSN()), &gormConfig)
@@ -7,16 +7,19 @@ type BaseEntity struct {
 	ID uint64 `gorm:"primaryKey;unique"`
 }
 
+// Hashes, addresses, topics, input and data are lowercase hex strings without
+// 0x prefix in Go; the hexbytes and hextopic serializers (storage.go) store
+// them as text or raw bytes depending on db.storage_format.
 type Transaction struct {
 	BaseEntity
-	Hash             string `gorm:"type:varchar(64);index;unique"`
+	Hash             string `gorm:"type:varchar(64);index;unique;serializer:hexbytes"`
 	FunctionSig      string `gorm:"type:varchar(50);index"`
-	Input            string `gorm:"type:string"`
+	Input            string `gorm:"type:string;serializer:hexbytes"`
 	BlockNumber      uint64 `gorm:"index"`
-	BlockHash        string `gorm:"type:varchar(64)"`
+	BlockHash        string `gorm:"type:varchar(64);serializer:hexbytes"`
 	TransactionIndex uint64
-	FromAddress      string `gorm:"type:varchar(40);index"`
-	ToAddress        string `gorm:"type:varchar(40);index"`
+	FromAddress      string `gorm:"type:varchar(40);index;serializer:hexbytes"`
+	ToAddress        string `gorm:"type:varchar(40);index;serializer:hexbytes"`
 	Status           uint64
 	Value            string `gorm:"type:string"`
 	GasPrice         string `gorm:"type:string"`
@@ -28,21 +31,183 @@ type Log struct {
 	BaseEntity
 	TransactionID   uint64       `gorm:"default:null"`
 	Transaction     *Transaction `gorm:"foreignKey:TransactionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
-	Address         string       `gorm:"type:varchar(40);index"`
-	Data            string       `gorm:"type:string"`
-	Topic0          string       `gorm:"type:varchar(64);index"`
-	Topic1          string       `gorm:"type:varchar(64);index"`
-	Topic2          string       `gorm:"type:varchar(64);index"`
-	Topic3          string       `gorm:"type:varchar(64);index"`
-	TransactionHash string       `gorm:"type:varchar(64);uniqueIndex:hash_index_unique"`
+	Address         string       `gorm:"type:varchar(40);index;serializer:hexbytes"`
+	Data            string       `gorm:"type:string;serializer:hexbytes"`
+	Topic0          string       `gorm:"type:varchar(64);index;serializer:hextopic"`
+	Topic1          string       `gorm:"type:varchar(64);index;serializer:hextopic"`
+	Topic2          string       `gorm:"type:varchar(64);index;serializer:hextopic"`
+	Topic3          string       `gorm:"type:varchar(64);index;serializer:hextopic"`
+	TransactionHash string       `gorm:"type:varchar(64);uniqueIndex:hash_index_unique;serializer:hexbytes"`
 	LogIndex        uint64       `gorm:"uniqueIndex:hash_index_unique"`
 	Timestamp       uint64       `gorm:"index"`
 	BlockNumber     uint64       `gorm:"index"`
 }
 
+// InternalCall is a call made by a contract while executing a transaction,
+// taken from the call trace of its block when indexer.trace_calls is set. Only
+// calls matching a collect_transactions filter are stored. CallIndex is the
+// position of the call in a depth-first walk of the whole call tree, 1 being
+// the first call the transaction made; Depth is 1 for calls made directly by
+// the transaction's target. There is no foreign key onto transactions, so
+// that transactions can still be partitioned; rows go with their transaction
+// in history drop.
+type InternalCall struct {
+	BaseEntity
+	TransactionID   uint64 `gorm:"index"`
+	TransactionHash string `gorm:"type:varchar(64);uniqueIndex:call_hash_index_unique;serializer:hexbytes"`
+	CallIndex       uint64 `gorm:"uniqueIndex:call_hash_index_unique"`
+	Depth           uint64
+	CallType        string `gorm:"type:varchar(20)"`
+	FunctionSig     string `gorm:"type:varchar(50);index"`
+	FromAddress     string `gorm:"type:varchar(40);index;serializer:hexbytes"`
+	ToAddress       string `gorm:"type:varchar(40);index;serializer:hexbytes"`
+	Input           string `gorm:"type:string;serializer:hexbytes"`
+	Output          string `gorm:"type:string;serializer:hexbytes"`
+	Value           string `gorm:"type:string"`
+	Reverted        bool
+	Error           string `gorm:"type:string"`
+	BlockNumber     uint64 `gorm:"index"`
+	Timestamp       uint64 `gorm:"index"`
+}
+
+// The FSP tables below are decoded in FSP mode from the reward-epoch events
+// among the collected logs. Addresses, hashes and keys are lowercase hex
+// strings without 0x prefix in either storage format, and token amounts and
+// weights wider than 64 bits are decimal strings. Each row keeps the block
+// number and timestamp of its event, so history drop removes it together
+// with the event's log.
+
+// SigningPolicy is the signing policy of a reward epoch, from the Relay's
+// SigningPolicyInitialized event. Its voters are in SigningPolicyVoter.
+type SigningPolicy struct {
+	BaseEntity
+	RewardEpochID      uint64 `gorm:"uniqueIndex:signing_policy_epoch_unique"`
+	StartVotingRoundID uint64
+	Threshold          uint64
+	Seed               string `gorm:"type:varchar(64)"`
+	TransactionHash    string `gorm:"type:varchar(64)"`
+	LogIndex           uint64
+	BlockNumber        uint64 `gorm:"index"`
+	Timestamp          uint64 `gorm:"index"`
+}
+
+// SigningPolicyVoter is a voter of a signing policy; VoterIndex is its
+// position in the policy, which signatures refer to.
+type SigningPolicyVoter struct {
+	BaseEntity
+	RewardEpochID        uint64 `gorm:"uniqueIndex:signing_policy_voter_unique"`
+	VoterIndex           uint64 `gorm:"uniqueIndex:signing_policy_voter_unique"`
+	SigningPolicyAddress string `gorm:"type:varchar(40);index"`
+	Weight               uint64
+	BlockNumber          uint64 `gorm:"index"`
+	Timestamp            uint64 `gorm:"index"`
+}
+
+// VoterRegistration is a voter's registration for a reward epoch. The
+// VoterRegistry's VoterRegistered event fills the addresses, public key and
+// registration weight; the FlareSystemsCalculator's VoterRegistrationInfo
+// event, emitted by the same registration, fills the delegation and node
+// columns. NodeIDs and NodeWeights are comma-separated lists in the same
+// order.
+type VoterRegistration struct {
+	BaseEntity
+	RewardEpochID           uint64 `gorm:"uniqueIndex:voter_registration_unique"`
+	Voter                   string `gorm:"type:varchar(40);uniqueIndex:voter_registration_unique"`
+	SigningPolicyAddress    string `gorm:"type:varchar(40);index"`
+	SubmitAddress           string `gorm:"type:varchar(40);index"`
+	SubmitSignaturesAddress string `gorm:"type:varchar(40);index"`
+	PublicKey               string `gorm:"type:varchar(128)"`
+	RegistrationWeight      string `gorm:"type:string"`
+	DelegationAddress       string `gorm:"type:varchar(40)"`
+	DelegationFeeBIPS       uint64 `gorm:"column:delegation_fee_bips"`
+	WNatWeight              string `gorm:"type:string"`
+	WNatCappedWeight        string `gorm:"type:string"`
+	NodeIDs                 string `gorm:"type:string"`
+	NodeWeights             string `gorm:"type:string"`
+	BlockNumber             uint64 `gorm:"index"`
+	Timestamp               uint64 `gorm:"index"`
+}
+
+// RewardOffer is a reward offered for a reward epoch to one protocol: ftso
+// (FtsoRewardOffersManager), fast_updates (FastUpdateIncentiveManager) or fdc
+// (FdcHub). Inflation is set for inflation offers and unset for community
+// offers and incentives. FeedIDs are the concatenated 21-byte ids of the
+// offer's feeds, empty for fdc; ClaimBackAddress is only set for community
+// FTSO offers.
+type RewardOffer struct {
+	BaseEntity
+	RewardEpochID    uint64 `gorm:"index"`
+	Protocol         string `gorm:"type:varchar(20);index"`
+	Inflation        bool
+	Amount           string `gorm:"type:string"`
+	FeedIDs          string `gorm:"type:string"`
+	ClaimBackAddress string `gorm:"type:varchar(40)"`
+	TransactionHash  string `gorm:"type:varchar(64);uniqueIndex:reward_offer_log_unique"`
+	LogIndex         uint64 `gorm:"uniqueIndex:reward_offer_log_unique"`
+	BlockNumber      uint64 `gorm:"index"`
+	Timestamp        uint64 `gorm:"index"`
+}
+
+// SubmissionPayload is one per-protocol message of an FSP submit1, submit2
+// or submitSignatures transaction, decoded from its calldata in FSP mode. A
+// transaction carries at most one message per protocol. Like internal calls,
+// rows have no foreign key onto transactions and go with their transaction in
+// history drop.
+type SubmissionPayload struct {
+	BaseEntity
+	TransactionID   uint64 `gorm:"index"`
+	TransactionHash string `gorm:"type:varchar(64);uniqueIndex:submission_payload_unique;serializer:hexbytes"`
+	FunctionSig     string `gorm:"type:varchar(50)"`
+	FromAddress     string `gorm:"type:varchar(40);index;serializer:hexbytes"`
+	ProtocolID      uint64 `gorm:"uniqueIndex:submission_payload_unique;index:submission_payload_round"`
+	VotingRoundID   uint64 `gorm:"index:submission_payload_round"`
+	Payload         string `gorm:"type:string;serializer:hexbytes"`
+	BlockNumber     uint64 `gorm:"index"`
+	Timestamp       uint64 `gorm:"index"`
+}
+
+// RewardEpoch is the on-chain timeline of a started reward epoch, read from
+// the FlareSystemsManager in FSP mode: its start, the random acquisition and
+// vote power block selection before it, and the signing of its signing
+// policy. Timestamp is the epoch's start timestamp.
+type RewardEpoch struct {
+	BaseEntity
+	RewardEpochID               uint64 `gorm:"uniqueIndex:reward_epoch_unique"`
+	StartVotingRoundID          uint64
+	StartBlock                  uint64
+	RandomAcquisitionStartBlock uint64
+	RandomAcquisitionEndBlock   uint64
+	VotePowerBlock              uint64
+	SigningPolicySignStartBlock uint64
+	SigningPolicySignEndBlock   uint64
+	Timestamp                   uint64 `gorm:"index"`
+}
+
+// VotingRound maps a voting round to the blocks with timestamps in its
+// window, in FSP mode. Timestamp is the round's start timestamp.
+type VotingRound struct {
+	BaseEntity
+	VotingRoundID uint64 `gorm:"uniqueIndex:voting_round_unique"`
+	FirstBlock    uint64 `gorm:"index"`
+	LastBlock     uint64 `gorm:"index"`
+	Timestamp     uint64 `gorm:"index"`
+}
+
+// ContractDeployment is an address the ContractRegistry returned for a
+// contract name, from block ActiveFromBlock on. For the address active when
+// discovery first ran, ActiveFromBlock is the start of the range searched
+// then, so the address may have been active before it. A known upgrade of
+// contracts.KnownDeployments has ActiveFromBlock 0.
+type ContractDeployment struct {
+	BaseEntity
+	ContractName    string `gorm:"type:varchar(64);uniqueIndex:contract_deployment_unique,priority:1"`
+	Address         string `gorm:"type:varchar(40);uniqueIndex:contract_deployment_unique,priority:2"`
+	ActiveFromBlock uint64 `gorm:"index"`
+}
+
 type Block struct {
 	BaseEntity
-	Hash      string `gorm:"type:varchar(64);index;unique"`
+	Hash      string `gorm:"type:varchar(64);index;unique;serializer:hexbytes"`
 	Number    uint64 `gorm:"index"`
 	Timestamp uint64 `gorm:"index"`
 }
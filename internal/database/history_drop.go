@@ -2,14 +2,17 @@ package database
 
 import (
 	"context"
+	"fmt"
 	"math/big"
 	"time"
 
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/admin"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/boff"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/tracing"
 
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
@@ -25,23 +28,27 @@ const (
 // backfill floor, first committed batch): while running it assumes it is the
 // only raiser of the first_* states, and the boundary gate in
 // dropAndRaiseFloor relies on the floors it reads being final apart from its
-// own writes.
+// own writes. The admin API can trigger an iteration early and change
+// checkInterval.
 func DropHistory(
 	ctx context.Context,
 	db *gorm.DB,
 	checkInterval uint64,
 	boundaryFn func(context.Context) (uint64, error),
 ) {
+	controls := admin.StartHistoryDrop(ctx, time.Duration(checkInterval)*time.Second)
 	for {
 		startTime := time.Now()
 		err := dropHistoryIteration(ctx, db, boundaryFn)
 		if err == nil {
-			logger.Infof("Finished history drop iteration: duration_ms=%d", time.Since(startTime).Milliseconds())
+			logging.From(ctx, logging.Database).Infow("Finished history drop iteration", "duration_ms", time.Since(startTime).Milliseconds())
 		} else {
-			logger.Errorf("History drop error: %s", err)
+			logging.From(ctx, logging.Database).Errorw("History drop error", "error", err)
 		}
 
-		time.Sleep(time.Duration(checkInterval) * time.Second)
+		if controls.WaitHistoryDrop(ctx, startTime) != nil {
+			return
+		}
 	}
 }
 
@@ -49,13 +56,17 @@ func DropHistory(
 // timeouts.
 const deleteBatchSize = 1000
 
-func dropHistoryIteration(ctx context.Context, db *gorm.DB, boundaryFn func(context.Context) (uint64, error)) error {
+func dropHistoryIteration(ctx context.Context, db *gorm.DB, boundaryFn func(context.Context) (uint64, error)) (err error) {
+	ctx, span := tracing.Start(ctx, "database.historyDrop")
+	defer func() { tracing.End(span, err) }()
+
 	boundary, err := boundaryFn(ctx)
 	if err != nil {
 		return errors.Wrap(err, "resolve history drop boundary")
 	}
 
-	logger.Infof("Starting history drop iteration: boundary=%d", boundary)
+	span.SetAttributes(tracing.Block("boundary", boundary))
+	logging.From(ctx, logging.Database).Infow("Starting history drop iteration", "boundary", boundary)
 	return dropHistoryBelow(ctx, db, boundary)
 }
 
@@ -82,20 +93,31 @@ func safeDeleteBoundary(lastBlockTime, intervalSeconds uint64) uint64 {
 	return lastBlockTime - intervalSeconds
 }
 
-// dropHistoryBelow runs two symmetric passes sharing one boundary: logs first
-// (they hold the FK on transactions), then transactions+blocks. Each pass
-// deletes below the boundary and maintains its own coverage floor from its own
-// table; in FSP mode logs are retained further back than blocks, so the floors
-// diverge until the log-only region is consumed. Should the two boundaries
-// ever be split, logs must be retained at least as long as blocks, or
-// first_database_block loses its all-logs-present guarantee.
+// dropHistoryBelow runs two symmetric passes sharing one boundary: logs and
+// the FSP tables decoded from them first (logs hold the FK on transactions),
+// then internal calls, submission payloads, transactions and blocks. Each
+// pass deletes below the boundary and maintains its own coverage floor from
+// its own table; in FSP mode logs are retained further back than blocks, so
+// the floors diverge until the log-only region is consumed. Should the two
+// boundaries ever be split, logs must be retained at least as long as
+// blocks, or first_database_block loses its all-logs-present guarantee.
 func dropHistoryBelow(ctx context.Context, db *gorm.DB, deleteStartTime uint64) error {
 	db = db.WithContext(ctx)
 
-	if err := dropAndRaiseFloor(db, deleteStartTime, LogFloor, firstSurvivingLog, Log{}); err != nil {
+	// The decoded FSP tables go with the logs they are decoded from, so they
+	// cover the same range as first_database_log_block; reward epochs go with
+	// the events of their signing-policy protocol, voting rounds with the
+	// blocks they map to.
+	err := dropAndRaiseFloor(
+		db, deleteStartTime, LogFloor, firstSurvivingLog,
+		Log{}, SigningPolicy{}, SigningPolicyVoter{}, VoterRegistration{}, RewardOffer{}, RewardEpoch{},
+	)
+	if err != nil {
 		return err
 	}
-	return dropAndRaiseFloor(db, deleteStartTime, BlockFloor, firstSurvivingBlock, Transaction{}, Block{})
+	return dropAndRaiseFloor(db, deleteStartTime, BlockFloor, firstSurvivingBlock,
+		InternalCall{}, SubmissionPayload{}, VotingRound{}, Transaction{}, Block{},
+	)
 }
 
 // dropAndRaiseFloor deletes the given entities below the boundary, then raises
@@ -112,6 +134,15 @@ func dropAndRaiseFloor(
 	entities ...interface{},
 ) error {
 	for _, entity := range entities {
+		table, err := tableName(db, entity)
+		if err != nil {
+			return err
+		}
+		// Whole expired partitions go at once; the row deletes below then
+		// only have the partition straddling the boundary left to clear.
+		if err := dropExpiredPartitions(db, table, deleteStartTime); err != nil {
+			return err
+		}
 		if err := DeleteInBatches(db, deleteStartTime, entity); err != nil {
 			return err
 		}
@@ -136,6 +167,14 @@ func dropAndRaiseFloor(
 	return UpdateState(db, floorState, index, timestamp)
 }
 
+func tableName(db *gorm.DB, entity interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entity); err != nil {
+		return "", errors.Wrapf(err, "parse table name of %T", entity)
+	}
+	return stmt.Schema.Table, nil
+}
+
 func firstSurvivingBlock(db *gorm.DB) (uint64, uint64, error) {
 	var block Block
 
@@ -188,20 +227,22 @@ func DeleteInBatches(db *gorm.DB, deleteStartTime uint64, entity interface{}) er
 		// Take a rest every so often to avoid locking up the database too much
 		batchCount++
 		if batchCount%deleteBatchesPauseAfter == 0 {
-			logger.Debugf("History drop progress: entity=%T, deleted=%d", entity, batchCount*deleteBatchSize)
+			logging.For(logging.Database).Debugw("History drop progress", "entity", fmt.Sprintf("%T", entity), "deleted", batchCount*deleteBatchSize)
 			time.Sleep(deleteBatchesPauseDuration)
 		}
 	}
 }
 
+// getBlockTimestamp returns the timestamp and number of a block, the latest
+// for a nil index. Only the header is fetched.
 func getBlockTimestamp(ctx context.Context, index *big.Int, client *chain.Client) (uint64, uint64, error) {
 	block, err := boff.RetryWithMaxElapsed(
 		ctx,
-		func() (*chain.Block, error) {
+		func() (*chain.Header, error) {
 			ctx, cancelFunc := context.WithTimeout(ctx, config.RPCTimeout)
 			defer cancelFunc()
 
-			return client.BlockByNumber(ctx, index)
+			return client.HeaderByNumber(ctx, index)
 		},
 		"getBlockTimestamp",
 	)
@@ -68,6 +68,7 @@ func TestDropHistoryFloors(t *testing.T) {
 			if tc.fspMode {
 				for _, n := range []uint64{1000, 1500, 1900} {
 					createLog(t, db, n)
+					require.NoError(t, db.Create(&SigningPolicy{RewardEpochID: n, BlockNumber: n, Timestamp: n}).Error)
 				}
 			}
 			for n := uint64(2000); n < 2010; n++ {
@@ -87,6 +88,8 @@ func TestDropHistoryFloors(t *testing.T) {
 			var below int64
 			require.NoError(t, db.Model(&Log{}).Where("timestamp < ?", tc.boundary).Count(&below).Error)
 			require.Zero(t, below, "logs below the boundary must be deleted")
+			require.NoError(t, db.Model(&SigningPolicy{}).Where("timestamp < ?", tc.boundary).Count(&below).Error)
+			require.Zero(t, below, "decoded FSP rows below the boundary must be deleted with their logs")
 		})
 	}
 }
@@ -0,0 +1,553 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// migrationLockName serializes migrations across processes sharing one
+// database (MySQL named lock, held on a single connection).
+const (
+	migrationLockName           = "flare_cchain_indexer_schema_migration"
+	migrationLockTimeoutSeconds = 60
+)
+
+// ErrSchemaTooNew is returned when the database was migrated by a newer
+// indexer than this binary. Running against it could write rows the newer
+// schema does not expect, so startup refuses instead.
+var ErrSchemaTooNew = errors.New("database schema is newer than this indexer supports")
+
+// SchemaMigration is one applied migration; the highest Version is the
+// schema version of the database.
+type SchemaMigration struct {
+	Version   uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"type:varchar(100)"`
+	AppliedAt time.Time
+}
+
+// TableName keeps the fixed schema_version name while still applying
+// db.table_prefix, which gorm skips for a plain TableName method.
+func (SchemaMigration) TableName(namer schema.Namer) string {
+	if ns, ok := namer.(schema.NamingStrategy); ok {
+		return ns.TablePrefix + "schema_version"
+	}
+	return "schema_version"
+}
+
+// Migration is an ordered up-migration. Plain schema changes are given as
+// SQL statements, run in order; changes that need the entity definitions or
+// data backfills use Up. Exactly one of the two is set. MySQL commits DDL
+// implicitly, so a migration is not atomic: keep each one to a single
+// logical change and make its statements safe to re-run after a partial
+// failure. SQL statements name tables as {prefix}name, so they apply to the
+// tables of the configured db.table_prefix.
+type Migration struct {
+	Version uint64
+	Name    string
+	SQL     []string
+	Up      func(tx *gorm.DB) error
+}
+
+// migrations is the ordered schema history. Append new migrations with the
+// next version; never edit or reorder an applied one.
+var migrations = []Migration{
+	{
+		// The schema the indexer used to create with AutoMigrate on every
+		// start, index and constraint names included. A database created
+		// before versioned migrations is brought to it by
+		// adoptUnversionedSchema first.
+		Version: 1,
+		Name:    "initial schema",
+		SQL: []string{
+			"CREATE TABLE IF NOT EXISTS `{prefix}states` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`name` varchar(50), " +
+				"`index` bigint unsigned, " +
+				"`block_timestamp` bigint unsigned, " +
+				"`updated` datetime(3) NULL, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `idx_states_name_unique` (`name`), " +
+				"CONSTRAINT `uni_{prefix}states_id` UNIQUE (`id`))",
+			"CREATE TABLE IF NOT EXISTS `{prefix}blocks` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`hash` varchar(64), " +
+				"`number` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"INDEX `idx_{prefix}blocks_hash` (`hash`), " +
+				"INDEX `idx_{prefix}blocks_number` (`number`), " +
+				"INDEX `idx_{prefix}blocks_timestamp` (`timestamp`), " +
+				"CONSTRAINT `uni_{prefix}blocks_id` UNIQUE (`id`), " +
+				"CONSTRAINT `uni_{prefix}blocks_hash` UNIQUE (`hash`))",
+			"CREATE TABLE IF NOT EXISTS `{prefix}transactions` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`hash` varchar(64), " +
+				"`function_sig` varchar(50), " +
+				"`input` longtext, " +
+				"`block_number` bigint unsigned, " +
+				"`block_hash` varchar(64), " +
+				"`transaction_index` bigint unsigned, " +
+				"`from_address` varchar(40), " +
+				"`to_address` varchar(40), " +
+				"`status` bigint unsigned, " +
+				"`value` longtext, " +
+				"`gas_price` longtext, " +
+				"`gas` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"INDEX `idx_{prefix}transactions_hash` (`hash`), " +
+				"INDEX `idx_{prefix}transactions_function_sig` (`function_sig`), " +
+				"INDEX `idx_{prefix}transactions_block_number` (`block_number`), " +
+				"INDEX `idx_{prefix}transactions_from_address` (`from_address`), " +
+				"INDEX `idx_{prefix}transactions_to_address` (`to_address`), " +
+				"INDEX `idx_{prefix}transactions_timestamp` (`timestamp`), " +
+				"CONSTRAINT `uni_{prefix}transactions_id` UNIQUE (`id`), " +
+				"CONSTRAINT `uni_{prefix}transactions_hash` UNIQUE (`hash`))",
+			"CREATE TABLE IF NOT EXISTS `{prefix}logs` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`transaction_id` bigint unsigned DEFAULT null, " +
+				"`address` varchar(40), " +
+				"`data` longtext, " +
+				"`topic0` varchar(64), " +
+				"`topic1` varchar(64), " +
+				"`topic2` varchar(64), " +
+				"`topic3` varchar(64), " +
+				"`transaction_hash` varchar(64), " +
+				"`log_index` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"`block_number` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"INDEX `idx_{prefix}logs_address` (`address`), " +
+				"INDEX `idx_{prefix}logs_topic0` (`topic0`), " +
+				"INDEX `idx_{prefix}logs_topic1` (`topic1`), " +
+				"INDEX `idx_{prefix}logs_topic2` (`topic2`), " +
+				"INDEX `idx_{prefix}logs_topic3` (`topic3`), " +
+				"UNIQUE INDEX `hash_index_unique` (`transaction_hash`, `log_index`), " +
+				"INDEX `idx_{prefix}logs_timestamp` (`timestamp`), " +
+				"INDEX `idx_{prefix}logs_block_number` (`block_number`), " +
+				"CONSTRAINT `fk_{prefix}logs_transaction` FOREIGN KEY (`transaction_id`) " +
+				"REFERENCES `{prefix}transactions`(`id`) ON DELETE CASCADE ON UPDATE CASCADE, " +
+				"CONSTRAINT `uni_{prefix}logs_id` UNIQUE (`id`))",
+		},
+	},
+	{
+		Version: 2,
+		Name:    "internal calls",
+		SQL: []string{
+			"CREATE TABLE IF NOT EXISTS `{prefix}internal_calls` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`transaction_id` bigint unsigned, " +
+				"`transaction_hash` varchar(64), " +
+				"`call_index` bigint unsigned, " +
+				"`depth` bigint unsigned, " +
+				"`call_type` varchar(20), " +
+				"`function_sig` varchar(50), " +
+				"`from_address` varchar(40), " +
+				"`to_address` varchar(40), " +
+				"`input` longtext, " +
+				"`output` longtext, " +
+				"`value` longtext, " +
+				"`reverted` boolean, " +
+				"`error` longtext, " +
+				"`block_number` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `call_hash_index_unique` (`transaction_hash`, `call_index`), " +
+				"INDEX `idx_internal_calls_transaction_id` (`transaction_id`), " +
+				"INDEX `idx_internal_calls_function_sig` (`function_sig`), " +
+				"INDEX `idx_internal_calls_from_address` (`from_address`), " +
+				"INDEX `idx_internal_calls_to_address` (`to_address`), " +
+				"INDEX `idx_internal_calls_block_number` (`block_number`), " +
+				"INDEX `idx_internal_calls_timestamp` (`timestamp`))",
+		},
+	},
+	{
+		Version: 3,
+		Name:    "decoded FSP tables",
+		SQL: []string{
+			"CREATE TABLE IF NOT EXISTS `{prefix}signing_policies` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`reward_epoch_id` bigint unsigned, " +
+				"`start_voting_round_id` bigint unsigned, " +
+				"`threshold` bigint unsigned, " +
+				"`seed` varchar(64), " +
+				"`transaction_hash` varchar(64), " +
+				"`log_index` bigint unsigned, " +
+				"`block_number` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `signing_policy_epoch_unique` (`reward_epoch_id`), " +
+				"INDEX `idx_signing_policies_block_number` (`block_number`), " +
+				"INDEX `idx_signing_policies_timestamp` (`timestamp`))",
+			"CREATE TABLE IF NOT EXISTS `{prefix}signing_policy_voters` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`reward_epoch_id` bigint unsigned, " +
+				"`voter_index` bigint unsigned, " +
+				"`signing_policy_address` varchar(40), " +
+				"`weight` bigint unsigned, " +
+				"`block_number` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `signing_policy_voter_unique` (`reward_epoch_id`, `voter_index`), " +
+				"INDEX `idx_signing_policy_voters_signing_policy_address` (`signing_policy_address`), " +
+				"INDEX `idx_signing_policy_voters_block_number` (`block_number`), " +
+				"INDEX `idx_signing_policy_voters_timestamp` (`timestamp`))",
+			"CREATE TABLE IF NOT EXISTS `{prefix}voter_registrations` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`reward_epoch_id` bigint unsigned, " +
+				"`voter` varchar(40), " +
+				"`signing_policy_address` varchar(40), " +
+				"`submit_address` varchar(40), " +
+				"`submit_signatures_address` varchar(40), " +
+				"`public_key` varchar(128), " +
+				"`registration_weight` longtext, " +
+				"`delegation_address` varchar(40), " +
+				"`delegation_fee_bips` bigint unsigned, " +
+				"`w_nat_weight` longtext, " +
+				"`w_nat_capped_weight` longtext, " +
+				"`node_ids` longtext, " +
+				"`node_weights` longtext, " +
+				"`block_number` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `voter_registration_unique` (`reward_epoch_id`, `voter`), " +
+				"INDEX `idx_voter_registrations_signing_policy_address` (`signing_policy_address`), " +
+				"INDEX `idx_voter_registrations_submit_address` (`submit_address`), " +
+				"INDEX `idx_voter_registrations_submit_signatures_address` (`submit_signatures_address`), " +
+				"INDEX `idx_voter_registrations_block_number` (`block_number`), " +
+				"INDEX `idx_voter_registrations_timestamp` (`timestamp`))",
+			"CREATE TABLE IF NOT EXISTS `{prefix}reward_offers` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`reward_epoch_id` bigint unsigned, " +
+				"`protocol` varchar(20), " +
+				"`inflation` boolean, " +
+				"`amount` longtext, " +
+				"`feed_ids` longtext, " +
+				"`claim_back_address` varchar(40), " +
+				"`transaction_hash` varchar(64), " +
+				"`log_index` bigint unsigned, " +
+				"`block_number` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `reward_offer_log_unique` (`transaction_hash`, `log_index`), " +
+				"INDEX `idx_reward_offers_reward_epoch_id` (`reward_epoch_id`), " +
+				"INDEX `idx_reward_offers_protocol` (`protocol`), " +
+				"INDEX `idx_reward_offers_block_number` (`block_number`), " +
+				"INDEX `idx_reward_offers_timestamp` (`timestamp`))",
+		},
+	},
+	{
+		Version: 4,
+		Name:    "submission payloads",
+		SQL: []string{
+			"CREATE TABLE IF NOT EXISTS `{prefix}submission_payloads` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`transaction_id` bigint unsigned, " +
+				"`transaction_hash` varchar(64), " +
+				"`function_sig` varchar(50), " +
+				"`from_address` varchar(40), " +
+				"`protocol_id` bigint unsigned, " +
+				"`voting_round_id` bigint unsigned, " +
+				"`payload` longtext, " +
+				"`block_number` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `submission_payload_unique` (`transaction_hash`, `protocol_id`), " +
+				"INDEX `submission_payload_round` (`protocol_id`, `voting_round_id`), " +
+				"INDEX `idx_submission_payloads_transaction_id` (`transaction_id`), " +
+				"INDEX `idx_submission_payloads_from_address` (`from_address`), " +
+				"INDEX `idx_submission_payloads_block_number` (`block_number`), " +
+				"INDEX `idx_submission_payloads_timestamp` (`timestamp`))",
+		},
+	},
+	{
+		Version: 5,
+		Name:    "reward epoch and voting round timeline",
+		SQL: []string{
+			"CREATE TABLE IF NOT EXISTS `{prefix}reward_epochs` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`reward_epoch_id` bigint unsigned, " +
+				"`start_voting_round_id` bigint unsigned, " +
+				"`start_block` bigint unsigned, " +
+				"`random_acquisition_start_block` bigint unsigned, " +
+				"`random_acquisition_end_block` bigint unsigned, " +
+				"`vote_power_block` bigint unsigned, " +
+				"`signing_policy_sign_start_block` bigint unsigned, " +
+				"`signing_policy_sign_end_block` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `reward_epoch_unique` (`reward_epoch_id`), " +
+				"INDEX `idx_reward_epochs_timestamp` (`timestamp`))",
+			"CREATE TABLE IF NOT EXISTS `{prefix}voting_rounds` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`voting_round_id` bigint unsigned, " +
+				"`first_block` bigint unsigned, " +
+				"`last_block` bigint unsigned, " +
+				"`timestamp` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `voting_round_unique` (`voting_round_id`), " +
+				"INDEX `idx_voting_rounds_first_block` (`first_block`), " +
+				"INDEX `idx_voting_rounds_last_block` (`last_block`), " +
+				"INDEX `idx_voting_rounds_timestamp` (`timestamp`))",
+		},
+	},
+	{
+		Version: 6,
+		Name:    "contract deployments",
+		SQL: []string{
+			"CREATE TABLE IF NOT EXISTS `{prefix}contract_deployments` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`contract_name` varchar(64), " +
+				"`address` varchar(40), " +
+				"`active_from_block` bigint unsigned, " +
+				"PRIMARY KEY (`id`), " +
+				"UNIQUE INDEX `contract_deployment_unique` (`contract_name`, `address`), " +
+				"INDEX `idx_contract_deployments_active_from_block` (`active_from_block`))",
+		},
+	},
+	{
+		Version: 7,
+		Name:    "chain metadata",
+		SQL: []string{
+			"CREATE TABLE IF NOT EXISTS `{prefix}chain_metadata` (" +
+				"`id` bigint unsigned AUTO_INCREMENT, " +
+				"`chain_id` bigint unsigned, " +
+				"`genesis_hash` varchar(64), " +
+				"`mode` varchar(20), " +
+				"`created` datetime(3) NULL, " +
+				"PRIMARY KEY (`id`))",
+		},
+	},
+}
+
+// LatestSchemaVersion is the schema version this binary migrates to.
+func LatestSchemaVersion() uint64 {
+	return migrations[len(migrations)-1].Version
+}
+
+// MigrationStatus compares the database schema version with this binary's.
+type MigrationStatus struct {
+	Current uint64
+	Latest  uint64
+	Applied []SchemaMigration
+	Pending []Migration
+}
+
+func (s *MigrationStatus) TooNew() bool {
+	return s.Current > s.Latest
+}
+
+// GetMigrationStatus reads the applied migrations. A database without a
+// schema_version table reads as version 0 with everything pending.
+func GetMigrationStatus(db *gorm.DB) (*MigrationStatus, error) {
+	var applied []SchemaMigration
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		if err := db.Order("version ASC").Find(&applied).Error; err != nil {
+			return nil, errors.Wrap(err, "read schema_version")
+		}
+	}
+
+	status := &MigrationStatus{Latest: LatestSchemaVersion(), Applied: applied}
+	if len(applied) > 0 {
+		status.Current = applied[len(applied)-1].Version
+	}
+	for _, m := range migrations {
+		if m.Version > status.Current {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// Migrate applies every pending migration in order and records each one in
+// schema_version as it completes. It fails with ErrSchemaTooNew when the
+// database is ahead of this binary.
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := acquireMigrationLock(conn); err != nil {
+			return err
+		}
+		defer releaseMigrationLock(conn)
+
+		if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+			return errors.Wrap(err, "create schema_version")
+		}
+
+		status, err := GetMigrationStatus(conn)
+		if err != nil {
+			return err
+		}
+		if status.TooNew() {
+			return errors.Wrapf(ErrSchemaTooNew, "database at version %d, indexer supports up to %d", status.Current, status.Latest)
+		}
+		if status.Current == 0 {
+			if err := adoptUnversionedSchema(conn); err != nil {
+				return errors.Wrap(err, "adopt unversioned schema")
+			}
+		}
+
+		for _, m := range status.Pending {
+			start := time.Now()
+			if err := applyMigration(conn, m); err != nil {
+				return errors.Wrapf(err, "migration %d (%s)", m.Version, m.Name)
+			}
+			logging.From(ctx, logging.Database).Infow(
+				"Applied schema migration",
+				"version", m.Version,
+				"name", m.Name,
+				"duration_ms", time.Since(start).Milliseconds(),
+			)
+		}
+
+		return nil
+	})
+}
+
+func applyMigration(db *gorm.DB, m Migration) error {
+	if m.Up != nil {
+		if err := m.Up(db); err != nil {
+			return err
+		}
+	}
+	if err := execMigrationSQL(db, m); err != nil {
+		return err
+	}
+
+	return db.Create(&SchemaMigration{
+		Version:   m.Version,
+		Name:      m.Name,
+		AppliedAt: time.Now(),
+	}).Error
+}
+
+// execMigrationSQL runs the SQL statements of a migration.
+func execMigrationSQL(db *gorm.DB, m Migration) error {
+	for _, stmt := range m.SQL {
+		stmt = strings.ReplaceAll(stmt, "{prefix}", TablePrefix(db))
+		if err := db.Exec(stmt).Error; err != nil {
+			return errors.Wrapf(err, "exec %q", stmt)
+		}
+	}
+	return nil
+}
+
+// Describe returns a human-readable plan line for the migration, listing its
+// SQL statements when it has any.
+func (m Migration) Describe() string {
+	desc := fmt.Sprintf("%d: %s", m.Version, m.Name)
+	if m.Up != nil {
+		desc += "\n    (Go migration)"
+	}
+	for _, stmt := range m.SQL {
+		desc += "\n    " + stmt + ";"
+	}
+	return desc
+}
+
+func acquireMigrationLock(conn *gorm.DB) error {
+	var acquired *int
+	err := conn.Raw("SELECT GET_LOCK(?, ?)", migrationLockName, migrationLockTimeoutSeconds).Scan(&acquired).Error
+	if err != nil {
+		return errors.Wrap(err, "acquire migration lock")
+	}
+	if acquired == nil || *acquired != 1 {
+		return errors.Errorf("timed out waiting for migration lock %q", migrationLockName)
+	}
+	return nil
+}
+
+func releaseMigrationLock(conn *gorm.DB) {
+	if err := conn.Exec("SELECT RELEASE_LOCK(?)", migrationLockName).Error; err != nil {
+		logging.For(logging.Database).Warnw("Failed to release migration lock", "error", err)
+	}
+}
+
+// adoptUnversionedSchema brings the tables of a database created before
+// versioned migrations, by an indexer that ran AutoMigrate on every start,
+// to the schema of migration 1, which then finds them in place. It migrates
+// only the tables that exist, from frozen copies of the entities of that
+// time; a new database is created by migration 1 alone.
+func adoptUnversionedSchema(db *gorm.DB) error {
+	for _, model := range []interface{}{&v1State{}, &v1Block{}, &v1Transaction{}, &v1Log{}} {
+		if !db.Migrator().HasTable(model) {
+			continue
+		}
+		if err := db.AutoMigrate(model); err != nil {
+			return errors.Wrapf(err, "migrate %T", model)
+		}
+	}
+	return nil
+}
+
+// The entities as of migration 1. They must not change.
+
+type v1State struct {
+	ID             uint64 `gorm:"primaryKey;unique"`
+	Name           string `gorm:"type:varchar(50);uniqueIndex:idx_states_name_unique"`
+	Index          uint64
+	BlockTimestamp uint64
+	Updated        time.Time
+}
+
+type v1Block struct {
+	ID        uint64 `gorm:"primaryKey;unique"`
+	Hash      string `gorm:"type:varchar(64);index;unique"`
+	Number    uint64 `gorm:"index"`
+	Timestamp uint64 `gorm:"index"`
+}
+
+type v1Transaction struct {
+	ID               uint64 `gorm:"primaryKey;unique"`
+	Hash             string `gorm:"type:varchar(64);index;unique"`
+	FunctionSig      string `gorm:"type:varchar(50);index"`
+	Input            string `gorm:"type:string"`
+	BlockNumber      uint64 `gorm:"index"`
+	BlockHash        string `gorm:"type:varchar(64)"`
+	TransactionIndex uint64
+	FromAddress      string `gorm:"type:varchar(40);index"`
+	ToAddress        string `gorm:"type:varchar(40);index"`
+	Status           uint64
+	Value            string `gorm:"type:string"`
+	GasPrice         string `gorm:"type:string"`
+	Gas              uint64
+	Timestamp        uint64 `gorm:"index"`
+}
+
+type v1Log struct {
+	ID              uint64         `gorm:"primaryKey;unique"`
+	TransactionID   uint64         `gorm:"default:null"`
+	Transaction     *v1Transaction `gorm:"foreignKey:TransactionID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Address         string         `gorm:"type:varchar(40);index"`
+	Data            string         `gorm:"type:string"`
+	Topic0          string         `gorm:"type:varchar(64);index"`
+	Topic1          string         `gorm:"type:varchar(64);index"`
+	Topic2          string         `gorm:"type:varchar(64);index"`
+	Topic3          string         `gorm:"type:varchar(64);index"`
+	TransactionHash string         `gorm:"type:varchar(64);uniqueIndex:hash_index_unique"`
+	LogIndex        uint64         `gorm:"uniqueIndex:hash_index_unique"`
+	Timestamp       uint64         `gorm:"index"`
+	BlockNumber     uint64         `gorm:"index"`
+}
+
+func (v1State) TableName(namer schema.Namer) string       { return v1TableName(namer, "states") }
+func (v1Block) TableName(namer schema.Namer) string       { return v1TableName(namer, "blocks") }
+func (v1Transaction) TableName(namer schema.Namer) string { return v1TableName(namer, "transactions") }
+func (v1Log) TableName(namer schema.Namer) string         { return v1TableName(namer, "logs") }
+
+// v1TableName applies db.table_prefix like SchemaMigration.TableName.
+func v1TableName(namer schema.Namer, name string) string {
+	if ns, ok := namer.(schema.NamingStrategy); ok {
+		return ns.TablePrefix + name
+	}
+	return name
+}
@@ -0,0 +1,183 @@
+package database
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		require.Equal(t, uint64(i+1), m.Version, "migration versions must be contiguous from 1")
+		require.NotEmpty(t, m.Name)
+		require.True(t, (m.Up != nil) != (len(m.SQL) > 0), "migration %d must set exactly one of Up and SQL", m.Version)
+	}
+}
+
+// ddlLogger records the statements of a dry run.
+type ddlLogger struct {
+	gormlogger.Interface
+	statements *[]string
+}
+
+func (l ddlLogger) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	sql, _ := fc()
+	*l.statements = append(*l.statements, sql)
+}
+
+func TestInitialSchemaMatchesAdoption(t *testing.T) {
+	var created []string
+	db, err := gorm.Open(mysql.New(mysql.Config{DSN: "u:p@tcp(127.0.0.1:1)/db", SkipInitializeWithVersion: true}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		Logger:               ddlLogger{gormlogger.Discard, &created},
+		NamingStrategy:       schema.NamingStrategy{TablePrefix: "flr_"},
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.Migrator().CreateTable(&v1State{}, &v1Block{}, &v1Transaction{}, &v1Log{}))
+
+	require.Len(t, created, len(migrations[0].SQL))
+	for i, stmt := range migrations[0].SQL {
+		want := strings.ReplaceAll(strings.Replace(created[i], "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1), ",", ", ")
+		got := strings.ReplaceAll(stmt, "{prefix}", "flr_")
+		require.Equal(t, want, got, "migration 1 must create what AutoMigrate created before versioning")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDSNEnv)
+	}
+
+	t.Run("adopts a pre-migrations database and is idempotent", func(t *testing.T) {
+		db := setupScratchDB(t, dsn) // tables created by AutoMigrate, no schema_version
+
+		require.NoError(t, Migrate(context.Background(), db))
+		require.NoError(t, Migrate(context.Background(), db))
+
+		status, err := GetMigrationStatus(db)
+		require.NoError(t, err)
+		require.Equal(t, LatestSchemaVersion(), status.Current)
+		require.Empty(t, status.Pending)
+	})
+
+	t.Run("refuses a newer schema", func(t *testing.T) {
+		db := setupScratchDB(t, dsn)
+		require.NoError(t, Migrate(context.Background(), db))
+		require.NoError(t, db.Create(&SchemaMigration{
+			Version: LatestSchemaVersion() + 1, Name: "from the future", AppliedAt: time.Now(),
+		}).Error)
+
+		require.ErrorIs(t, Migrate(context.Background(), db), ErrSchemaTooNew)
+	})
+
+	t.Run("table prefixes keep schemas apart", func(t *testing.T) {
+		setupScratchDB(t, dsn)
+		open := func(prefix string) *gorm.DB {
+			db, err := gorm.Open(mysql.Open(dsn+"history_drop_test?parseTime=true"), &gorm.Config{
+				Logger:         gormlogger.Discard,
+				NamingStrategy: schema.NamingStrategy{TablePrefix: prefix},
+			})
+			require.NoError(t, err)
+			require.NoError(t, Migrate(context.Background(), db))
+			return db
+		}
+		flare, coston := open("flr_"), open("c2_")
+
+		require.True(t, flare.Migrator().HasTable("flr_schema_version"))
+		require.True(t, flare.Migrator().HasTable("c2_blocks"))
+		require.NoError(t, flare.Create(&Block{Hash: "aa", Number: 1, Timestamp: 1}).Error)
+
+		var count int64
+		require.NoError(t, coston.Model(&Block{}).Count(&count).Error)
+		require.Zero(t, count)
+	})
+	t.Run("creates the internal calls table of the entity", func(t *testing.T) {
+		db := setupScratchDB(t, dsn)
+		require.NoError(t, db.Migrator().DropTable(&InternalCall{}))
+		require.NoError(t, Migrate(context.Background(), db))
+
+		call := InternalCall{
+			TransactionID:   1,
+			TransactionHash: strings.Repeat("01", 32),
+			CallIndex:       2,
+			Depth:           1,
+			CallType:        "CALL",
+			FunctionSig:     "12345678",
+			FromAddress:     strings.Repeat("02", 20),
+			ToAddress:       strings.Repeat("03", 20),
+			Input:           "12345678",
+			Output:          "",
+			Value:           "0",
+			Reverted:        true,
+			Error:           "execution reverted",
+			BlockNumber:     5,
+			Timestamp:       50,
+		}
+		require.NoError(t, db.Create(&call).Error)
+
+		var got InternalCall
+		require.NoError(t, db.First(&got, call.ID).Error)
+		require.Equal(t, call, got)
+	})
+	t.Run("creates the decoded FSP tables of the entities", func(t *testing.T) {
+		db := setupScratchDB(t, dsn)
+		require.NoError(t, db.Migrator().DropTable(
+			&SigningPolicy{}, &SigningPolicyVoter{}, &VoterRegistration{}, &RewardOffer{}, &SubmissionPayload{},
+			&RewardEpoch{}, &VotingRound{}, &ContractDeployment{},
+		))
+		require.NoError(t, Migrate(context.Background(), db))
+
+		registration := VoterRegistration{
+			RewardEpochID:        7,
+			Voter:                strings.Repeat("01", 20),
+			SigningPolicyAddress: strings.Repeat("02", 20),
+			PublicKey:            strings.Repeat("03", 64),
+			RegistrationWeight:   "1000000000000000000",
+			DelegationFeeBIPS:    2000,
+			NodeIDs:              strings.Repeat("04", 20),
+			NodeWeights:          "5",
+			BlockNumber:          5,
+			Timestamp:            50,
+		}
+		require.NoError(t, db.Create(&registration).Error)
+
+		var got VoterRegistration
+		require.NoError(t, db.First(&got, registration.ID).Error)
+		require.Equal(t, registration, got)
+
+		for _, entity := range []interface{}{
+			&SigningPolicy{}, &SigningPolicyVoter{}, &RewardOffer{}, &RewardEpoch{}, &VotingRound{},
+			&ContractDeployment{},
+		} {
+			require.True(t, db.Migrator().HasTable(entity))
+		}
+
+		submission := SubmissionPayload{
+			TransactionID:   1,
+			TransactionHash: strings.Repeat("05", 32),
+			FunctionSig:     "9d00c9fd",
+			FromAddress:     strings.Repeat("06", 20),
+			ProtocolID:      100,
+			VotingRoundID:   9000,
+			Payload:         "010203",
+			BlockNumber:     5,
+			Timestamp:       50,
+		}
+		require.NoError(t, db.Create(&submission).Error)
+
+		var gotSubmission SubmissionPayload
+		require.NoError(t, db.First(&gotSubmission, submission.ID).Error)
+		require.Equal(t, submission, gotSubmission)
+	})
+}
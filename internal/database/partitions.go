@@ -0,0 +1,436 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// PartitionMaintenanceInterval is how often partitions are added ahead of
+	// the incoming data.
+	PartitionMaintenanceInterval = 10 * time.Minute
+
+	catchAllPartition = "pmax"
+	// maxPartitions stays well below MySQL's 8192 limit; a schema that would
+	// exceed it needs a larger partition_size instead.
+	maxPartitions = 1024
+)
+
+// partitionedTable is a table range-partitioned on the column holding the
+// configured partition key.
+type partitionedTable struct {
+	name   string
+	column string
+}
+
+// partitionedTables lists the partitioned tables in the order they are
+// converted: logs first, since its foreign key onto transactions must be
+// dropped before transactions can be partitioned.
+func partitionedTables(db *gorm.DB, mode string) []partitionedTable {
+	if mode == config.PartitioningBlock {
+		return []partitionedTable{
+			{name: prefixedTable(db, "logs"), column: "block_number"},
+			{name: prefixedTable(db, "transactions"), column: "block_number"},
+			{name: prefixedTable(db, "blocks"), column: "number"},
+		}
+	}
+	return []partitionedTable{
+		{name: prefixedTable(db, "logs"), column: "timestamp"},
+		{name: prefixedTable(db, "transactions"), column: "timestamp"},
+		{name: prefixedTable(db, "blocks"), column: "timestamp"},
+	}
+}
+
+// partition is one range partition; bound is its exclusive upper bound,
+// maxValue marks the MAXVALUE catch-all.
+type partition struct {
+	name     string
+	bound    uint64
+	maxValue bool
+}
+
+// EnsurePartitioning converts blocks, transactions and logs to range
+// partitioning when cfg.Partitioning is set, and creates partitions ahead of
+// the current data. Converting an existing table rebuilds it and can take a
+// long time on a large database; it happens once, on the first start with
+// partitioning enabled. Partitioned tables cannot hold foreign keys and every
+// unique key must contain the partition column, so the logs→transactions
+// foreign key is dropped and the partition column is appended to each unique
+// key. Rows are still unique by hash, since a hash determines its block.
+func EnsurePartitioning(ctx context.Context, db *gorm.DB, cfg *config.DBConfig) error {
+	if !cfg.PartitioningEnabled() {
+		return nil
+	}
+	db = db.WithContext(ctx)
+
+	for _, table := range partitionedTables(db, cfg.Partitioning) {
+		parts, err := tablePartitions(db, table.name)
+		if err != nil {
+			return err
+		}
+		if len(parts) > 0 {
+			continue
+		}
+
+		if err := convertToPartitioned(db, cfg, table); err != nil {
+			return errors.Wrapf(err, "partition table %s", table.name)
+		}
+	}
+
+	return MaintainPartitionsOnce(db, cfg)
+}
+
+// MaintainPartitions periodically adds partitions ahead of the incoming
+// data, so inserts land in their own partitions instead of the catch-all,
+// until ctx is done.
+func MaintainPartitions(ctx context.Context, db *gorm.DB, cfg *config.DBConfig) {
+	for {
+		if err := MaintainPartitionsOnce(db.WithContext(ctx), cfg); err != nil {
+			logging.From(ctx, logging.Database).Errorw("Partition maintenance error", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(PartitionMaintenanceInterval):
+		}
+	}
+}
+
+// MaintainPartitionsOnce splits new partitions off the catch-all until the
+// partitions reach PartitionsAhead partitions past the current reference
+// point (the wall clock, or the chain tip in block mode).
+func MaintainPartitionsOnce(db *gorm.DB, cfg *config.DBConfig) error {
+	for _, table := range partitionedTables(db, cfg.Partitioning) {
+		parts, err := tablePartitions(db, table.name)
+		if err != nil {
+			return err
+		}
+		if len(parts) == 0 {
+			continue
+		}
+
+		reference, err := partitionReference(db, cfg, table)
+		if err != nil {
+			return err
+		}
+
+		highest := highestBound(parts)
+		bounds := partitionBounds(highest, aheadBound(reference, cfg), cfg.PartitionSize)
+		if len(bounds) == 0 {
+			continue
+		}
+		if len(parts)+len(bounds) > maxPartitions {
+			return errors.Errorf(
+				"table %s would exceed %d partitions; raise db.partition_size", table.name, maxPartitions,
+			)
+		}
+
+		stmt := fmt.Sprintf(
+			"ALTER TABLE `%s` REORGANIZE PARTITION %s INTO (%s)",
+			table.name, catchAllPartition, partitionDefinitions(bounds),
+		)
+		if err := db.Exec(stmt).Error; err != nil {
+			return errors.Wrapf(err, "add partitions to %s", table.name)
+		}
+		logging.For(logging.Database).Infow("Added partitions", "table", table.name, "count", len(bounds), "up_to", bounds[len(bounds)-1])
+	}
+
+	return nil
+}
+
+// dropExpiredPartitions drops every whole partition of a partitioned table
+// holding only rows older than deleteStartTime; it is a no-op on an
+// unpartitioned table. Rows below the boundary in the partition straddling
+// it are left to the row deletes.
+func dropExpiredPartitions(db *gorm.DB, table string, deleteStartTime uint64) error {
+	parts, err := tablePartitions(db, table)
+	if err != nil || len(parts) == 0 {
+		return err
+	}
+
+	column, err := partitionColumn(db, table)
+	if err != nil {
+		return err
+	}
+
+	// The first value still to be retained: everything in a partition whose
+	// exclusive bound is at or below it is expired. In block mode that is the
+	// lowest block holding a retained row; with no retained rows nothing is
+	// dropped, so future partitions are never touched.
+	retainFrom := deleteStartTime
+	if column != "timestamp" {
+		var lowest *uint64
+		err := db.Table(table).Select(fmt.Sprintf("MIN(`%s`)", column)).
+			Where("timestamp >= ?", deleteStartTime).Scan(&lowest).Error
+		if err != nil {
+			return errors.Wrapf(err, "find lowest retained %s in %s", column, table)
+		}
+		if lowest == nil {
+			return nil
+		}
+		retainFrom = *lowest
+	}
+
+	expired := expiredPartitions(parts, retainFrom)
+	if len(expired) == 0 {
+		return nil
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE `%s` DROP PARTITION %s", table, strings.Join(expired, ", "))
+	if err := db.Exec(stmt).Error; err != nil {
+		return errors.Wrapf(err, "drop partitions of %s", table)
+	}
+	logging.For(logging.Database).Infow("History drop removed partitions", "table", table, "partitions", strings.Join(expired, ","))
+	return nil
+}
+
+// expiredPartitions returns the leading partitions whose exclusive upper
+// bound is at or below retainFrom. The catch-all is never expired.
+func expiredPartitions(parts []partition, retainFrom uint64) []string {
+	var expired []string
+	for _, p := range parts {
+		if p.maxValue || p.bound > retainFrom {
+			break
+		}
+		expired = append(expired, p.name)
+	}
+	return expired
+}
+
+func convertToPartitioned(db *gorm.DB, cfg *config.DBConfig, table partitionedTable) error {
+	logging.For(logging.Database).Infof("Converting table to partitioned: table=%s, column=%s (this rebuilds the table)", table.name, table.column)
+	start := time.Now()
+
+	if err := dropForeignKeys(db, table.name); err != nil {
+		return err
+	}
+	if err := extendUniqueKeys(db, table); err != nil {
+		return err
+	}
+
+	var lowest *uint64
+	if err := db.Table(table.name).Select(fmt.Sprintf("MIN(`%s`)", table.column)).Scan(&lowest).Error; err != nil {
+		return errors.Wrap(err, "find lowest partition key")
+	}
+	reference, err := partitionReference(db, cfg, table)
+	if err != nil {
+		return err
+	}
+	from := reference
+	if lowest != nil && *lowest < from {
+		from = *lowest
+	}
+
+	// The first partition holds everything below the first aligned bound
+	// above from; the rest tile up to the ahead bound.
+	bounds := partitionBounds(alignDown(from, cfg.PartitionSize), aheadBound(reference, cfg), cfg.PartitionSize)
+	if len(bounds) > maxPartitions {
+		return errors.Errorf("%d partitions needed, more than %d; raise db.partition_size", len(bounds), maxPartitions)
+	}
+
+	stmt := fmt.Sprintf(
+		"ALTER TABLE `%s` PARTITION BY RANGE (`%s`) (%s)",
+		table.name, table.column, partitionDefinitions(bounds),
+	)
+	if err := db.Exec(stmt).Error; err != nil {
+		return err
+	}
+
+	logging.For(logging.Database).Infow(
+		"Converted table to partitioned",
+		"table", table.name,
+		"partitions", len(bounds)+1,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+func dropForeignKeys(db *gorm.DB, table string) error {
+	var keys []struct {
+		ConstraintName string
+		TableName      string
+	}
+	err := db.Raw(
+		`SELECT CONSTRAINT_NAME AS constraint_name, TABLE_NAME AS table_name
+		 FROM information_schema.REFERENTIAL_CONSTRAINTS
+		 WHERE CONSTRAINT_SCHEMA = DATABASE() AND (TABLE_NAME = ? OR REFERENCED_TABLE_NAME = ?)`,
+		table, table,
+	).Scan(&keys).Error
+	if err != nil {
+		return errors.Wrap(err, "list foreign keys")
+	}
+
+	for _, key := range keys {
+		stmt := fmt.Sprintf("ALTER TABLE `%s` DROP FOREIGN KEY `%s`", key.TableName, key.ConstraintName)
+		if err := db.Exec(stmt).Error; err != nil {
+			return errors.Wrapf(err, "drop foreign key %s", key.ConstraintName)
+		}
+	}
+	return nil
+}
+
+// extendUniqueKeys appends the partition column to every unique key that
+// lacks it, as MySQL requires of partitioned tables.
+func extendUniqueKeys(db *gorm.DB, table partitionedTable) error {
+	var keys []struct {
+		IndexName string
+		Columns   string
+	}
+	err := db.Raw(
+		`SELECT INDEX_NAME AS index_name, GROUP_CONCAT(COLUMN_NAME ORDER BY SEQ_IN_INDEX) AS columns
+		 FROM information_schema.STATISTICS
+		 WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND NON_UNIQUE = 0
+		 GROUP BY INDEX_NAME`,
+		table.name,
+	).Scan(&keys).Error
+	if err != nil {
+		return errors.Wrap(err, "list unique keys")
+	}
+
+	for _, key := range keys {
+		columns := strings.Split(key.Columns, ",")
+		if slices.Contains(columns, table.column) {
+			continue
+		}
+		columns = append(columns, table.column)
+		quoted := "`" + strings.Join(columns, "`, `") + "`"
+
+		var stmt string
+		if key.IndexName == "PRIMARY" {
+			stmt = fmt.Sprintf("ALTER TABLE `%s` DROP PRIMARY KEY, ADD PRIMARY KEY (%s)", table.name, quoted)
+		} else {
+			stmt = fmt.Sprintf(
+				"ALTER TABLE `%s` DROP INDEX `%s`, ADD UNIQUE INDEX `%s` (%s)",
+				table.name, key.IndexName, key.IndexName, quoted,
+			)
+		}
+		if err := db.Exec(stmt).Error; err != nil {
+			return errors.Wrapf(err, "extend unique key %s", key.IndexName)
+		}
+	}
+	return nil
+}
+
+func tablePartitions(db *gorm.DB, table string) ([]partition, error) {
+	var rows []struct {
+		PartitionName        *string
+		PartitionDescription *string
+	}
+	err := db.Raw(
+		`SELECT PARTITION_NAME AS partition_name, PARTITION_DESCRIPTION AS partition_description
+		 FROM information_schema.PARTITIONS
+		 WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?
+		 ORDER BY PARTITION_ORDINAL_POSITION`,
+		table,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, errors.Wrapf(err, "list partitions of %s", table)
+	}
+
+	var parts []partition
+	for _, row := range rows {
+		if row.PartitionName == nil || row.PartitionDescription == nil {
+			continue // unpartitioned tables report a single NULL row
+		}
+		p := partition{name: *row.PartitionName}
+		if *row.PartitionDescription == "MAXVALUE" {
+			p.maxValue = true
+		} else {
+			bound, err := strconv.ParseUint(*row.PartitionDescription, 10, 64)
+			if err != nil {
+				return nil, errors.Wrapf(err, "parse bound of partition %s.%s", table, p.name)
+			}
+			p.bound = bound
+		}
+		parts = append(parts, p)
+	}
+	return parts, nil
+}
+
+func partitionColumn(db *gorm.DB, table string) (string, error) {
+	var expression *string
+	err := db.Raw(
+		`SELECT PARTITION_EXPRESSION FROM information_schema.PARTITIONS
+		 WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? LIMIT 1`,
+		table,
+	).Scan(&expression).Error
+	if err != nil {
+		return "", errors.Wrapf(err, "read partition expression of %s", table)
+	}
+	if expression == nil {
+		return "", errors.Errorf("table %s is not partitioned", table)
+	}
+	return strings.Trim(*expression, "`"), nil
+}
+
+// partitionReference is the current position of the partition key: the wall
+// clock in timestamp mode, the highest of the indexed data and the observed
+// chain tip in block mode.
+func partitionReference(db *gorm.DB, cfg *config.DBConfig, table partitionedTable) (uint64, error) {
+	if cfg.Partitioning != config.PartitioningBlock {
+		return uint64(time.Now().Unix()), nil
+	}
+
+	var highest *uint64
+	if err := db.Table(table.name).Select(fmt.Sprintf("MAX(`%s`)", table.column)).Scan(&highest).Error; err != nil {
+		return 0, errors.Wrapf(err, "find highest %s in %s", table.column, table.name)
+	}
+	tip, err := GetState(db, ChainTip)
+	if err != nil {
+		return 0, errors.Wrap(err, "GetState(ChainTip)")
+	}
+
+	reference := tip.Index
+	if highest != nil && *highest > reference {
+		reference = *highest
+	}
+	return reference, nil
+}
+
+func aheadBound(reference uint64, cfg *config.DBConfig) uint64 {
+	return alignDown(reference, cfg.PartitionSize) + (cfg.PartitionsAhead+1)*cfg.PartitionSize
+}
+
+// partitionBounds returns the aligned exclusive upper bounds above after, up
+// to and including upTo.
+func partitionBounds(after, upTo, size uint64) []uint64 {
+	var bounds []uint64
+	for bound := alignDown(after, size) + size; bound <= upTo; bound += size {
+		bounds = append(bounds, bound)
+	}
+	return bounds
+}
+
+func highestBound(parts []partition) uint64 {
+	var highest uint64
+	for _, p := range parts {
+		if !p.maxValue && p.bound > highest {
+			highest = p.bound
+		}
+	}
+	return highest
+}
+
+func partitionDefinitions(bounds []uint64) string {
+	defs := make([]string, 0, len(bounds)+1)
+	for _, bound := range bounds {
+		defs = append(defs, fmt.Sprintf("PARTITION p%d VALUES LESS THAN (%d)", bound, bound))
+	}
+	defs = append(defs, fmt.Sprintf("PARTITION %s VALUES LESS THAN MAXVALUE", catchAllPartition))
+	return strings.Join(defs, ", ")
+}
+
+func alignDown(value, size uint64) uint64 {
+	return value - value%size
+}
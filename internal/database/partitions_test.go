@@ -0,0 +1,46 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionBounds(t *testing.T) {
+	require.Equal(t, []uint64{200, 300, 400}, partitionBounds(150, 400, 100), "starts at the first aligned bound above")
+	require.Equal(t, []uint64{300}, partitionBounds(200, 399, 100), "an aligned start is already covered")
+	require.Empty(t, partitionBounds(400, 400, 100), "nothing to add once the ahead bound is reached")
+}
+
+func TestAheadBound(t *testing.T) {
+	cfg := &config.DBConfig{PartitionSize: 100, PartitionsAhead: 3}
+	// The partition holding the reference plus three empty ones above it.
+	require.Equal(t, uint64(500), aheadBound(150, cfg))
+}
+
+func TestExpiredPartitions(t *testing.T) {
+	parts := []partition{
+		{name: "p100", bound: 100},
+		{name: "p200", bound: 200},
+		{name: "p300", bound: 300},
+		{name: catchAllPartition, maxValue: true},
+	}
+
+	tests := []struct {
+		name       string
+		retainFrom uint64
+		want       []string
+	}{
+		{"nothing expired", 50, nil},
+		{"bound equal to retain point is expired", 200, []string{"p100", "p200"}},
+		{"straddling partition is kept for row deletes", 250, []string{"p100", "p200"}},
+		{"catch-all is never dropped", 10_000, []string{"p100", "p200", "p300"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, expiredPartitions(parts, tc.retainFrom))
+		})
+	}
+}
@@ -10,7 +10,9 @@ import (
 
 // StateName identifies a row in the states table. The string values are a
 // cross-repo contract (consumers read them by name) — rename identifiers
-// freely, never the values without a coordinated migration.
+// freely, never the values without a coordinated migration: a schema
+// migration (migrations.go) that renames the rows, released together with
+// the consumers that read them.
 type StateName string
 
 const (
@@ -27,6 +29,9 @@ const (
 	LogFloor StateName = "first_database_log_block"
 )
 
+// AllStateNames lists every StateName, in the order status reports show them.
+var AllStateNames = []StateName{ChainTip, LastIndexed, BlockFloor, LogFloor}
+
 // States capture the state of the DB, giving guarantees about which blocks and
 // logs are indexed. The DB rows are the single source of truth: there is no
 // in-memory cache, so writes are safe inside transactions and out-of-band
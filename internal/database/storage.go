@@ -0,0 +1,404 @@
+package database
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// NullTopic is the Log.Topic value of an absent topic, stored as is in hex
+// storage and as SQL NULL in binary storage.
+const NullTopic = "NULL"
+
+// binaryStorage selects the column encoding of the hexbytes and hextopic
+// serializers. It is set from db.storage_format when connecting, before any
+// rows are read or written.
+var binaryStorage atomic.Bool
+
+func init() {
+	schema.RegisterSerializer("hexbytes", hexSerializer{})
+	schema.RegisterSerializer("hextopic", hexSerializer{nullValue: NullTopic})
+}
+
+// hexSerializer keeps entity fields as lowercase hex strings regardless of
+// the storage format: in hex storage the string is stored as is, in binary
+// storage it is decoded to raw bytes on write and encoded back on read. In
+// binary storage nullValue, or the empty string when it is unset, is stored
+// as SQL NULL: a fixed-width binary(20) column would pad an empty value,
+// e.g. the to address of a contract creation, to 20 zero bytes.
+type hexSerializer struct {
+	nullValue string
+}
+
+func (s hexSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+		value = s.nullValue
+	case []byte:
+		if binaryStorage.Load() {
+			value = hex.EncodeToString(v)
+		} else {
+			value = string(v)
+		}
+	case string:
+		value = v
+	default:
+		return errors.Errorf("unsupported value %T for hex field %s", dbValue, field.Name)
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+func (s hexSerializer) Value(_ context.Context, field *schema.Field, _ reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, errors.Errorf("unsupported value %T for hex field %s", fieldValue, field.Name)
+	}
+	if !binaryStorage.Load() {
+		return value, nil
+	}
+	if value == s.nullValue {
+		return nil, nil
+	}
+
+	decoded, err := HexParam(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "hex field %s", field.Name)
+	}
+	return decoded, nil
+}
+
+// HexParam converts a hex string (with or without 0x prefix) to a query
+// parameter for a hash, address, topic, input or data column in the
+// configured storage format, e.g.
+//
+//	hash, err := database.HexParam(txHash)
+//	db.Where("hash = ?", hash).First(&tx)
+//
+// Struct conditions such as db.Where(&Transaction{Hash: h}) bypass the
+// serializers and only work in hex storage.
+func HexParam(value string) (interface{}, error) {
+	value = strings.ToLower(strings.TrimPrefix(value, "0x"))
+	if !binaryStorage.Load() {
+		return value, nil
+	}
+	decoded, err := hex.DecodeString(value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "decode hex %q", value)
+	}
+	return decoded, nil
+}
+
+// HexColumn returns a SQL expression selecting column as a lowercase hex
+// string in the configured storage format, for raw queries.
+func HexColumn(column string) string {
+	if !binaryStorage.Load() {
+		return column
+	}
+	return fmt.Sprintf("LOWER(HEX(%s))", column)
+}
+
+// storageColumn is a column whose SQL type depends on the storage format.
+// Conversions pass through an intermediate VARBINARY/BLOB type that holds
+// both encodings so the data can be rewritten in place.
+type storageColumn struct {
+	name         string
+	hexType      string
+	binaryType   string
+	intermediate string
+	// topic columns store NullTopic as SQL NULL in binary storage.
+	topic bool
+}
+
+func hashColumn(name string) storageColumn {
+	return storageColumn{name: name, hexType: "varchar(64)", binaryType: "binary(32)", intermediate: "varbinary(64)"}
+}
+
+func addressColumn(name string) storageColumn {
+	return storageColumn{name: name, hexType: "varchar(40)", binaryType: "binary(20)", intermediate: "varbinary(40)"}
+}
+
+func payloadColumn(name string) storageColumn {
+	return storageColumn{name: name, hexType: "longtext", binaryType: "longblob", intermediate: "longblob"}
+}
+
+func topicColumn(name string) storageColumn {
+	c := hashColumn(name)
+	c.topic = true
+	return c
+}
+
+// storageTable lists the format-dependent columns of a table. probe is a
+// hash column set on every row; its type tells the table's format and its
+// length whether an interrupted conversion has already rewritten the data.
+type storageTable struct {
+	name    string
+	probe   string
+	columns []storageColumn
+}
+
+var storageTables = []storageTable{
+	{
+		name:  "blocks",
+		probe: "hash",
+		columns: []storageColumn{
+			hashColumn("hash"),
+		},
+	},
+	{
+		name:  "transactions",
+		probe: "hash",
+		columns: []storageColumn{
+			hashColumn("hash"),
+			payloadColumn("input"),
+			hashColumn("block_hash"),
+			addressColumn("from_address"),
+			addressColumn("to_address"),
+		},
+	},
+	{
+		name:  "logs",
+		probe: "transaction_hash",
+		columns: []storageColumn{
+			addressColumn("address"),
+			payloadColumn("data"),
+			topicColumn("topic0"),
+			topicColumn("topic1"),
+			topicColumn("topic2"),
+			topicColumn("topic3"),
+			hashColumn("transaction_hash"),
+		},
+	},
+	{
+		name:  "internal_calls",
+		probe: "transaction_hash",
+		columns: []storageColumn{
+			hashColumn("transaction_hash"),
+			addressColumn("from_address"),
+			addressColumn("to_address"),
+			payloadColumn("input"),
+			payloadColumn("output"),
+		},
+	},
+	{
+		name:  "submission_payloads",
+		probe: "transaction_hash",
+		columns: []storageColumn{
+			hashColumn("transaction_hash"),
+			addressColumn("from_address"),
+			payloadColumn("payload"),
+		},
+	},
+}
+
+// prefixedStorageTables is storageTables with db.table_prefix applied.
+func prefixedStorageTables(db *gorm.DB) []storageTable {
+	tables := make([]storageTable, len(storageTables))
+	for i, table := range storageTables {
+		table.name = prefixedTable(db, table.name)
+		tables[i] = table
+	}
+	return tables
+}
+
+// storageConverting is the format of a table whose conversion was
+// interrupted between its schema changes.
+const storageConverting = "converting"
+
+// TableStorageFormats reports the storage format of each table:
+// config.StorageFormatHex, config.StorageFormatBinary or "converting".
+func TableStorageFormats(db *gorm.DB) (map[string]string, error) {
+	formats := make(map[string]string, len(storageTables))
+	for _, table := range prefixedStorageTables(db) {
+		format, err := tableStorageFormat(db, table)
+		if err != nil {
+			return nil, err
+		}
+		formats[table.name] = format
+	}
+	return formats, nil
+}
+
+func tableStorageFormat(db *gorm.DB, table storageTable) (string, error) {
+	var columnType string
+	err := db.Raw(
+		`SELECT COLUMN_TYPE FROM information_schema.COLUMNS
+		 WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?`,
+		table.name, table.probe,
+	).Scan(&columnType).Error
+	if err != nil {
+		return "", errors.Wrapf(err, "read column type of %s.%s", table.name, table.probe)
+	}
+
+	switch strings.ToLower(columnType) {
+	case "varchar(64)":
+		return config.StorageFormatHex, nil
+	case "binary(32)":
+		return config.StorageFormatBinary, nil
+	case "varbinary(64)":
+		return storageConverting, nil
+	default:
+		return "", errors.Errorf("unexpected type %q of %s.%s", columnType, table.name, table.probe)
+	}
+}
+
+// EnsureStorageFormat checks that the tables use cfg.StorageFormat. Empty
+// tables are converted; tables holding data in the other format make startup
+// fail, since converting them is a long operation left to the
+// convert-storage command.
+func EnsureStorageFormat(ctx context.Context, db *gorm.DB, cfg *config.DBConfig) error {
+	db = db.WithContext(ctx)
+
+	formats, err := TableStorageFormats(db)
+	if err != nil {
+		return err
+	}
+
+	for _, table := range prefixedStorageTables(db) {
+		if formats[table.name] == cfg.StorageFormat {
+			continue
+		}
+
+		var row struct{ ID uint64 }
+		err := db.Table(table.name).Select("id").Limit(1).Scan(&row).Error
+		if err != nil {
+			return errors.Wrapf(err, "check whether %s is empty", table.name)
+		}
+		if row.ID != 0 {
+			return errors.Errorf(
+				"table %s is in %s storage but db.storage_format is %q; run the convert-storage command to convert the data",
+				table.name, formats[table.name], cfg.StorageFormat,
+			)
+		}
+	}
+
+	return ConvertStorageFormat(ctx, db, cfg.StorageFormat)
+}
+
+// ConvertStorageFormat rewrites blocks, transactions, logs and internal
+// calls in place to the target storage format. Each table is converted by one ALTER to an
+// intermediate type, one UPDATE rewriting every row, and one ALTER to the
+// target type. The UPDATE is a single transaction, so a conversion
+// interrupted at any point is completed by running it again. Tables already
+// in the target format are skipped.
+func ConvertStorageFormat(ctx context.Context, db *gorm.DB, target string) error {
+	if target != config.StorageFormatHex && target != config.StorageFormatBinary {
+		return errors.Errorf("unknown storage format %q", target)
+	}
+	db = db.WithContext(ctx)
+
+	for _, table := range prefixedStorageTables(db) {
+		format, err := tableStorageFormat(db, table)
+		if err != nil {
+			return err
+		}
+		if format == target {
+			continue
+		}
+
+		start := time.Now()
+		if err := convertTable(db, table, format, target); err != nil {
+			return errors.Wrapf(err, "convert %s to %s storage", table.name, target)
+		}
+		logging.From(ctx, logging.Database).Infow(
+			"Converted table storage",
+			"table", table.name,
+			"format", target,
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+
+	return nil
+}
+
+func convertTable(db *gorm.DB, table storageTable, format, target string) error {
+	if format != storageConverting {
+		if err := alterColumns(db, table, func(c storageColumn) string { return c.intermediate }); err != nil {
+			return err
+		}
+	}
+
+	rewritten, err := isRewritten(db, table, target)
+	if err != nil {
+		return err
+	}
+	if !rewritten {
+		if err := db.Exec(rewriteStatement(table, target)).Error; err != nil {
+			return errors.Wrap(err, "rewrite rows")
+		}
+	}
+
+	return alterColumns(db, table, func(c storageColumn) string {
+		if target == config.StorageFormatBinary {
+			return c.binaryType
+		}
+		return c.hexType
+	})
+}
+
+func alterColumns(db *gorm.DB, table storageTable, columnType func(storageColumn) string) error {
+	modify := make([]string, len(table.columns))
+	for i, c := range table.columns {
+		modify[i] = fmt.Sprintf("MODIFY `%s` %s NULL", c.name, columnType(c))
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE `%s` %s", table.name, strings.Join(modify, ", "))
+	if err := db.Exec(stmt).Error; err != nil {
+		return errors.Wrapf(err, "exec %q", stmt)
+	}
+	return nil
+}
+
+// isRewritten tells from the probe length of any row whether the rows are
+// already in the target encoding: 32 bytes in binary, 64 characters in hex.
+// An empty table needs no rewrite.
+func isRewritten(db *gorm.DB, table storageTable, target string) (bool, error) {
+	var length *int
+	err := db.Raw(fmt.Sprintf("SELECT LENGTH(`%s`) FROM `%s` LIMIT 1", table.probe, table.name)).Scan(&length).Error
+	if err != nil {
+		return false, errors.Wrapf(err, "read %s.%s", table.name, table.probe)
+	}
+	if length == nil {
+		return true, nil
+	}
+	if target == config.StorageFormatBinary {
+		return *length == 32, nil
+	}
+	return *length == 64, nil
+}
+
+func rewriteStatement(table storageTable, target string) string {
+	set := make([]string, len(table.columns))
+	for i, c := range table.columns {
+		switch {
+		case target == config.StorageFormatBinary && c.topic:
+			set[i] = fmt.Sprintf("`%[1]s` = IF(`%[1]s` = '%[2]s', NULL, UNHEX(`%[1]s`))", c.name, NullTopic)
+		case target == config.StorageFormatBinary:
+			set[i] = fmt.Sprintf("`%[1]s` = UNHEX(NULLIF(`%[1]s`, ''))", c.name)
+		case c.topic:
+			set[i] = fmt.Sprintf("`%[1]s` = COALESCE(LOWER(HEX(`%[1]s`)), '%[2]s')", c.name, NullTopic)
+		default:
+			set[i] = fmt.Sprintf("`%[1]s` = COALESCE(LOWER(HEX(`%[1]s`)), '')", c.name)
+		}
+	}
+	return fmt.Sprintf("UPDATE `%s` SET %s", table.name, strings.Join(set, ", "))
+}
+
+// setStorageFormat selects the encoding used by the serializers for this
+// process.
+func setStorageFormat(format string) {
+	binaryStorage.Store(format == config.StorageFormatBinary)
+}
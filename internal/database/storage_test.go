@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func useStorageFormat(t *testing.T, format string) {
+	setStorageFormat(format)
+	t.Cleanup(func() { setStorageFormat(config.StorageFormatHex) })
+}
+
+func TestHexSerializer(t *testing.T) {
+	logSchema, err := schema.Parse(&Log{}, &sync.Map{}, schema.NamingStrategy{})
+	require.NoError(t, err)
+	topic := logSchema.LookUpField("Topic1")
+	require.NotNil(t, topic)
+
+	hash := strings.Repeat("ab", 32)
+	ctx := context.Background()
+
+	t.Run("hex storage passes strings through", func(t *testing.T) {
+		useStorageFormat(t, config.StorageFormatHex)
+
+		var log Log
+		dst := reflect.ValueOf(&log).Elem()
+		value, err := topic.Serializer.Value(ctx, topic, dst, NullTopic)
+		require.NoError(t, err)
+		require.Equal(t, NullTopic, value)
+
+		require.NoError(t, topic.Serializer.Scan(ctx, topic, dst, []byte(hash)))
+		require.Equal(t, hash, log.Topic1)
+	})
+
+	t.Run("binary storage decodes and encodes", func(t *testing.T) {
+		useStorageFormat(t, config.StorageFormatBinary)
+
+		var log Log
+		dst := reflect.ValueOf(&log).Elem()
+		value, err := topic.Serializer.Value(ctx, topic, dst, hash)
+		require.NoError(t, err)
+		require.Len(t, value, 32)
+
+		require.NoError(t, topic.Serializer.Scan(ctx, topic, dst, value))
+		require.Equal(t, hash, log.Topic1)
+
+		value, err = topic.Serializer.Value(ctx, topic, dst, NullTopic)
+		require.NoError(t, err)
+		require.Nil(t, value)
+
+		require.NoError(t, topic.Serializer.Scan(ctx, topic, dst, nil))
+		require.Equal(t, NullTopic, log.Topic1)
+
+		_, err = topic.Serializer.Value(ctx, topic, dst, "not hex")
+		require.Error(t, err)
+	})
+
+	t.Run("binary storage keeps empty values empty", func(t *testing.T) {
+		useStorageFormat(t, config.StorageFormatBinary)
+		txSchema, err := schema.Parse(&Transaction{}, &sync.Map{}, schema.NamingStrategy{})
+		require.NoError(t, err)
+		to := txSchema.LookUpField("ToAddress")
+		require.NotNil(t, to)
+
+		// A contract creation has no to address.
+		tx := Transaction{ToAddress: ""}
+		dst := reflect.ValueOf(&tx).Elem()
+		value, err := to.Serializer.Value(ctx, to, dst, tx.ToAddress)
+		require.NoError(t, err)
+		require.Nil(t, value, "stored as NULL, not padded to 20 zero bytes")
+
+		tx.ToAddress = strings.Repeat("04", 20)
+		require.NoError(t, to.Serializer.Scan(ctx, to, dst, value))
+		require.Empty(t, tx.ToAddress)
+	})
+}
+
+func TestHexParam(t *testing.T) {
+	useStorageFormat(t, config.StorageFormatHex)
+	value, err := HexParam("0xABCD")
+	require.NoError(t, err)
+	require.Equal(t, "abcd", value)
+	require.Equal(t, "hash", HexColumn("hash"))
+
+	useStorageFormat(t, config.StorageFormatBinary)
+	value, err = HexParam("0xABCD")
+	require.NoError(t, err)
+	require.Equal(t, []byte{0xab, 0xcd}, value)
+	require.Equal(t, "LOWER(HEX(hash))", HexColumn("hash"))
+}
+
+func TestConvertStorageFormat(t *testing.T) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDSNEnv)
+	}
+
+	db := setupScratchDB(t, dsn)
+	useStorageFormat(t, config.StorageFormatHex)
+
+	tx := Transaction{
+		BaseEntity:  BaseEntity{ID: 1},
+		Hash:        strings.Repeat("01", 32),
+		Input:       "6c532fae00",
+		BlockHash:   strings.Repeat("02", 32),
+		FromAddress: strings.Repeat("03", 20),
+		ToAddress:   strings.Repeat("04", 20),
+	}
+	log := Log{
+		TransactionID:   1,
+		Address:         strings.Repeat("05", 20),
+		Data:            "",
+		Topic0:          strings.Repeat("06", 32),
+		Topic1:          NullTopic,
+		Topic2:          NullTopic,
+		Topic3:          NullTopic,
+		TransactionHash: tx.Hash,
+	}
+	creation := Transaction{
+		BaseEntity:  BaseEntity{ID: 2},
+		Hash:        strings.Repeat("08", 32),
+		BlockHash:   strings.Repeat("02", 32),
+		FromAddress: strings.Repeat("03", 20),
+	}
+	require.NoError(t, db.Create(&Block{Hash: strings.Repeat("07", 32), Number: 1}).Error)
+	require.NoError(t, db.Create(&tx).Error)
+	require.NoError(t, db.Create(&creation).Error)
+	require.NoError(t, db.Create(&log).Error)
+
+	for _, format := range []string{config.StorageFormatBinary, config.StorageFormatHex} {
+		require.NoError(t, ConvertStorageFormat(context.Background(), db, format))
+		// Converting again is a no-op.
+		require.NoError(t, ConvertStorageFormat(context.Background(), db, format))
+
+		formats, err := TableStorageFormats(db)
+		require.NoError(t, err)
+		for table, f := range formats {
+			require.Equal(t, format, f, table)
+		}
+
+		setStorageFormat(format)
+		var gotTx Transaction
+		require.NoError(t, db.First(&gotTx, 1).Error)
+		require.Equal(t, tx, gotTx)
+		var gotCreation Transaction
+		require.NoError(t, db.First(&gotCreation, 2).Error)
+		require.Equal(t, creation, gotCreation, "empty to address and input survive the conversion")
+
+		// Rows written in the current format read back the same way.
+		written := creation
+		written.ID, written.Hash = 0, strings.Repeat("09", 32)
+		require.NoError(t, db.Create(&written).Error)
+		var gotWritten Transaction
+		require.NoError(t, db.First(&gotWritten, written.ID).Error)
+		require.Equal(t, written, gotWritten)
+		require.NoError(t, db.Delete(&written).Error)
+
+		var gotLog Log
+		require.NoError(t, db.First(&gotLog).Error)
+		log.ID = gotLog.ID
+		require.Equal(t, log, gotLog)
+
+		hash, err := HexParam(tx.Hash)
+		require.NoError(t, err)
+		var count int64
+		require.NoError(t, db.Model(&Log{}).Where("transaction_hash = ?", hash).Count(&count).Error)
+		require.EqualValues(t, 1, count)
+	}
+}
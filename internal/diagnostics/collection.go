@@ -5,9 +5,8 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
-
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
 )
 
 const undefined = "undefined"
@@ -15,41 +14,57 @@ const undefined = "undefined"
 // LogIndexerPolicy prints what the indexer will collect, one entry per line,
 // with hex-encoded function selectors and topic hashes.
 func LogIndexerPolicy(cfg config.IndexerConfig) {
-	logger.Infof(
-		"Indexer collection policy: %d transaction filters, %d log filters",
+	logging.For(logging.Engine).Infof(
+		"Indexer collection policy: %d transaction filters, %d transfer filters, %d log filters",
 		len(cfg.CollectTransactions),
+		len(cfg.CollectTransfers),
 		len(cfg.CollectLogs),
 	)
 	for i := range cfg.CollectTransactions {
 		tx := &cfg.CollectTransactions[i]
-		logger.Infof(
-			"  tx_filter: contract=%s, func_sig=%s, status=%t, collect_events=%t",
-			contractRef(tx.ContractName, tx.ContractAddress),
-			formatHexOrAny(tx.FuncSig),
-			tx.Status,
-			tx.CollectEvents,
+		logging.For(logging.Engine).Infow(
+			"  tx_filter",
+			"contract", contractRef(tx.ContractName, tx.ContractAddress),
+			"func_sig", formatHexOrAny(tx.FuncSig),
+			"from", addressesOrAny(tx.Senders()),
+			"status", tx.Status,
+			"collect_events", tx.CollectEvents,
+		)
+	}
+	for i := range cfg.CollectTransfers {
+		transfer := &cfg.CollectTransfers[i]
+		logging.For(logging.Engine).Infow(
+			"  transfer_filter",
+			"to", addressesOrAny(transfer.Recipients()),
+			"from", addressesOrAny(transfer.Senders()),
+			"min_value", transfer.MinValue,
+			"status", transfer.Status,
+			"collect_events", transfer.CollectEvents,
 		)
 	}
 	for i := range cfg.CollectLogs {
 		lg := &cfg.CollectLogs[i]
-		logger.Infof(
-			"  log_filter: contract=%s, topic=%s",
-			contractRef(lg.ContractName, lg.ContractAddress),
-			formatHexOrAny(lg.Topic),
+		logging.For(logging.Engine).Infow(
+			"  log_filter",
+			"contract", contractRef(lg.ContractName, lg.ContractAddress),
+			"topic", formatHexOrAny(lg.Topic),
 		)
 	}
+	if cfg.TraceCalls {
+		logging.For(logging.Engine).Info("  internal calls: traced with debug_traceBlockByNumber and matched against tx_filter")
+	}
 }
 
 // LogFspEventFilter prints the contract+topic pairs used for FSP event-range
 // backfilling.
 func LogFspEventFilter(logs []config.LogInfo) {
-	logger.Infof("FSP event range filter: %d entries", len(logs))
+	logging.For(logging.Engine).Infof("FSP event range filter: %d entries", len(logs))
 	for i := range logs {
 		lg := &logs[i]
-		logger.Infof(
-			"  fsp_event_filter: contract=%s, topic=%s",
-			contractRef(lg.ContractName, lg.ContractAddress),
-			formatHexOrAny(lg.Topic),
+		logging.For(logging.Engine).Infow(
+			"  fsp_event_filter",
+			"contract", contractRef(lg.ContractName, lg.ContractAddress),
+			"topic", formatHexOrAny(lg.Topic),
 		)
 	}
 }
@@ -69,6 +84,13 @@ func contractRef(name, address string) string {
 	return "<any>"
 }
 
+func addressesOrAny(addresses []string) string {
+	if len(addresses) == 0 {
+		return "<any>"
+	}
+	return strings.Join(addresses, ",")
+}
+
 func formatHexOrAny(value string) string {
 	v := strings.ToLower(strings.TrimSpace(value))
 	if v == "" || v == undefined {
@@ -0,0 +1,302 @@
+// Package export writes indexed blocks, transactions and logs to Parquet or
+// CSV files for analytics, partitioned by day or block range, with a
+// manifest describing what was exported.
+package export
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	FormatParquet = "parquet"
+	FormatCSV     = "csv"
+
+	PartitionDay    = "day"
+	PartitionBlocks = "blocks"
+
+	// chunkBlocks is the block range read from the database per query.
+	chunkBlocks = 1000
+)
+
+type Options struct {
+	Format      string
+	OutDir      string
+	PartitionBy string
+	// PartitionBlocks is the width of a block-range partition, and the
+	// number of blocks per rolled file in continuous mode.
+	PartitionBlocks uint64
+}
+
+func (o *Options) validate() error {
+	if o.Format != FormatParquet && o.Format != FormatCSV {
+		return errors.Errorf("invalid format %q: must be %q or %q", o.Format, FormatParquet, FormatCSV)
+	}
+	if o.PartitionBy != PartitionDay && o.PartitionBy != PartitionBlocks {
+		return errors.Errorf("invalid partitioning %q: must be %q or %q", o.PartitionBy, PartitionDay, PartitionBlocks)
+	}
+	if o.PartitionBlocks == 0 {
+		return errors.New("partition size in blocks must be positive")
+	}
+	if o.OutDir == "" {
+		return errors.New("output directory is required")
+	}
+	return nil
+}
+
+// Exporter writes block ranges into an export directory and keeps its
+// manifest up to date after every range.
+type Exporter struct {
+	db       *gorm.DB
+	opts     Options
+	manifest *Manifest
+}
+
+// NewExporter prepares outDir. With resume set, an existing manifest written
+// with the same options is continued (see NextBlock); otherwise outDir must
+// not contain an export yet.
+func NewExporter(db *gorm.DB, opts Options, resume bool) (*Exporter, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "create %s", opts.OutDir)
+	}
+
+	manifest, err := ReadManifest(opts.OutDir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest != nil && !resume {
+		return nil, errors.Errorf("%s already contains an export; choose an empty directory", opts.OutDir)
+	}
+	if manifest != nil && (manifest.Format != opts.Format || manifest.PartitionBy != opts.PartitionBy ||
+		manifest.PartitionBlocks != partitionBlocks(opts)) {
+		return nil, errors.Errorf(
+			"%s contains a %s export partitioned by %s; resume it with the same options",
+			opts.OutDir, manifest.Format, manifest.PartitionBy,
+		)
+	}
+
+	return &Exporter{db: db, opts: opts, manifest: manifest}, nil
+}
+
+func partitionBlocks(opts Options) uint64 {
+	if opts.PartitionBy == PartitionBlocks {
+		return opts.PartitionBlocks
+	}
+	return 0
+}
+
+// NextBlock is the block after the last exported one, or from when nothing
+// has been exported yet.
+func (e *Exporter) NextBlock(from uint64) uint64 {
+	if e.manifest == nil || e.manifest.ToBlock+1 < from {
+		return from
+	}
+	return e.manifest.ToBlock + 1
+}
+
+// Export writes blocks, transactions and logs of [from, to] and updates the
+// manifest. The range must already be indexed: to may not exceed the
+// last_database_block state.
+func (e *Exporter) Export(ctx context.Context, from, to uint64) error {
+	if from > to {
+		return errors.Errorf("empty range: from %d > to %d", from, to)
+	}
+	db := e.db.WithContext(ctx)
+
+	states, err := database.GetStates(db, database.AllStateNames...)
+	if err != nil {
+		return errors.Wrap(err, "read coverage states")
+	}
+	lastIndexed := states[database.LastIndexed]
+	if !database.IsSet(lastIndexed) || to > lastIndexed.Index {
+		return errors.Errorf("block %d is not indexed yet (last_database_block=%d)", to, lastIndexed.Index)
+	}
+
+	if e.manifest == nil {
+		e.manifest = &Manifest{
+			Format:          e.opts.Format,
+			PartitionBy:     e.opts.PartitionBy,
+			PartitionBlocks: partitionBlocks(e.opts),
+			FromBlock:       from,
+		}
+	}
+
+	start := time.Now()
+	blockFloor := coveredFrom(from, states[database.BlockFloor])
+	logFloor := blockFloor
+	if database.IsSet(states[database.LogFloor]) {
+		logFloor = coveredFrom(from, states[database.LogFloor])
+	}
+
+	err = exportTable(db, e, "blocks", "number", "number ASC", blockHeader, blockFloor, from, to, newBlockRow)
+	if err != nil {
+		return err
+	}
+	err = exportTable(db, e, "transactions", "block_number", "block_number ASC, transaction_index ASC",
+		transactionHeader, blockFloor, from, to, newTransactionRow)
+	if err != nil {
+		return err
+	}
+	err = exportTable(db, e, "logs", "block_number", "block_number ASC, log_index ASC", logHeader, logFloor, from, to, newLogRow)
+	if err != nil {
+		return err
+	}
+
+	e.manifest.ToBlock = to
+	e.manifest.ExportedAt = time.Now().UTC()
+	e.manifest.States = status.StateEntries(states)
+	if err := e.manifest.write(e.opts.OutDir); err != nil {
+		return err
+	}
+
+	logging.From(ctx, logging.Main).Infow(
+		"Exported range",
+		"from", from,
+		"to", to,
+		"format", e.opts.Format,
+		"out", e.opts.OutDir,
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return nil
+}
+
+func coveredFrom(from uint64, floor database.State) uint64 {
+	if database.IsSet(floor) && floor.Index > from {
+		return floor.Index
+	}
+	return from
+}
+
+// exportTable streams the rows of one table in [from, to] into partition
+// files, reading chunkBlocks blocks per query. Rows arrive in block order, so
+// each partition is written by a single file that is closed when the first
+// row of the next partition arrives.
+func exportTable[E any, R row](
+	db *gorm.DB, e *Exporter, table, blockColumn, order string, header []string,
+	covered, from, to uint64, convert func(E) R,
+) error {
+	tm := e.manifest.table(table)
+	if len(tm.Files) == 0 && tm.Rows == 0 {
+		tm.CoveredFromBlock = covered
+	}
+
+	var (
+		file  *partitionFile[R]
+		batch []R
+	)
+	flush := func() error {
+		if file == nil || len(batch) == 0 {
+			return nil
+		}
+		err := file.write(batch)
+		batch = batch[:0]
+		if err != nil {
+			file.abort()
+			file = nil
+		}
+		return err
+	}
+	closeFile := func() error {
+		if err := flush(); err != nil || file == nil {
+			return err
+		}
+		entry, err := file.close(e.opts.OutDir)
+		file = nil
+		if err != nil {
+			return err
+		}
+		tm.Files = append(tm.Files, entry)
+		tm.Rows += entry.Rows
+		return nil
+	}
+
+	for lo := from; ; lo += chunkBlocks {
+		hi := min(lo+chunkBlocks-1, to)
+
+		var entities []E
+		err := db.Where(blockColumn+" BETWEEN ? AND ?", lo, hi).Order(order).Find(&entities).Error
+		if err != nil {
+			if file != nil {
+				file.abort()
+			}
+			return errors.Wrapf(err, "read %s %d-%d", table, lo, hi)
+		}
+
+		for _, entity := range entities {
+			r := convert(entity)
+			partition := partitionName(e.opts.PartitionBy, e.opts.PartitionBlocks, r)
+			if file == nil || file.partition != partition {
+				if err := closeFile(); err != nil {
+					return err
+				}
+				file, err = openPartitionFile[R](e.opts.OutDir, table, partition, e.opts.Format, header)
+				if err != nil {
+					return err
+				}
+			}
+			batch = append(batch, r)
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+
+		if hi >= to {
+			break
+		}
+	}
+
+	return closeFile()
+}
+
+// Follow exports every completed block-range partition from next on as
+// continuous indexing commits it: whenever last_database_block reaches the
+// end of a partition, the partitions up to it are exported, one file per
+// table and partition. The first iteration catches up on all partitions
+// already indexed. It returns when ctx is cancelled.
+func (e *Exporter) Follow(ctx context.Context, next uint64, pollInterval time.Duration) error {
+	if e.opts.PartitionBy != PartitionBlocks {
+		return errors.New("continuous export requires block-range partitioning")
+	}
+
+	for {
+		lastIndexed, err := database.GetState(e.db.WithContext(ctx), database.LastIndexed)
+		if err != nil {
+			return errors.Wrap(err, "read last_database_block")
+		}
+
+		if end, ok := completedEnd(next, lastIndexed.Index, e.opts.PartitionBlocks); ok && database.IsSet(lastIndexed) {
+			if err := e.Export(ctx, next, end); err != nil {
+				return err
+			}
+			next = end + 1
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// completedEnd is the last block of the highest partition that ends at or
+// below lastIndexed and at or above next.
+func completedEnd(next, lastIndexed, partitionBlocks uint64) (uint64, bool) {
+	completed := (lastIndexed + 1) / partitionBlocks * partitionBlocks
+	if completed == 0 || completed-1 < next {
+		return 0, false
+	}
+	return completed - 1, true
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPartitionName(t *testing.T) {
+	r := BlockRow{Number: 12_345, Timestamp: 1_700_000_000} // 2023-11-14 22:13:20 UTC
+	require.Equal(t, "date=2023-11-14", partitionName(PartitionDay, 1000, r))
+	require.Equal(t, "blocks=12000-12999", partitionName(PartitionBlocks, 1000, r))
+}
+
+func TestCompletedEnd(t *testing.T) {
+	tests := []struct {
+		name                               string
+		next, lastIndexed, partitionBlocks uint64
+		want                               uint64
+		ok                                 bool
+	}{
+		{name: "first partition incomplete", next: 0, lastIndexed: 998, partitionBlocks: 1000},
+		{name: "first partition complete", next: 0, lastIndexed: 999, partitionBlocks: 1000, want: 999, ok: true},
+		{name: "catch up several partitions", next: 500, lastIndexed: 3500, partitionBlocks: 1000, want: 2999, ok: true},
+		{name: "already exported", next: 3000, lastIndexed: 3500, partitionBlocks: 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := completedEnd(tt.next, tt.lastIndexed, tt.partitionBlocks)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPartitionFile(t *testing.T) {
+	logs := []LogRow{
+		newLogRow(database.Log{BlockNumber: 10, LogIndex: 0, Topic0: "aa", Topic1: database.NullTopic}),
+		newLogRow(database.Log{BlockNumber: 12, LogIndex: 3, Topic0: "bb", Topic1: "cc"}),
+	}
+
+	for _, format := range []string{FormatParquet, FormatCSV} {
+		t.Run(format, func(t *testing.T) {
+			out := t.TempDir()
+			file, err := openPartitionFile[LogRow](out, "logs", "blocks=0-99", format, logHeader)
+			require.NoError(t, err)
+			require.NoError(t, file.write(logs))
+
+			entry, err := file.close(out)
+			require.NoError(t, err)
+			require.Equal(t, FileEntry{
+				Path: "logs/blocks=0-99/10-12." + format, Partition: "blocks=0-99", Rows: 2, FirstBlock: 10, LastBlock: 12,
+			}, entry)
+
+			path := filepath.Join(out, entry.Path)
+			if format == FormatParquet {
+				rows, err := parquet.ReadFile[LogRow](path)
+				require.NoError(t, err)
+				require.Equal(t, logs, rows)
+				return
+			}
+
+			f, err := os.Open(path)
+			require.NoError(t, err)
+			defer f.Close()
+			records, err := csv.NewReader(f).ReadAll()
+			require.NoError(t, err)
+			require.Equal(t, [][]string{logHeader, logs[0].csvRecord(), logs[1].csvRecord()}, records)
+			require.Equal(t, "", records[1][5], "absent topic is exported empty")
+		})
+	}
+}
+
+func TestManifest(t *testing.T) {
+	out := t.TempDir()
+
+	m, err := ReadManifest(out)
+	require.NoError(t, err)
+	require.Nil(t, m)
+
+	m = &Manifest{Format: FormatCSV, PartitionBy: PartitionBlocks, PartitionBlocks: 100, ToBlock: 199}
+	m.table("blocks").Rows = 200
+	require.NoError(t, m.write(out))
+
+	read, err := ReadManifest(out)
+	require.NoError(t, err)
+	require.Equal(t, m.Tables["blocks"], read.Tables["blocks"])
+
+	e := &Exporter{manifest: read}
+	require.Equal(t, uint64(200), e.NextBlock(0))
+	require.Equal(t, uint64(500), e.NextBlock(500))
+}
@@ -0,0 +1,90 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
+
+	"github.com/pkg/errors"
+)
+
+const manifestFile = "manifest.json"
+
+// Manifest describes an export directory: the exported block range, the
+// files of each table and the coverage states at the time of the last
+// export, which tell which part of the range the tables actually hold.
+type Manifest struct {
+	Format          string                    `json:"format"`
+	PartitionBy     string                    `json:"partition_by"`
+	PartitionBlocks uint64                    `json:"partition_blocks,omitempty"`
+	FromBlock       uint64                    `json:"from_block"`
+	ToBlock         uint64                    `json:"to_block"`
+	ExportedAt      time.Time                 `json:"exported_at"`
+	States          []status.StateEntry       `json:"states"`
+	Tables          map[string]*TableManifest `json:"tables"`
+}
+
+// TableManifest lists the files of one table. CoveredFromBlock is the first
+// block of the exported range the table holds complete data for (the block
+// floor, or the log floor for logs); blocks below it were never indexed or
+// were dropped by history drop.
+type TableManifest struct {
+	Rows             uint64      `json:"rows"`
+	CoveredFromBlock uint64      `json:"covered_from_block"`
+	Files            []FileEntry `json:"files"`
+}
+
+type FileEntry struct {
+	Path       string `json:"path"`
+	Partition  string `json:"partition"`
+	Rows       uint64 `json:"rows"`
+	FirstBlock uint64 `json:"first_block"`
+	LastBlock  uint64 `json:"last_block"`
+}
+
+// ReadManifest reads the manifest of an export directory; it returns nil
+// without error when the directory has none.
+func ReadManifest(outDir string) (*Manifest, error) {
+	content, err := os.ReadFile(filepath.Join(outDir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read manifest")
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, errors.Wrap(err, "parse manifest")
+	}
+	return &m, nil
+}
+
+// write replaces the manifest atomically.
+func (m *Manifest) write(outDir string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(outDir, manifestFile+".tmp")
+	if err := os.WriteFile(tmp, content, 0o644); err != nil {
+		return errors.Wrap(err, "write manifest")
+	}
+	return errors.Wrap(os.Rename(tmp, filepath.Join(outDir, manifestFile)), "write manifest")
+}
+
+func (m *Manifest) table(name string) *TableManifest {
+	if m.Tables == nil {
+		m.Tables = make(map[string]*TableManifest)
+	}
+	t, ok := m.Tables[name]
+	if !ok {
+		t = &TableManifest{}
+		m.Tables[name] = t
+	}
+	return t
+}
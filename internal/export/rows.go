@@ -0,0 +1,140 @@
+package export
+
+import (
+	"strconv"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+)
+
+// row is one exported record. Rows carry their block number and timestamp so
+// they can be assigned to a partition.
+type row interface {
+	block() uint64
+	time() uint64
+	csvRecord() []string
+}
+
+// BlockRow, TransactionRow and LogRow are the exported columns. Hashes,
+// addresses, topics, input and data are lowercase hex without 0x prefix in
+// both storage formats; absent log topics are empty.
+type BlockRow struct {
+	Number    uint64 `parquet:"number"`
+	Hash      string `parquet:"hash"`
+	Timestamp uint64 `parquet:"timestamp"`
+}
+
+var blockHeader = []string{"number", "hash", "timestamp"}
+
+func newBlockRow(b database.Block) BlockRow {
+	return BlockRow{Number: b.Number, Hash: b.Hash, Timestamp: b.Timestamp}
+}
+
+func (r BlockRow) block() uint64 { return r.Number }
+func (r BlockRow) time() uint64  { return r.Timestamp }
+
+func (r BlockRow) csvRecord() []string {
+	return []string{formatUint(r.Number), r.Hash, formatUint(r.Timestamp)}
+}
+
+type TransactionRow struct {
+	BlockNumber      uint64 `parquet:"block_number"`
+	BlockHash        string `parquet:"block_hash"`
+	TransactionIndex uint64 `parquet:"transaction_index"`
+	Hash             string `parquet:"hash"`
+	FunctionSig      string `parquet:"function_sig"`
+	Input            string `parquet:"input"`
+	FromAddress      string `parquet:"from_address"`
+	ToAddress        string `parquet:"to_address"`
+	Status           uint64 `parquet:"status"`
+	Value            string `parquet:"value"`
+	GasPrice         string `parquet:"gas_price"`
+	Gas              uint64 `parquet:"gas"`
+	Timestamp        uint64 `parquet:"timestamp"`
+}
+
+var transactionHeader = []string{
+	"block_number", "block_hash", "transaction_index", "hash", "function_sig", "input",
+	"from_address", "to_address", "status", "value", "gas_price", "gas", "timestamp",
+}
+
+func newTransactionRow(tx database.Transaction) TransactionRow {
+	return TransactionRow{
+		BlockNumber:      tx.BlockNumber,
+		BlockHash:        tx.BlockHash,
+		TransactionIndex: tx.TransactionIndex,
+		Hash:             tx.Hash,
+		FunctionSig:      tx.FunctionSig,
+		Input:            tx.Input,
+		FromAddress:      tx.FromAddress,
+		ToAddress:        tx.ToAddress,
+		Status:           tx.Status,
+		Value:            tx.Value,
+		GasPrice:         tx.GasPrice,
+		Gas:              tx.Gas,
+		Timestamp:        tx.Timestamp,
+	}
+}
+
+func (r TransactionRow) block() uint64 { return r.BlockNumber }
+func (r TransactionRow) time() uint64  { return r.Timestamp }
+
+func (r TransactionRow) csvRecord() []string {
+	return []string{
+		formatUint(r.BlockNumber), r.BlockHash, formatUint(r.TransactionIndex), r.Hash, r.FunctionSig, r.Input,
+		r.FromAddress, r.ToAddress, formatUint(r.Status), r.Value, r.GasPrice, formatUint(r.Gas), formatUint(r.Timestamp),
+	}
+}
+
+type LogRow struct {
+	BlockNumber     uint64 `parquet:"block_number"`
+	TransactionHash string `parquet:"transaction_hash"`
+	LogIndex        uint64 `parquet:"log_index"`
+	Address         string `parquet:"address"`
+	Topic0          string `parquet:"topic0"`
+	Topic1          string `parquet:"topic1"`
+	Topic2          string `parquet:"topic2"`
+	Topic3          string `parquet:"topic3"`
+	Data            string `parquet:"data"`
+	Timestamp       uint64 `parquet:"timestamp"`
+}
+
+var logHeader = []string{
+	"block_number", "transaction_hash", "log_index", "address",
+	"topic0", "topic1", "topic2", "topic3", "data", "timestamp",
+}
+
+func newLogRow(l database.Log) LogRow {
+	return LogRow{
+		BlockNumber:     l.BlockNumber,
+		TransactionHash: l.TransactionHash,
+		LogIndex:        l.LogIndex,
+		Address:         l.Address,
+		Topic0:          exportTopic(l.Topic0),
+		Topic1:          exportTopic(l.Topic1),
+		Topic2:          exportTopic(l.Topic2),
+		Topic3:          exportTopic(l.Topic3),
+		Data:            l.Data,
+		Timestamp:       l.Timestamp,
+	}
+}
+
+func (r LogRow) block() uint64 { return r.BlockNumber }
+func (r LogRow) time() uint64  { return r.Timestamp }
+
+func (r LogRow) csvRecord() []string {
+	return []string{
+		formatUint(r.BlockNumber), r.TransactionHash, formatUint(r.LogIndex), r.Address,
+		r.Topic0, r.Topic1, r.Topic2, r.Topic3, r.Data, formatUint(r.Timestamp),
+	}
+}
+
+func exportTopic(topic string) string {
+	if topic == database.NullTopic {
+		return ""
+	}
+	return topic
+}
+
+func formatUint(v uint64) string {
+	return strconv.FormatUint(v, 10)
+}
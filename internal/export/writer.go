@@ -0,0 +1,158 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/pkg/errors"
+)
+
+// rowWriter encodes rows of one file.
+type rowWriter[R row] interface {
+	write(rows []R) error
+	close() error
+}
+
+func newRowWriter[R row](format string, f *os.File, header []string) (rowWriter[R], error) {
+	switch format {
+	case FormatParquet:
+		return &parquetWriter[R]{w: parquet.NewGenericWriter[R](f, parquet.Compression(&parquet.Zstd))}, nil
+	case FormatCSV:
+		w := csv.NewWriter(f)
+		if err := w.Write(header); err != nil {
+			return nil, err
+		}
+		return &csvWriter[R]{w: w}, nil
+	default:
+		return nil, errors.Errorf("unknown export format %q", format)
+	}
+}
+
+type parquetWriter[R row] struct {
+	w *parquet.GenericWriter[R]
+}
+
+func (p *parquetWriter[R]) write(rows []R) error {
+	_, err := p.w.Write(rows)
+	return err
+}
+
+func (p *parquetWriter[R]) close() error {
+	return p.w.Close()
+}
+
+type csvWriter[R row] struct {
+	w *csv.Writer
+}
+
+func (c *csvWriter[R]) write(rows []R) error {
+	for _, r := range rows {
+		if err := c.w.Write(r.csvRecord()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *csvWriter[R]) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// partitionFile is the open file of one table partition. It is written to a
+// temporary file and renamed to <table>/<partition>/<first>-<last>.<ext> on
+// close, so a file under its final name is always complete.
+type partitionFile[R row] struct {
+	partition string
+	dir       string
+	format    string
+	tmp       *os.File
+	w         rowWriter[R]
+	rows      uint64
+	first     uint64
+	last      uint64
+}
+
+func openPartitionFile[R row](outDir, table, partition, format string, header []string) (*partitionFile[R], error) {
+	dir := filepath.Join(outDir, table, partition)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.Wrapf(err, "create %s", dir)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".export-*.tmp")
+	if err != nil {
+		return nil, errors.Wrapf(err, "create file in %s", dir)
+	}
+
+	w, err := newRowWriter[R](format, tmp, header)
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return nil, err
+	}
+
+	return &partitionFile[R]{partition: partition, dir: dir, format: format, tmp: tmp, w: w}, nil
+}
+
+func (p *partitionFile[R]) write(rows []R) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if p.rows == 0 {
+		p.first = rows[0].block()
+	}
+	p.last = rows[len(rows)-1].block()
+	p.rows += uint64(len(rows))
+
+	return errors.Wrapf(p.w.write(rows), "write %s", p.tmp.Name())
+}
+
+// close finishes the file and returns its manifest entry, with the path
+// relative to outDir.
+func (p *partitionFile[R]) close(outDir string) (FileEntry, error) {
+	if err := p.w.close(); err != nil {
+		p.abort()
+		return FileEntry{}, errors.Wrapf(err, "finish %s", p.tmp.Name())
+	}
+	if err := p.tmp.Close(); err != nil {
+		_ = os.Remove(p.tmp.Name())
+		return FileEntry{}, errors.Wrapf(err, "close %s", p.tmp.Name())
+	}
+
+	path := filepath.Join(p.dir, fmt.Sprintf("%d-%d.%s", p.first, p.last, p.format))
+	if err := os.Rename(p.tmp.Name(), path); err != nil {
+		_ = os.Remove(p.tmp.Name())
+		return FileEntry{}, errors.Wrapf(err, "rename %s", p.tmp.Name())
+	}
+
+	rel, err := filepath.Rel(outDir, path)
+	if err != nil {
+		return FileEntry{}, err
+	}
+	return FileEntry{
+		Path:       filepath.ToSlash(rel),
+		Partition:  p.partition,
+		Rows:       p.rows,
+		FirstBlock: p.first,
+		LastBlock:  p.last,
+	}, nil
+}
+
+func (p *partitionFile[R]) abort() {
+	_ = p.tmp.Close()
+	_ = os.Remove(p.tmp.Name())
+}
+
+// partitionName is the directory of a row: date=YYYY-MM-DD (UTC) by day, or
+// blocks=<start>-<end> for block ranges aligned to partitionBlocks.
+func partitionName(partitionBy string, partitionBlocks uint64, r row) string {
+	if partitionBy == PartitionDay {
+		return "date=" + time.Unix(int64(r.time()), 0).UTC().Format(time.DateOnly)
+	}
+	start := r.block() - r.block()%partitionBlocks
+	return fmt.Sprintf("blocks=%d-%d", start, start+partitionBlocks-1)
+}
@@ -5,15 +5,14 @@ import (
 	"math/big"
 	"time"
 
-	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/boff"
-	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
 
 	avxTypes "github.com/ava-labs/coreth/core/types"
 	"github.com/ava-labs/coreth/interfaces"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
@@ -32,9 +31,10 @@ func backfillFspEventLogs(
 
 	start := time.Now()
 	inserted := 0
-	logger.Infof("FSP event indexing started: from=%d, to=%d", fromBlock, toBlock)
+	logging.From(ctx, logging.Fsp).Infow("FSP event indexing started", "from", fromBlock, "to", toBlock)
 
 	for blockStart := fromBlock; blockStart <= toBlock; blockStart += chunkRange {
+		ready.Heartbeat(ctx)
 		blockEnd := min(blockStart+chunkRange-1, toBlock)
 		logs, err := fetchEventRangeLogsChunk(ctx, ci, blockStart, blockEnd, logAddresses, logTopics)
 		if err != nil {
@@ -53,9 +53,12 @@ func backfillFspEventLogs(
 		inserted += len(dbLogs)
 	}
 
-	logger.Infof(
-		"FSP event indexing completed: from=%d, to=%d, inserted=%d, duration_ms=%d",
-		fromBlock, toBlock, inserted, time.Since(start).Milliseconds(),
+	logging.From(ctx, logging.Fsp).Infow(
+		"FSP event indexing completed",
+		"from", fromBlock,
+		"to", toBlock,
+		"inserted", inserted,
+		"duration_ms", time.Since(start).Milliseconds(),
 	)
 	return nil
 }
@@ -81,16 +84,7 @@ func fetchEventRangeLogsChunk(
 		query.Topics = [][]common.Hash{logTopics}
 	}
 
-	logs, err := boff.RetryWithMaxElapsed(
-		ctx,
-		func() ([]avxTypes.Log, error) {
-			ctx, cancelFunc := context.WithTimeout(ctx, config.RPCTimeout)
-			defer cancelFunc()
-
-			return ci.Client().FilterLogs(ctx, query)
-		},
-		"fetchFspEventRangeLogsChunk",
-	)
+	logs, err := ci.FilterLogs(ctx, query, "fetchFspEventRangeLogsChunk")
 	if err != nil {
 		return nil, err
 	}
@@ -132,8 +126,12 @@ func saveLogs(ci *core.Engine, logs []*database.Log) error {
 	}
 
 	return ci.DB().Transaction(func(tx *gorm.DB) error {
-		return tx.Clauses(clause.Insert{Modifier: "IGNORE"}).
+		err := tx.Clauses(clause.Insert{Modifier: "IGNORE"}).
 			CreateInBatches(logs, database.DBTransactionBatchesSize).
 			Error
+		if err != nil {
+			return err
+		}
+		return ci.RunSaveHooks(tx, core.SavedRows{Logs: logs})
 	})
 }
@@ -0,0 +1,206 @@
+package fsp
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/calculator"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/registry"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/relay"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/system"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// completenessCheckInterval is how often the retained epochs are checked for
+// missing lifecycle events.
+const completenessCheckInterval = 30 * time.Minute
+
+// lifecycleEvent is an event every reward epoch's signing-policy protocol
+// emits between the epoch's random acquisition start and its start, under
+// any of topics, from an address the registry resolves for contractName.
+type lifecycleEvent struct {
+	name         string
+	contractName string
+	topics       []common.Hash
+}
+
+var epochLifecycleEvents = []lifecycleEvent{
+	{"RandomAcquisitionStarted", "FlareSystemsManager", []common.Hash{
+		eventID(system.FlareSystemsManagerMetaData, "RandomAcquisitionStarted"),
+	}},
+	{"VotePowerBlockSelected", "FlareSystemsManager", []common.Hash{
+		eventID(system.FlareSystemsManagerMetaData, "VotePowerBlockSelected"),
+	}},
+	{"VoterRegistered", "VoterRegistry", []common.Hash{
+		eventID(registry.RegistryMetaData, "VoterRegistered"),
+		common.HexToHash(config.VoterRegisteredNextTopic),
+	}},
+	{"VoterRegistrationInfo", "FlareSystemsCalculator", []common.Hash{
+		eventID(calculator.CalculatorMetaData, "VoterRegistrationInfo"),
+		common.HexToHash(config.VoterRegistrationInfoNextTopic),
+	}},
+	{"SigningPolicyInitialized", "Relay", []common.Hash{
+		eventID(relay.RelayMetaData, "SigningPolicyInitialized"),
+	}},
+	{"RewardEpochStarted", "FlareSystemsManager", []common.Hash{
+		eventID(system.FlareSystemsManagerMetaData, "RewardEpochStarted"),
+	}},
+}
+
+// runCompletenessCheck checks the retained reward epochs for missing
+// lifecycle events every completenessCheckInterval until ctx is done.
+func runCompletenessCheck(ctx context.Context, ci *core.Engine) {
+	for {
+		if err := checkCompleteness(ctx, ci); err != nil {
+			logging.From(ctx, logging.Fsp).Errorw("FSP completeness check error", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(completenessCheckInterval):
+		}
+	}
+}
+
+// checkCompleteness verifies that every reward epoch in reward_epochs whose
+// signing-policy protocol window lies within the log coverage has all of its
+// lifecycle events, and backfills the window of each epoch missing any. The
+// backfill only inserts what is absent, so re-running it over a window is
+// safe. Events still missing afterwards were never returned by the node and
+// are reported; the next check tries again.
+func checkCompleteness(ctx context.Context, ci *core.Engine) error {
+	db := ci.DB().WithContext(ctx)
+	states, err := database.GetStates(db, database.LogFloor, database.LastIndexed)
+	if err != nil {
+		return errors.Wrap(err, "database.GetStates")
+	}
+	floor, last := states[database.LogFloor], states[database.LastIndexed]
+	if !database.IsSet(floor) || !database.IsSet(last) {
+		return nil
+	}
+
+	var epochs []database.RewardEpoch
+	err = db.Where(
+		"random_acquisition_start_block > 0 AND random_acquisition_start_block >= ? AND start_block <= ?",
+		floor.Index, last.Index,
+	).Order("reward_epoch_id ASC").Find(&epochs).Error
+	if err != nil {
+		return errors.Wrap(err, "read retained reward epochs")
+	}
+
+	if len(epochs) == 0 {
+		return nil
+	}
+	addresses, err := lifecycleAddresses(ctx, ci.ContractResolver())
+	if err != nil {
+		return err
+	}
+
+	var (
+		logAddresses []common.Address
+		logTopics    []common.Hash
+	)
+	for i := range epochs {
+		epoch := &epochs[i]
+		missing, err := missingLifecycleEvents(db, addresses, epoch.RandomAcquisitionStartBlock, epoch.StartBlock)
+		if err != nil {
+			return errors.Wrapf(err, "check reward epoch %d", epoch.RewardEpochID)
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		logging.From(ctx, logging.Fsp).Warnw(
+			"Reward epoch is missing lifecycle events, backfilling its window",
+			"reward_epoch", epoch.RewardEpochID,
+			"missing", strings.Join(missing, ","),
+			"from", epoch.RandomAcquisitionStartBlock,
+			"to", epoch.StartBlock,
+		)
+		if logAddresses == nil {
+			if logAddresses, logTopics, err = resolveFspContractAddresses(ctx, ci.ContractResolver()); err != nil {
+				return err
+			}
+		}
+		err = backfillFspEventLogs(ctx, ci, epoch.RandomAcquisitionStartBlock, epoch.StartBlock, logAddresses, logTopics)
+		if err != nil {
+			return errors.Wrapf(err, "backfill reward epoch %d", epoch.RewardEpochID)
+		}
+
+		if missing, err = missingLifecycleEvents(db, addresses, epoch.RandomAcquisitionStartBlock, epoch.StartBlock); err != nil {
+			return errors.Wrapf(err, "check reward epoch %d", epoch.RewardEpochID)
+		}
+		if len(missing) != 0 {
+			logging.From(ctx, logging.Fsp).Errorw(
+				"Reward epoch is still missing lifecycle events after backfill",
+				"reward_epoch", epoch.RewardEpochID,
+				"missing", strings.Join(missing, ","),
+			)
+		}
+	}
+	return nil
+}
+
+// lifecycleAddresses returns the address query parameters of every contract
+// emitting a lifecycle event, including its earlier deployments, by
+// contract name.
+func lifecycleAddresses(ctx context.Context, resolver *contracts.ContractResolver) (map[string][]interface{}, error) {
+	addresses := make(map[string][]interface{})
+	for _, event := range epochLifecycleEvents {
+		if _, ok := addresses[event.contractName]; ok {
+			continue
+		}
+		resolved, err := resolver.ResolveAllByName(ctx, event.contractName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolve %s for the completeness check", event.contractName)
+		}
+		params := make([]interface{}, len(resolved))
+		for i, address := range resolved {
+			if params[i], err = database.HexParam(address.Hex()); err != nil {
+				return nil, err
+			}
+		}
+		addresses[event.contractName] = params
+	}
+	return addresses, nil
+}
+
+// missingLifecycleEvents returns the names of the lifecycle events without a
+// log from their contract in the blocks [from, to].
+func missingLifecycleEvents(db *gorm.DB, addresses map[string][]interface{}, from, to uint64) ([]string, error) {
+	var missing []string
+	for _, event := range epochLifecycleEvents {
+		topics := make([]interface{}, len(event.topics))
+		for i, topic := range event.topics {
+			param, err := database.HexParam(topic.Hex())
+			if err != nil {
+				return nil, err
+			}
+			topics[i] = param
+		}
+
+		var count int64
+		if eventAddresses := addresses[event.contractName]; len(eventAddresses) != 0 {
+			err := db.Model(&database.Log{}).
+				Where("topic0 IN ? AND address IN ? AND block_number BETWEEN ? AND ?", topics, eventAddresses, from, to).
+				Count(&count).Error
+			if err != nil {
+				return nil, errors.Wrapf(err, "count %s logs", event.name)
+			}
+		}
+		if count == 0 {
+			missing = append(missing, event.name)
+		}
+	}
+	return missing, nil
+}
@@ -0,0 +1,32 @@
+package fsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/require"
+)
+
+// The checker must only expect events the FSP event backfill collects, from
+// the contract it collects them from, or it would backfill the same windows
+// forever.
+func TestEpochLifecycleEventsAreCollected(t *testing.T) {
+	type collectedLog struct {
+		contractName string
+		topic        common.Hash
+	}
+	collected := make(map[collectedLog]bool)
+	for _, log := range config.FspRewardEpochLogs() {
+		collected[collectedLog{log.ContractName, common.HexToHash(strings.ToLower(log.Topic))}] = true
+	}
+
+	for _, event := range epochLifecycleEvents {
+		for _, topic := range event.topics {
+			require.True(t, collected[collectedLog{event.contractName, topic}],
+				"%s topic %s is not collected from %s", event.name, topic, event.contractName)
+		}
+	}
+}
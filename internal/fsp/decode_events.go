@@ -0,0 +1,540 @@
+package fsp
+
+import (
+	"context"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/calculator"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/fdchub"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/fumanager"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/offers"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/registry"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/relay"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Protocols of the reward_offers rows.
+const (
+	protocolFtso        = "ftso"
+	protocolFastUpdates = "fast_updates"
+	protocolFdc         = "fdc"
+)
+
+// Columns of voter_registrations written by each of the two registration
+// events; an upsert of one leaves the other's columns alone.
+var (
+	voterRegisteredColumns = []string{
+		"signing_policy_address", "submit_address", "submit_signatures_address",
+		"public_key", "registration_weight", "block_number", "timestamp",
+	}
+	voterRegistrationInfoColumns = []string{
+		"delegation_address", "delegation_fee_bips", "w_nat_weight",
+		"w_nat_capped_weight", "node_ids", "node_weights",
+	}
+)
+
+// decodedEvents are the rows decoded from the FSP events of one write.
+type decodedEvents struct {
+	policies          []*database.SigningPolicy
+	policyVoters      []*database.SigningPolicyVoter
+	registrations     []*database.VoterRegistration
+	registrationInfos []*database.VoterRegistration
+	offers            []*database.RewardOffer
+}
+
+type eventHandler struct {
+	contractName string
+	decode       func(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error
+}
+
+// calculatorNextMetaData is the VoterRegistrationInfo event of the upgraded
+// FlareSystemsCalculator, which has no binding in go-flare-common yet. It
+// only differs from the old one in the reward epoch ID being a uint32.
+var calculatorNextMetaData = &bind.MetaData{
+	ABI: `[{"anonymous":false,"type":"event","name":"VoterRegistrationInfo","inputs":[` +
+		`{"indexed":true,"name":"voter","type":"address"},` +
+		`{"indexed":true,"name":"rewardEpochId","type":"uint32"},` +
+		`{"indexed":false,"name":"delegationAddress","type":"address"},` +
+		`{"indexed":false,"name":"delegationFeeBIPS","type":"uint16"},` +
+		`{"indexed":false,"name":"wNatWeight","type":"uint256"},` +
+		`{"indexed":false,"name":"wNatCappedWeight","type":"uint256"},` +
+		`{"indexed":false,"name":"nodeIds","type":"bytes20[]"},` +
+		`{"indexed":false,"name":"nodeWeights","type":"uint256[]"}]}]`,
+}
+
+// calculatorNextVoterRegistrationInfo is the upgraded FlareSystemsCalculator
+// VoterRegistrationInfo event.
+type calculatorNextVoterRegistrationInfo struct {
+	Voter             common.Address
+	RewardEpochId     uint32
+	DelegationAddress common.Address
+	DelegationFeeBIPS uint16
+	WNatWeight        *big.Int
+	WNatCappedWeight  *big.Int
+	NodeIds           [][20]byte
+	NodeWeights       []*big.Int
+}
+
+// eventDecoder decodes the reward-epoch events among saved logs into the FSP
+// tables. Events are recognized by topic and only decoded when emitted by an
+// address the contract registry resolves for the event's contract. The
+// upgraded VoterRegistry's VoterRegistered event has no known ABI; it is
+// logged as a warning and stays undecoded.
+type eventDecoder struct {
+	handlers map[common.Hash]eventHandler
+	// addresses is replaced by resolve when a contract is redeployed.
+	addresses map[string]map[common.Address]bool
+	mu        sync.RWMutex
+
+	relay      *relay.RelayFilterer
+	registry   *registry.RegistryFilterer
+	calculator *calculator.CalculatorFilterer
+	offers     *offers.OffersFilterer
+	fumanager  *fumanager.FUManagerFilterer
+	fdchub     *fdchub.FdcHubFilterer
+	// calculatorNext parses the upgraded FlareSystemsCalculator events.
+	calculatorNext *bind.BoundContract
+}
+
+func newEventDecoder(addresses map[string]map[common.Address]bool) (*eventDecoder, error) {
+	d := &eventDecoder{addresses: addresses}
+
+	var err error
+	if d.relay, err = relay.NewRelayFilterer(common.Address{}, nil); err != nil {
+		return nil, errors.Wrap(err, "bind Relay filterer")
+	}
+	if d.registry, err = registry.NewRegistryFilterer(common.Address{}, nil); err != nil {
+		return nil, errors.Wrap(err, "bind VoterRegistry filterer")
+	}
+	if d.calculator, err = calculator.NewCalculatorFilterer(common.Address{}, nil); err != nil {
+		return nil, errors.Wrap(err, "bind FlareSystemsCalculator filterer")
+	}
+	if d.offers, err = offers.NewOffersFilterer(common.Address{}, nil); err != nil {
+		return nil, errors.Wrap(err, "bind FtsoRewardOffersManager filterer")
+	}
+	if d.fumanager, err = fumanager.NewFUManagerFilterer(common.Address{}, nil); err != nil {
+		return nil, errors.Wrap(err, "bind FastUpdateIncentiveManager filterer")
+	}
+	if d.fdchub, err = fdchub.NewFdcHubFilterer(common.Address{}, nil); err != nil {
+		return nil, errors.Wrap(err, "bind FdcHub filterer")
+	}
+	calculatorNextABI, err := calculatorNextMetaData.GetAbi()
+	if err != nil {
+		return nil, errors.Wrap(err, "parse FlareSystemsCalculatorNext ABI")
+	}
+	d.calculatorNext = bind.NewBoundContract(common.Address{}, *calculatorNextABI, nil, nil, nil)
+
+	d.handlers = map[common.Hash]eventHandler{
+		eventID(relay.RelayMetaData, "SigningPolicyInitialized"):        {"Relay", decodeSigningPolicy},
+		eventID(registry.RegistryMetaData, "VoterRegistered"):           {"VoterRegistry", decodeVoterRegistered},
+		eventID(calculator.CalculatorMetaData, "VoterRegistrationInfo"): {"FlareSystemsCalculator", decodeVoterRegistrationInfo},
+		eventID(offers.OffersMetaData, "InflationRewardsOffered"):       {"FtsoRewardOffersManager", decodeFtsoInflationOffer},
+		eventID(offers.OffersMetaData, "RewardsOffered"):                {"FtsoRewardOffersManager", decodeFtsoCommunityOffer},
+		eventID(fumanager.FUManagerMetaData, "InflationRewardsOffered"): {"FastUpdateIncentiveManager", decodeFastUpdatesInflationOffer},
+		eventID(fumanager.FUManagerMetaData, "IncentiveOffered"):        {"FastUpdateIncentiveManager", decodeFastUpdatesIncentive},
+		eventID(fdchub.FdcHubMetaData, "InflationRewardsOffered"):       {"FdcHub", decodeFdcInflationOffer},
+		eventID(calculatorNextMetaData, "VoterRegistrationInfo"):        {"FlareSystemsCalculator", decodeVoterRegistrationInfoNext},
+		common.HexToHash(config.VoterRegisteredNextTopic):               {"VoterRegistry", warnVoterRegisteredNext},
+	}
+
+	return d, nil
+}
+
+// resolveEventDecoder builds the decoder with the addresses of the contracts
+// emitting the decoded events, including their earlier deployments.
+func resolveEventDecoder(ctx context.Context, resolver *contracts.ContractResolver) (*eventDecoder, error) {
+	addresses, err := resolveEventAddresses(ctx, resolver)
+	if err != nil {
+		return nil, err
+	}
+	return newEventDecoder(addresses)
+}
+
+// resolve is the core.ContractsHook re-reading the addresses from the
+// resolver after a redeployment.
+func (d *eventDecoder) resolve(resolver *contracts.ContractResolver) core.ContractsHook {
+	return func(ctx context.Context) error {
+		addresses, err := resolveEventAddresses(ctx, resolver)
+		if err != nil {
+			return err
+		}
+		d.mu.Lock()
+		d.addresses = addresses
+		d.mu.Unlock()
+		return nil
+	}
+}
+
+func resolveEventAddresses(ctx context.Context, resolver *contracts.ContractResolver) (map[string]map[common.Address]bool, error) {
+	addresses := make(map[string]map[common.Address]bool)
+	for _, name := range []string{
+		"Relay", "VoterRegistry", "FlareSystemsCalculator",
+		"FtsoRewardOffersManager", "FastUpdateIncentiveManager", "FdcHub",
+	} {
+		resolved, err := resolver.ResolveAllByName(ctx, name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "resolve %s for FSP event decoding", name)
+		}
+		addresses[name] = make(map[common.Address]bool, len(resolved))
+		for _, address := range resolved {
+			addresses[name][address] = true
+		}
+	}
+	return addresses, nil
+}
+
+func eventID(meta *bind.MetaData, name string) common.Hash {
+	parsedABI, err := meta.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	event, ok := parsedABI.Events[name]
+	if !ok {
+		panic("event not found in ABI: " + name)
+	}
+	return event.ID
+}
+
+// saveHook is the core.SaveHook writing the rows decoded from the saved logs.
+func (d *eventDecoder) saveHook(tx *gorm.DB, rows core.SavedRows) error {
+	return d.decode(rows.Logs).save(tx)
+}
+
+// decode skips the logs that fail to decode, so one malformed event does
+// not hold back the indexing of the whole batch.
+func (d *eventDecoder) decode(logs []*database.Log) *decodedEvents {
+	d.mu.RLock()
+	addresses := d.addresses
+	d.mu.RUnlock()
+
+	rows := &decodedEvents{}
+	for _, dbLog := range logs {
+		handler, ok := d.handlers[common.HexToHash(dbLog.Topic0)]
+		if !ok || !addresses[handler.contractName][common.HexToAddress(dbLog.Address)] {
+			continue
+		}
+		log, err := ethLog(dbLog)
+		if err == nil {
+			err = handler.decode(d, log, dbLog, rows)
+		}
+		if err != nil {
+			logging.For(logging.Fsp).Warnw(
+				"Skipping undecodable FSP event", "contract", handler.contractName,
+				"transaction", dbLog.TransactionHash, "log_index", dbLog.LogIndex, "error", err,
+			)
+		}
+	}
+	return rows
+}
+
+// decodedEventsBackfillBatch is the number of stored logs decoded per
+// database transaction by backfill.
+const decodedEventsBackfillBatch = 1000
+
+// backfill decodes the logs already stored into the FSP tables: those of a
+// database upgraded from a version that did not decode them, and of events
+// only decoded since. Rows already present are left alone, so it is safe to
+// run on every start. The upgraded VoterRegistry's VoterRegistered events
+// are left out, to not repeat their warnings on each start.
+func (d *eventDecoder) backfill(ctx context.Context, db *gorm.DB) error {
+	db = db.WithContext(ctx)
+	topics := make([]interface{}, 0, len(d.handlers))
+	for topic := range d.handlers {
+		if topic == common.HexToHash(config.VoterRegisteredNextTopic) {
+			continue
+		}
+		param, err := database.HexParam(topic.Hex())
+		if err != nil {
+			return err
+		}
+		topics = append(topics, param)
+	}
+
+	var lastID uint64
+	decoded := 0
+	for {
+		var logs []*database.Log
+		err := db.Where("topic0 IN ? AND id > ?", topics, lastID).
+			Order("id ASC").Limit(decodedEventsBackfillBatch).Find(&logs).Error
+		if err != nil {
+			return errors.Wrap(err, "read logs to decode")
+		}
+		if len(logs) == 0 {
+			break
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return d.decode(logs).save(tx)
+		})
+		if err != nil {
+			return err
+		}
+		lastID = logs[len(logs)-1].ID
+		decoded += len(logs)
+	}
+
+	logging.From(ctx, logging.Fsp).Infow("Decoded stored FSP events", "logs", decoded)
+	return nil
+}
+
+// ethLog turns a log row back into the log the bindings parse.
+func ethLog(dbLog *database.Log) (types.Log, error) {
+	data, err := hex.DecodeString(dbLog.Data)
+	if err != nil {
+		return types.Log{}, errors.Wrapf(err, "log data of transaction %s log %d", dbLog.TransactionHash, dbLog.LogIndex)
+	}
+
+	log := types.Log{
+		Address:     common.HexToAddress(dbLog.Address),
+		Data:        data,
+		BlockNumber: dbLog.BlockNumber,
+		TxHash:      common.HexToHash(dbLog.TransactionHash),
+		Index:       uint(dbLog.LogIndex),
+	}
+	for _, topic := range []string{dbLog.Topic0, dbLog.Topic1, dbLog.Topic2, dbLog.Topic3} {
+		if topic == database.NullTopic {
+			break
+		}
+		log.Topics = append(log.Topics, common.HexToHash(topic))
+	}
+	return log, nil
+}
+
+func (rows *decodedEvents) save(tx *gorm.DB) error {
+	insertIgnore := tx.Clauses(clause.Insert{Modifier: "IGNORE"})
+	if len(rows.policies) != 0 {
+		if err := insertIgnore.CreateInBatches(rows.policies, database.DBTransactionBatchesSize).Error; err != nil {
+			return errors.Wrap(err, "save signing policies")
+		}
+	}
+	if len(rows.policyVoters) != 0 {
+		if err := insertIgnore.CreateInBatches(rows.policyVoters, database.DBTransactionBatchesSize).Error; err != nil {
+			return errors.Wrap(err, "save signing policy voters")
+		}
+	}
+	if err := upsertRegistrations(tx, rows.registrations, voterRegisteredColumns); err != nil {
+		return errors.Wrap(err, "save voter registrations")
+	}
+	if err := upsertRegistrations(tx, rows.registrationInfos, voterRegistrationInfoColumns); err != nil {
+		return errors.Wrap(err, "save voter registration info")
+	}
+	if len(rows.offers) != 0 {
+		if err := insertIgnore.CreateInBatches(rows.offers, database.DBTransactionBatchesSize).Error; err != nil {
+			return errors.Wrap(err, "save reward offers")
+		}
+	}
+	return nil
+}
+
+func upsertRegistrations(tx *gorm.DB, registrations []*database.VoterRegistration, columns []string) error {
+	if len(registrations) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "reward_epoch_id"}, {Name: "voter"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+	}).CreateInBatches(registrations, database.DBTransactionBatchesSize).Error
+}
+
+func decodeSigningPolicy(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.relay.ParseSigningPolicyInitialized(log)
+	if err != nil {
+		return err
+	}
+	if len(event.Voters) != len(event.Weights) {
+		return errors.Errorf("%d voters with %d weights", len(event.Voters), len(event.Weights))
+	}
+
+	epoch := event.RewardEpochId.Uint64()
+	rows.policies = append(rows.policies, &database.SigningPolicy{
+		RewardEpochID:      epoch,
+		StartVotingRoundID: uint64(event.StartVotingRoundId),
+		Threshold:          uint64(event.Threshold),
+		Seed:               hex.EncodeToString(common.BigToHash(event.Seed).Bytes()),
+		TransactionHash:    dbLog.TransactionHash,
+		LogIndex:           dbLog.LogIndex,
+		BlockNumber:        dbLog.BlockNumber,
+		Timestamp:          dbLog.Timestamp,
+	})
+	for i, voter := range event.Voters {
+		rows.policyVoters = append(rows.policyVoters, &database.SigningPolicyVoter{
+			RewardEpochID:        epoch,
+			VoterIndex:           uint64(i),
+			SigningPolicyAddress: hexAddress(voter),
+			Weight:               uint64(event.Weights[i]),
+			BlockNumber:          dbLog.BlockNumber,
+			Timestamp:            dbLog.Timestamp,
+		})
+	}
+	return nil
+}
+
+func decodeVoterRegistered(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.registry.ParseVoterRegistered(log)
+	if err != nil {
+		return err
+	}
+
+	rows.registrations = append(rows.registrations, &database.VoterRegistration{
+		RewardEpochID:           event.RewardEpochId.Uint64(),
+		Voter:                   hexAddress(event.Voter),
+		SigningPolicyAddress:    hexAddress(event.SigningPolicyAddress),
+		SubmitAddress:           hexAddress(event.SubmitAddress),
+		SubmitSignaturesAddress: hexAddress(event.SubmitSignaturesAddress),
+		PublicKey:               hex.EncodeToString(event.PublicKeyPart1[:]) + hex.EncodeToString(event.PublicKeyPart2[:]),
+		RegistrationWeight:      event.RegistrationWeight.String(),
+		BlockNumber:             dbLog.BlockNumber,
+		Timestamp:               dbLog.Timestamp,
+	})
+	return nil
+}
+
+func decodeVoterRegistrationInfo(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.calculator.ParseVoterRegistrationInfo(log)
+	if err != nil {
+		return err
+	}
+	rows.registrationInfos = append(rows.registrationInfos, registrationInfo(dbLog, event))
+	return nil
+}
+
+func decodeVoterRegistrationInfoNext(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	var event calculatorNextVoterRegistrationInfo
+	if err := d.calculatorNext.UnpackLog(&event, "VoterRegistrationInfo", log); err != nil {
+		return err
+	}
+	rows.registrationInfos = append(rows.registrationInfos, registrationInfo(dbLog, &calculator.CalculatorVoterRegistrationInfo{
+		Voter:             event.Voter,
+		RewardEpochId:     new(big.Int).SetUint64(uint64(event.RewardEpochId)),
+		DelegationAddress: event.DelegationAddress,
+		DelegationFeeBIPS: event.DelegationFeeBIPS,
+		WNatWeight:        event.WNatWeight,
+		WNatCappedWeight:  event.WNatCappedWeight,
+		NodeIds:           event.NodeIds,
+		NodeWeights:       event.NodeWeights,
+	}))
+	return nil
+}
+
+func registrationInfo(dbLog *database.Log, event *calculator.CalculatorVoterRegistrationInfo) *database.VoterRegistration {
+	nodeIDs := make([]string, len(event.NodeIds))
+	for i := range event.NodeIds {
+		nodeIDs[i] = hex.EncodeToString(event.NodeIds[i][:])
+	}
+	return &database.VoterRegistration{
+		RewardEpochID:     event.RewardEpochId.Uint64(),
+		Voter:             hexAddress(event.Voter),
+		DelegationAddress: hexAddress(event.DelegationAddress),
+		DelegationFeeBIPS: uint64(event.DelegationFeeBIPS),
+		WNatWeight:        event.WNatWeight.String(),
+		WNatCappedWeight:  event.WNatCappedWeight.String(),
+		NodeIDs:           strings.Join(nodeIDs, ","),
+		NodeWeights:       joinDecimals(event.NodeWeights),
+		BlockNumber:       dbLog.BlockNumber,
+		Timestamp:         dbLog.Timestamp,
+	}
+}
+
+// warnVoterRegisteredNext reports a registration of the upgraded
+// VoterRegistry, whose VoterRegistered signature is not known, so that the
+// voter_registrations rows it leaves out do not go unnoticed. The log itself
+// is stored in logs.
+func warnVoterRegisteredNext(_ *eventDecoder, _ types.Log, dbLog *database.Log, _ *decodedEvents) error {
+	logging.For(logging.Fsp).Warnw(
+		"Not decoding upgraded VoterRegistry VoterRegistered event, its ABI is unknown",
+		"transaction", dbLog.TransactionHash, "log_index", dbLog.LogIndex, "block", dbLog.BlockNumber,
+	)
+	return nil
+}
+
+func decodeFtsoInflationOffer(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.offers.ParseInflationRewardsOffered(log)
+	if err != nil {
+		return err
+	}
+	rows.offers = append(rows.offers, rewardOffer(dbLog, event.RewardEpochId, protocolFtso, true, event.Amount, event.FeedIds))
+	return nil
+}
+
+func decodeFtsoCommunityOffer(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.offers.ParseRewardsOffered(log)
+	if err != nil {
+		return err
+	}
+	offer := rewardOffer(dbLog, event.RewardEpochId, protocolFtso, false, event.Amount, event.FeedId[:])
+	offer.ClaimBackAddress = hexAddress(event.ClaimBackAddress)
+	rows.offers = append(rows.offers, offer)
+	return nil
+}
+
+func decodeFastUpdatesInflationOffer(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.fumanager.ParseInflationRewardsOffered(log)
+	if err != nil {
+		return err
+	}
+	var feedIDs []byte
+	for _, feed := range event.FeedConfigurations {
+		feedIDs = append(feedIDs, feed.FeedId[:]...)
+	}
+	rows.offers = append(rows.offers, rewardOffer(dbLog, event.RewardEpochId, protocolFastUpdates, true, event.Amount, feedIDs))
+	return nil
+}
+
+func decodeFastUpdatesIncentive(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.fumanager.ParseIncentiveOffered(log)
+	if err != nil {
+		return err
+	}
+	rows.offers = append(rows.offers, rewardOffer(dbLog, event.RewardEpochId, protocolFastUpdates, false, event.OfferAmount, nil))
+	return nil
+}
+
+func decodeFdcInflationOffer(d *eventDecoder, log types.Log, dbLog *database.Log, rows *decodedEvents) error {
+	event, err := d.fdchub.ParseInflationRewardsOffered(log)
+	if err != nil {
+		return err
+	}
+	rows.offers = append(rows.offers, rewardOffer(dbLog, event.RewardEpochId, protocolFdc, true, event.Amount, nil))
+	return nil
+}
+
+func rewardOffer(
+	dbLog *database.Log, epoch *big.Int, protocol string, inflation bool, amount *big.Int, feedIDs []byte,
+) *database.RewardOffer {
+	return &database.RewardOffer{
+		RewardEpochID:   epoch.Uint64(),
+		Protocol:        protocol,
+		Inflation:       inflation,
+		Amount:          amount.String(),
+		FeedIDs:         hex.EncodeToString(feedIDs),
+		TransactionHash: dbLog.TransactionHash,
+		LogIndex:        dbLog.LogIndex,
+		BlockNumber:     dbLog.BlockNumber,
+		Timestamp:       dbLog.Timestamp,
+	}
+}
+
+func hexAddress(address common.Address) string {
+	return strings.ToLower(address.Hex()[2:])
+}
+
+func joinDecimals(values []*big.Int) string {
+	decimals := make([]string, len(values))
+	for i, value := range values {
+		decimals[i] = value.String()
+	}
+	return strings.Join(decimals, ",")
+}
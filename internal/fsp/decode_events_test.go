@@ -0,0 +1,158 @@
+package fsp
+
+import (
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/calculator"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/offers"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/registry"
+	"github.com/flare-foundation/go-flare-common/pkg/contracts/relay"
+	"github.com/stretchr/testify/require"
+)
+
+// eventLog packs an event the way a node returns it and builds its log row.
+func eventLog(
+	t *testing.T, meta *bind.MetaData, name string, address common.Address, logIndex uint64,
+	topics []common.Hash, data ...interface{},
+) *database.Log {
+	t.Helper()
+	parsedABI, err := meta.GetAbi()
+	require.NoError(t, err)
+	event := parsedABI.Events[name]
+	packed, err := event.Inputs.NonIndexed().Pack(data...)
+	require.NoError(t, err)
+
+	row := [4]string{database.NullTopic, database.NullTopic, database.NullTopic, database.NullTopic}
+	for i, topic := range append([]common.Hash{event.ID}, topics...) {
+		row[i] = hex.EncodeToString(topic.Bytes())
+	}
+	return &database.Log{
+		Address:         hexAddress(address),
+		Data:            hex.EncodeToString(packed),
+		Topic0:          row[0],
+		Topic1:          row[1],
+		Topic2:          row[2],
+		Topic3:          row[3],
+		TransactionHash: strings.Repeat("ab", 32),
+		LogIndex:        logIndex,
+		BlockNumber:     100,
+		Timestamp:       1000,
+	}
+}
+
+func TestEventDecoder(t *testing.T) {
+	relayAddress := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	registryAddress := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	calculatorAddress := common.HexToAddress("0x00000000000000000000000000000000000000a3")
+	offersAddress := common.HexToAddress("0x00000000000000000000000000000000000000a4")
+	voter := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	signer := common.HexToAddress("0x00000000000000000000000000000000000000b2")
+
+	d, err := newEventDecoder(map[string]map[common.Address]bool{
+		"Relay":                   {relayAddress: true},
+		"VoterRegistry":           {registryAddress: true},
+		"FlareSystemsCalculator":  {calculatorAddress: true},
+		"FtsoRewardOffersManager": {offersAddress: true},
+	})
+	require.NoError(t, err)
+
+	epochTopic := common.BigToHash(big.NewInt(7))
+	var feedID [21]byte
+	feedID[0] = 1
+	logs := []*database.Log{
+		eventLog(t, relay.RelayMetaData, "SigningPolicyInitialized", relayAddress, 0,
+			[]common.Hash{epochTopic},
+			uint32(500), uint16(30), big.NewInt(255), []common.Address{signer, voter}, []uint16{40, 20}, []byte{1}, uint64(1000)),
+		eventLog(t, registry.RegistryMetaData, "VoterRegistered", registryAddress, 1,
+			[]common.Hash{common.BytesToHash(voter.Bytes()), epochTopic, common.BytesToHash(signer.Bytes())},
+			voter, voter, [32]byte{1}, [32]byte{2}, big.NewInt(1e18)),
+		eventLog(t, calculator.CalculatorMetaData, "VoterRegistrationInfo", calculatorAddress, 2,
+			[]common.Hash{common.BytesToHash(voter.Bytes()), epochTopic},
+			voter, uint16(2000), big.NewInt(5), big.NewInt(4), [][20]byte{{9}}, []*big.Int{big.NewInt(3)}),
+		eventLog(t, offers.OffersMetaData, "RewardsOffered", offersAddress, 3,
+			[]common.Hash{epochTopic},
+			feedID, int8(5), big.NewInt(42), uint16(0), big.NewInt(0), big.NewInt(0), voter),
+		// Same event from a contract the registry does not know.
+		eventLog(t, offers.OffersMetaData, "RewardsOffered", voter, 4,
+			[]common.Hash{epochTopic},
+			feedID, int8(5), big.NewInt(42), uint16(0), big.NewInt(0), big.NewInt(0), voter),
+	}
+
+	// A log whose data does not decode is skipped.
+	malformed := eventLog(t, registry.RegistryMetaData, "VoterRegistered", registryAddress, 5,
+		[]common.Hash{common.BytesToHash(signer.Bytes()), epochTopic, common.BytesToHash(signer.Bytes())},
+		voter, voter, [32]byte{1}, [32]byte{2}, big.NewInt(1e18))
+	malformed.Data = malformed.Data[:64]
+	logs = append(logs, malformed)
+
+	rows := d.decode(logs)
+
+	require.Len(t, rows.policies, 1)
+	require.Equal(t, uint64(7), rows.policies[0].RewardEpochID)
+	require.Equal(t, uint64(500), rows.policies[0].StartVotingRoundID)
+	require.Equal(t, uint64(30), rows.policies[0].Threshold)
+	require.Equal(t, strings.Repeat("0", 62)+"ff", rows.policies[0].Seed)
+	require.Len(t, rows.policyVoters, 2)
+	require.Equal(t, uint64(1), rows.policyVoters[1].VoterIndex)
+	require.Equal(t, hexAddress(voter), rows.policyVoters[1].SigningPolicyAddress)
+	require.Equal(t, uint64(20), rows.policyVoters[1].Weight)
+
+	require.Len(t, rows.registrations, 1)
+	require.Equal(t, hexAddress(signer), rows.registrations[0].SigningPolicyAddress)
+	require.Equal(t, "1000000000000000000", rows.registrations[0].RegistrationWeight)
+	require.Len(t, rows.registrations[0].PublicKey, 128)
+	require.Len(t, rows.registrationInfos, 1)
+	require.Equal(t, uint64(2000), rows.registrationInfos[0].DelegationFeeBIPS)
+	require.Equal(t, "09"+strings.Repeat("00", 19), rows.registrationInfos[0].NodeIDs)
+	require.Equal(t, "3", rows.registrationInfos[0].NodeWeights)
+
+	require.Len(t, rows.offers, 1)
+	require.Equal(t, protocolFtso, rows.offers[0].Protocol)
+	require.False(t, rows.offers[0].Inflation)
+	require.Equal(t, "42", rows.offers[0].Amount)
+	require.Equal(t, hex.EncodeToString(feedID[:]), rows.offers[0].FeedIDs)
+	require.Equal(t, hexAddress(voter), rows.offers[0].ClaimBackAddress)
+	require.Equal(t, uint64(3), rows.offers[0].LogIndex)
+}
+
+func TestEventDecoderNextEvents(t *testing.T) {
+	registryAddress := common.HexToAddress("0x00000000000000000000000000000000000000a2")
+	calculatorAddress := common.HexToAddress("0x00000000000000000000000000000000000000a3")
+	voter := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+
+	require.Equal(t, config.VoterRegistrationInfoNextTopic, eventID(calculatorNextMetaData, "VoterRegistrationInfo").Hex())
+
+	d, err := newEventDecoder(map[string]map[common.Address]bool{
+		"VoterRegistry":          {registryAddress: true},
+		"FlareSystemsCalculator": {calculatorAddress: true},
+	})
+	require.NoError(t, err)
+
+	registered := eventLog(t, registry.RegistryMetaData, "VoterRegistered", registryAddress, 0,
+		[]common.Hash{common.BytesToHash(voter.Bytes()), common.BigToHash(big.NewInt(7)), common.BytesToHash(voter.Bytes())},
+		voter, voter, [32]byte{1}, [32]byte{2}, big.NewInt(1e18))
+	registered.Topic0 = strings.TrimPrefix(config.VoterRegisteredNextTopic, "0x")
+	logs := []*database.Log{
+		eventLog(t, calculatorNextMetaData, "VoterRegistrationInfo", calculatorAddress, 1,
+			[]common.Hash{common.BytesToHash(voter.Bytes()), common.BigToHash(big.NewInt(1 << 25))},
+			voter, uint16(2000), big.NewInt(5), big.NewInt(4), [][20]byte{{9}, {8}}, []*big.Int{big.NewInt(3), big.NewInt(2)}),
+		registered,
+	}
+
+	rows := d.decode(logs)
+	require.Empty(t, rows.registrations, "the upgraded VoterRegistered event is not decoded")
+	require.Len(t, rows.registrationInfos, 1)
+	require.Equal(t, uint64(1<<25), rows.registrationInfos[0].RewardEpochID, "the reward epoch ID is wider than uint24")
+	require.Equal(t, hexAddress(voter), rows.registrationInfos[0].Voter)
+	require.Equal(t, uint64(2000), rows.registrationInfos[0].DelegationFeeBIPS)
+	require.Equal(t, "09"+strings.Repeat("00", 19)+",08"+strings.Repeat("00", 19), rows.registrationInfos[0].NodeIDs)
+	require.Equal(t, "3,2", rows.registrationInfos[0].NodeWeights)
+}
@@ -0,0 +1,131 @@
+package fsp
+
+import (
+	"context"
+	"encoding/hex"
+	"slices"
+	"sync"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/ethereum/go-ethereum/common"
+	commondb "github.com/flare-foundation/go-flare-common/pkg/database"
+	"github.com/flare-foundation/go-flare-common/pkg/payload"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// submissionSelectors are the Submission functions whose calldata is a
+// sequence of per-protocol messages: submit1, submit2 and submitSignatures.
+var submissionSelectors = map[string]bool{
+	"6c532fae": true,
+	"9d00c9fd": true,
+	"57eed580": true,
+}
+
+// submissionDecoder splits the calldata of saved Submission transactions
+// into their per-protocol messages for the submission_payloads table.
+type submissionDecoder struct {
+	// addresses is replaced by resolve when Submission is redeployed.
+	addresses map[common.Address]bool
+	mu        sync.RWMutex
+}
+
+// resolveSubmissionDecoder builds the decoder with the Submission addresses,
+// including earlier deployments.
+func resolveSubmissionDecoder(ctx context.Context, resolver *contracts.ContractResolver) (*submissionDecoder, error) {
+	addresses, err := resolveSubmissionAddresses(ctx, resolver)
+	if err != nil {
+		return nil, err
+	}
+	return &submissionDecoder{addresses: addresses}, nil
+}
+
+// resolve is the core.ContractsHook re-reading the Submission addresses from
+// the resolver after a redeployment.
+func (d *submissionDecoder) resolve(resolver *contracts.ContractResolver) core.ContractsHook {
+	return func(ctx context.Context) error {
+		addresses, err := resolveSubmissionAddresses(ctx, resolver)
+		if err != nil {
+			return err
+		}
+		d.mu.Lock()
+		d.addresses = addresses
+		d.mu.Unlock()
+		return nil
+	}
+}
+
+func resolveSubmissionAddresses(ctx context.Context, resolver *contracts.ContractResolver) (map[common.Address]bool, error) {
+	resolved, err := resolver.ResolveAllByName(ctx, "Submission")
+	if err != nil {
+		return nil, errors.Wrap(err, "resolve Submission for submission decoding")
+	}
+
+	addresses := make(map[common.Address]bool, len(resolved))
+	for _, address := range resolved {
+		addresses[address] = true
+	}
+	return addresses, nil
+}
+
+// saveHook is the core.SaveHook writing the messages of the saved
+// submission transactions.
+func (d *submissionDecoder) saveHook(tx *gorm.DB, rows core.SavedRows) error {
+	payloads := d.decode(rows.Transactions)
+	if len(payloads) == 0 {
+		return nil
+	}
+
+	err := tx.Clauses(clause.Insert{Modifier: "IGNORE"}).
+		CreateInBatches(payloads, database.DBTransactionBatchesSize).
+		Error
+	return errors.Wrap(err, "save submission payloads")
+}
+
+// decode returns the messages of the submission transactions among txs.
+// Anyone can call the submission functions, so calldata that is not a valid
+// message sequence is skipped rather than failing the write.
+func (d *submissionDecoder) decode(txs []*database.Transaction) []*database.SubmissionPayload {
+	d.mu.RLock()
+	addresses := d.addresses
+	d.mu.RUnlock()
+
+	var payloads []*database.SubmissionPayload
+	for _, dbTx := range txs {
+		if !submissionSelectors[dbTx.FunctionSig] || !addresses[common.HexToAddress(dbTx.ToAddress)] {
+			continue
+		}
+
+		messages, err := payload.ExtractPayloads(&commondb.Transaction{Hash: dbTx.Hash, Input: dbTx.Input})
+		if err != nil {
+			logging.For(logging.Fsp).Debugw("Skipping malformed submission", "transaction", dbTx.Hash, "error", err)
+			continue
+		}
+
+		protocolIDs := make([]uint8, 0, len(messages))
+		for protocolID := range messages {
+			protocolIDs = append(protocolIDs, protocolID)
+		}
+		slices.Sort(protocolIDs)
+		for _, protocolID := range protocolIDs {
+			message := messages[protocolID]
+			payloads = append(payloads, &database.SubmissionPayload{
+				TransactionID:   dbTx.ID,
+				TransactionHash: dbTx.Hash,
+				FunctionSig:     dbTx.FunctionSig,
+				FromAddress:     dbTx.FromAddress,
+				ProtocolID:      uint64(protocolID),
+				VotingRoundID:   uint64(message.VotingRound),
+				Payload:         hex.EncodeToString(message.Payload),
+				BlockNumber:     dbTx.BlockNumber,
+				Timestamp:       dbTx.Timestamp,
+			})
+		}
+	}
+	return payloads
+}
@@ -0,0 +1,52 @@
+package fsp
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/flare-foundation/go-flare-common/pkg/payload"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmissionDecoder(t *testing.T) {
+	submission := common.HexToAddress("0x00000000000000000000000000000000000000c1")
+	d := &submissionDecoder{addresses: map[common.Address]bool{submission: true}}
+
+	// Messages as built by FSP clients: 0x<protocol><round><length><payload>.
+	ftso := strings.TrimPrefix(payload.BuildMessage(100, 9000, []byte{1, 2, 3}), "0x")
+	fdc := strings.TrimPrefix(payload.BuildMessage(200, 9001, nil), "0x")
+
+	submit2 := &database.Transaction{
+		Hash:        strings.Repeat("aa", 32),
+		FunctionSig: "9d00c9fd",
+		Input:       "9d00c9fd" + fdc + ftso,
+		FromAddress: strings.Repeat("0b", 20),
+		ToAddress:   hexAddress(submission),
+		BlockNumber: 5,
+		Timestamp:   50,
+	}
+	submit2.ID = 3
+	txs := []*database.Transaction{
+		submit2,
+		// Truncated message.
+		{Hash: strings.Repeat("bb", 32), FunctionSig: "6c532fae", Input: "6c532fae" + ftso[:10], ToAddress: hexAddress(submission)},
+		// Another function of the contract.
+		{Hash: strings.Repeat("cc", 32), FunctionSig: "12345678", Input: "12345678" + ftso, ToAddress: hexAddress(submission)},
+		// Same calldata sent elsewhere.
+		{Hash: strings.Repeat("dd", 32), FunctionSig: "9d00c9fd", Input: "9d00c9fd" + ftso, ToAddress: strings.Repeat("0c", 20)},
+	}
+
+	payloads := d.decode(txs)
+	require.Len(t, payloads, 2)
+	require.Equal(t, uint64(100), payloads[0].ProtocolID)
+	require.Equal(t, uint64(9000), payloads[0].VotingRoundID)
+	require.Equal(t, "010203", payloads[0].Payload)
+	require.Equal(t, uint64(3), payloads[0].TransactionID)
+	require.Equal(t, strings.Repeat("0b", 20), payloads[0].FromAddress)
+	require.Equal(t, uint64(200), payloads[1].ProtocolID)
+	require.Equal(t, uint64(9001), payloads[1].VotingRoundID)
+	require.Empty(t, payloads[1].Payload)
+}
@@ -258,3 +258,25 @@ func TestResolveStartEpochAtEpochZero(t *testing.T) {
 	require.True(t, ok)
 	require.Equal(t, uint64(0), id)
 }
+
+func TestFspStatusInfo(t *testing.T) {
+	t.Run("reports the epoch startup would resolve and its anchor", func(t *testing.T) {
+		fsm := &fakeFSM{current: 250, epochs: startedEpochs(223, 250)}
+
+		info, err := fspStatusInfo(context.Background(), fsm, 100)
+		require.NoError(t, err)
+		require.Equal(t, uint64(250), info.CurrentRewardEpoch)
+		require.Equal(t, uint64(223), info.StartRewardEpoch, "window shrinks to the oldest epoch with data")
+		require.True(t, info.HasEventAnchor)
+		require.Equal(t, fsm.epochs[223].startBlock-fspEventLeadBlocks, info.EventAnchorBlock)
+	})
+
+	t.Run("history_epochs zero starts at the current epoch", func(t *testing.T) {
+		fsm := &fakeFSM{current: 250, epochs: startedEpochs(223, 250)}
+
+		info, err := fspStatusInfo(context.Background(), fsm, 0)
+		require.NoError(t, err)
+		require.Equal(t, uint64(250), info.StartRewardEpoch)
+		require.Equal(t, fsm.epochs[248].raBlock, info.EventAnchorBlock)
+	})
+}
@@ -0,0 +1,68 @@
+package fsp
+
+import (
+	"context"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+)
+
+// Plan computes what FSP startup would do without writing: the event
+// backfill and catchup ranges of IndexStartup, where continuous indexing
+// resumes, and the boundary of the first history-drop iteration. states are
+// the coverage states; empty when the database has not been created yet.
+func Plan(
+	ctx context.Context, ci *core.Engine, states map[database.StateName]database.State,
+) (*core.StartupPlan, error) {
+	fsmCaller, err := bindFsm(ctx, ci)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := planStartup(ctx, ci, fsmCaller, states)
+	if err != nil {
+		return nil, err
+	}
+
+	historyEpochs := ci.Params().HistoryEpochs
+	plan := &core.StartupPlan{
+		Mode:                     config.IndexerModeFsp,
+		LatestConfirmed:          p.latestConfirmedNumber,
+		LatestConfirmedTimestamp: p.latestConfirmedTimestamp,
+		RetentionEnabled:         true,
+	}
+
+	currentEpochID, err := fspCurrentEpochID(ctx, fsmCaller)
+	if err != nil {
+		return nil, err
+	}
+	plan.Notef(
+		"reward epochs: current=%d, start=%d (history_epochs=%d), full indexing from block %d",
+		currentEpochID, p.startEpochID, historyEpochs, p.fullStartBlock,
+	)
+
+	switch {
+	case p.backfillEvents:
+		plan.Ranges = append(plan.Ranges, ci.EstimateLogRange(core.RangeKindFspEvents, p.eventStartBlock, p.fullStartBlock-1))
+	case !p.haveEventAnchor:
+		plan.Notef("no FSP event backfill: no reward epoch has FSP start data to anchor on")
+	case p.eventStartBlock >= p.fullStartBlock:
+		plan.Notef("no FSP event backfill: event anchor %d is covered by the full range", p.eventStartBlock)
+	default:
+		plan.Notef("no FSP event backfill: events from block %d are already indexed", p.eventStartBlock)
+	}
+
+	lastIndexed := p.latestConfirmedNumber
+	if p.catchupFromBlock <= p.latestConfirmedNumber {
+		plan.Ranges = append(plan.Ranges, ci.EstimateFullRange(p.catchupFromBlock, p.latestConfirmedNumber))
+	}
+	plan.ContinuousFrom = database.ResumeIndex(lastIndexed, states[database.LastIndexed])
+
+	plan.RetentionBoundary, err = fspRetentionBoundary(ctx, fsmCaller, historyEpochs)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
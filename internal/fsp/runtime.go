@@ -9,10 +9,10 @@ import (
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
 
-	systemcontract "github.com/flare-foundation/go-flare-common/pkg/contracts/system"
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 )
@@ -24,11 +24,11 @@ func RunIndexer(
 	ethClient *chain.Client,
 	resolver *contracts.ContractResolver,
 ) error {
-	logger.Infof(
-		"Starting indexer in FSP mode: history_epochs=%d, collect_transactions=%d, collect_logs=%d",
-		cfg.Indexer.HistoryEpochs,
-		len(cfg.Indexer.CollectTransactions),
-		len(cfg.Indexer.CollectLogs),
+	logging.From(ctx, logging.Fsp).Infow(
+		"Starting indexer in FSP mode",
+		"history_epochs", cfg.Indexer.HistoryEpochs,
+		"collect_transactions", len(cfg.Indexer.CollectTransactions),
+		"collect_logs", len(cfg.Indexer.CollectLogs),
 	)
 
 	cIndexer, err := core.NewEngine(cfg, db, ethClient, resolver)
@@ -36,9 +36,52 @@ func RunIndexer(
 		return err
 	}
 
-	ready.SetSynced(false)
+	decoder, err := boff.Retry(
+		ctx,
+		func() (*eventDecoder, error) {
+			return resolveEventDecoder(ctx, resolver)
+		},
+		"resolveEventDecoder",
+	)
+	if err != nil {
+		return errors.Wrap(err, "set up FSP event decoding")
+	}
+	cIndexer.AddSaveHook(decoder.saveHook)
+	cIndexer.AddContractsHook(decoder.resolve(resolver))
+
+	submissions, err := boff.Retry(
+		ctx,
+		func() (*submissionDecoder, error) {
+			return resolveSubmissionDecoder(ctx, resolver)
+		},
+		"resolveSubmissionDecoder",
+	)
+	if err != nil {
+		return errors.Wrap(err, "set up FSP submission decoding")
+	}
+	cIndexer.AddSaveHook(submissions.saveHook)
+	cIndexer.AddContractsHook(submissions.resolve(resolver))
+
+	tl, err := boff.Retry(
+		ctx,
+		func() (*timeline, error) {
+			fsm, err := bindFsm(ctx, cIndexer)
+			if err != nil {
+				return nil, err
+			}
+			return newTimeline(ctx, db, fsm, cfg.Indexer.HistoryEpochs)
+		},
+		"newTimeline",
+	)
+	if err != nil {
+		return errors.Wrap(err, "set up FSP timeline")
+	}
+	cIndexer.AddContractsHook(tl.rebind(cIndexer))
+	go cIndexer.WatchContractRegistry(ctx)
+
+	ready.SetSynced(ctx, false)
 
-	historyLastIndex, err := boff.Retry(
+	historyLastIndex, err := boff.RetryAlways(
 		ctx,
 		func() (uint64, error) {
 			return IndexStartup(ctx, cIndexer)
@@ -49,21 +92,25 @@ func RunIndexer(
 		return errors.Wrap(err, "FSP startup backfill fatal error")
 	}
 
-	fsmAddress, err := cIndexer.ContractResolver().ResolveByName(ctx, fspFsmContractName)
-	if err != nil {
-		return errors.Wrap(err, "resolve FlareSystemsManager for history drop")
-	}
-	fsmCaller, err := systemcontract.NewFlareSystemsManagerCaller(fsmAddress, cIndexer.Client())
+	// After the startup backfill, which hands the decoder the addresses of
+	// earlier deployments.
+	err = boff.RetryNoReturn(
+		ctx,
+		func() error {
+			return decoder.backfill(ctx, db)
+		},
+		"backfillDecodedEvents",
+	)
 	if err != nil {
-		return errors.Wrap(err, "bind FlareSystemsManager caller for history drop")
+		return errors.Wrap(err, "decode stored FSP events")
 	}
 
-	logger.Infof(
+	logging.From(ctx, logging.Fsp).Infof(
 		"Using FSP history drop: history_epochs=%d, retention anchored on the oldest needed epoch's on-chain data",
 		cfg.Indexer.HistoryEpochs,
 	)
 	if cfg.DB.HistoryDrop != nil {
-		logger.Warnf(
+		logging.From(ctx, logging.Fsp).Warnf(
 			"db.history_drop=%d is ignored in FSP mode; retention is derived from history_epochs",
 			*cfg.DB.HistoryDrop,
 		)
@@ -74,16 +121,26 @@ func RunIndexer(
 		database.HistoryDropIntervalCheck,
 		func(ctx context.Context) (uint64, error) {
 			// Retried so a single transient RPC failure among the boundary's
-			// contract reads does not skip a whole drop iteration.
+			// contract reads does not skip a whole drop iteration. The
+			// FlareSystemsManager is bound on each call from the resolver's
+			// cache, so a redeployment is followed.
 			return boff.RetryWithMaxElapsed(ctx, func() (uint64, error) {
-				return fspRetentionBoundary(ctx, fsmCaller, cfg.Indexer.HistoryEpochs)
+				fsm, err := bindFsm(ctx, cIndexer)
+				if err != nil {
+					return 0, err
+				}
+				return fspRetentionBoundary(ctx, fsm, cfg.Indexer.HistoryEpochs)
 			}, "fspRetentionBoundary")
 		},
 	)
 
-	ready.SetSynced(true)
+	go runTimeline(ctx, tl)
+	go runCompletenessCheck(ctx, cIndexer)
 
-	err = boff.RetryNoReturn(
+	ready.SetSynced(ctx, true)
+	status.SetPhase(ctx, status.PhaseContinuous)
+
+	err = boff.RetryAlwaysNoReturn(
 		ctx,
 		func() error {
 			// Re-read progress each attempt so a retry resumes from the
@@ -100,7 +157,7 @@ func RunIndexer(
 		return errors.Wrap(err, "FSP Index continuous fatal error")
 	}
 
-	logger.Infof("Finished FSP indexing")
+	logging.From(ctx, logging.Fsp).Infof("Finished FSP indexing")
 
 	return nil
 }
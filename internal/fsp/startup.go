@@ -6,9 +6,10 @@ import (
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
 
 	systemcontract "github.com/flare-foundation/go-flare-common/pkg/contracts/system"
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 	"github.com/pkg/errors"
 )
 
@@ -25,41 +26,37 @@ const fspFsmContractName = "FlareSystemsManager"
 // event backfill anchored on recorded epoch data.
 const fspTxLookbackSeconds = uint64(60 * 60)
 
-func IndexStartup(ctx context.Context, ci *core.Engine) (uint64, error) {
-	latestConfirmedNumber, latestConfirmedTimestamp, err := ci.FetchLastBlockIndex(ctx)
-	if err != nil {
-		return 0, errors.Wrap(err, "ci.FetchLastBlockIndex")
-	}
+// startupPlan is what IndexStartup does, computed from chain state and the
+// coverage states without writing.
+type startupPlan struct {
+	latestConfirmedNumber    uint64
+	latestConfirmedTimestamp uint64
+	fullStartBlock           uint64
+	startEpochID             uint64
+	eventStartBlock          uint64
+	haveEventAnchor          bool
+	catchupFromBlock         uint64
+	backfillEvents           bool
+}
 
-	fsmAddress, err := ci.ContractResolver().ResolveByName(ctx, fspFsmContractName)
-	if err != nil {
-		return 0, err
-	}
-	fsmCaller, err := systemcontract.NewFlareSystemsManagerCaller(fsmAddress, ci.Client())
+func planStartup(
+	ctx context.Context, ci *core.Engine, fsm fsmReader, states map[database.StateName]database.State,
+) (*startupPlan, error) {
+	latestConfirmedNumber, latestConfirmedTimestamp, err := ci.FetchLastBlockIndex(ctx)
 	if err != nil {
-		return 0, errors.Wrap(err, "bind FlareSystemsManager caller")
+		return nil, errors.Wrap(err, "ci.FetchLastBlockIndex")
 	}
 
 	fullStartBlock, startEpochID, err := resolveFullStartBlock(
-		ctx, ci, fsmCaller, latestConfirmedNumber, latestConfirmedTimestamp,
+		ctx, ci, fsm, latestConfirmedNumber, latestConfirmedTimestamp,
 	)
 	if err != nil {
-		return 0, err
-	}
-
-	eventStartBlock, haveEventAnchor, err := fspEventBackfillAnchor(ctx, fsmCaller, startEpochID)
-	if err != nil {
-		return 0, errors.Wrap(err, "compute FSP event backfill start")
+		return nil, err
 	}
 
-	states, err := database.GetStates(
-		ci.DB().WithContext(ctx),
-		database.BlockFloor,
-		database.LastIndexed,
-		database.LogFloor,
-	)
+	eventStartBlock, haveEventAnchor, err := fspEventBackfillAnchor(ctx, fsm, startEpochID)
 	if err != nil {
-		return 0, errors.Wrap(err, "database.GetStates")
+		return nil, errors.Wrap(err, "compute FSP event backfill start")
 	}
 
 	// Catchup start: continue from where we left off if existing data covers
@@ -80,56 +77,110 @@ func IndexStartup(ctx context.Context, ci *core.Engine) (uint64, error) {
 	backfillEvents := haveEventAnchor && eventStartBlock < fullStartBlock &&
 		(!database.IsSet(firstFspEvent) || firstFspEvent.Index > eventStartBlock)
 
-	logger.Infof(
-		"FSP startup plan: catchup_from=%d, latest_confirmed=%d, backfill_events=%t, event_start=%d",
-		catchupFromBlock,
-		latestConfirmedNumber,
-		backfillEvents,
-		eventStartBlock,
+	return &startupPlan{
+		latestConfirmedNumber:    latestConfirmedNumber,
+		latestConfirmedTimestamp: latestConfirmedTimestamp,
+		fullStartBlock:           fullStartBlock,
+		startEpochID:             startEpochID,
+		eventStartBlock:          eventStartBlock,
+		haveEventAnchor:          haveEventAnchor,
+		catchupFromBlock:         catchupFromBlock,
+		backfillEvents:           backfillEvents,
+	}, nil
+}
+
+func bindFsm(ctx context.Context, ci *core.Engine) (*systemcontract.FlareSystemsManagerCaller, error) {
+	fsmAddress, err := ci.ContractResolver().ResolveByName(ctx, fspFsmContractName)
+	if err != nil {
+		return nil, err
+	}
+	fsmCaller, err := systemcontract.NewFlareSystemsManagerCaller(fsmAddress, ci.Client())
+	if err != nil {
+		return nil, errors.Wrap(err, "bind FlareSystemsManager caller")
+	}
+	return fsmCaller, nil
+}
+
+func IndexStartup(ctx context.Context, ci *core.Engine) (uint64, error) {
+	fsmCaller, err := bindFsm(ctx, ci)
+	if err != nil {
+		return 0, err
+	}
+
+	states, err := database.GetStates(
+		ci.DB().WithContext(ctx),
+		database.BlockFloor,
+		database.LastIndexed,
+		database.LogFloor,
+	)
+	if err != nil {
+		return 0, errors.Wrap(err, "database.GetStates")
+	}
+
+	plan, err := planStartup(ctx, ci, fsmCaller, states)
+	if err != nil {
+		return 0, err
+	}
+
+	deploymentsFrom := plan.fullStartBlock
+	if plan.haveEventAnchor {
+		deploymentsFrom = min(deploymentsFrom, plan.eventStartBlock)
+	}
+	if err := ci.SyncContractDeployments(ctx, deploymentsFrom); err != nil {
+		return 0, err
+	}
+
+	logging.From(ctx, logging.Fsp).Infow(
+		"FSP startup plan",
+		"catchup_from", plan.catchupFromBlock,
+		"latest_confirmed", plan.latestConfirmedNumber,
+		"backfill_events", plan.backfillEvents,
+		"event_start", plan.eventStartBlock,
 	)
 
-	if backfillEvents {
+	if plan.backfillEvents {
+		status.SetPhase(ctx, status.PhaseFspEventBackfill)
 		logAddresses, logTopics, err := resolveFspContractAddresses(ctx, ci.ContractResolver())
 		if err != nil {
 			return 0, err
 		}
-		if err := backfillFspEventLogs(ctx, ci, eventStartBlock, fullStartBlock-1, logAddresses, logTopics); err != nil {
+		if err := backfillFspEventLogs(ctx, ci, plan.eventStartBlock, plan.fullStartBlock-1, logAddresses, logTopics); err != nil {
 			return 0, errors.Wrap(err, "backfill FSP events")
 		}
-		eventStartTimestamp, err := ci.FetchBlockTimestamp(ctx, eventStartBlock)
+		eventStartTimestamp, err := ci.FetchBlockTimestamp(ctx, plan.eventStartBlock)
 		if err != nil {
-			return 0, errors.Wrapf(err, "fetch FSP event-start timestamp for block %d", eventStartBlock)
+			return 0, errors.Wrapf(err, "fetch FSP event-start timestamp for block %d", plan.eventStartBlock)
 		}
-		if err := database.UpdateState(ci.DB(), database.LogFloor, eventStartBlock, eventStartTimestamp); err != nil {
+		if err := database.UpdateState(ci.DB(), database.LogFloor, plan.eventStartBlock, eventStartTimestamp); err != nil {
 			return 0, errors.Wrap(err, "set first FSP event index state")
 		}
-	} else if !haveEventAnchor {
-		logger.Warnf("Skipping FSP event backfill: no reward epoch has FSP start data to anchor on")
-	} else if eventStartBlock >= fullStartBlock {
-		logger.Infof("Skipping FSP event backfill: event window is covered by the full catchup range")
+	} else if !plan.haveEventAnchor {
+		logging.From(ctx, logging.Fsp).Warnf("Skipping FSP event backfill: no reward epoch has FSP start data to anchor on")
+	} else if plan.eventStartBlock >= plan.fullStartBlock {
+		logging.From(ctx, logging.Fsp).Infof("Skipping FSP event backfill: event window is covered by the full catchup range")
 	} else {
-		logger.Infof("Skipping FSP event backfill, already indexed")
+		logging.From(ctx, logging.Fsp).Infof("Skipping FSP event backfill, already indexed")
 	}
 
-	lastIndexed := latestConfirmedNumber
-	if catchupFromBlock <= latestConfirmedNumber {
-		lastIndexed, err = ci.IndexHistory(ctx, catchupFromBlock)
+	lastIndexed := plan.latestConfirmedNumber
+	if plan.catchupFromBlock <= plan.latestConfirmedNumber {
+		lastIndexed, err = ci.IndexHistory(ctx, plan.catchupFromBlock)
 		if err != nil {
 			return 0, errors.Wrap(err, "backfill FSP catchup range")
 		}
 	} else {
-		logger.Infof(
-			"Skipping FSP catchup block backfill: start=%d, latest_confirmed=%d",
-			catchupFromBlock,
-			latestConfirmedNumber,
+		logging.From(ctx, logging.Fsp).Infow(
+			"Skipping FSP catchup block backfill",
+			"start", plan.catchupFromBlock,
+			"latest_confirmed", plan.latestConfirmedNumber,
 		)
 	}
 
-	logger.Infof(
-		"FSP startup backfill complete: target_full_start=%d, target_event_start=%d, last_indexed=%d",
-		fullStartBlock,
-		eventStartBlock,
-		lastIndexed,
+	logging.From(ctx, logging.Fsp).Infow(
+		"FSP startup backfill complete",
+		"target_full_start", plan.fullStartBlock,
+		"target_event_start", plan.eventStartBlock,
+		"last_indexed", lastIndexed,
 	)
 
 	return lastIndexed, nil
@@ -166,7 +217,7 @@ func resolveFullStartBlock(
 		// No epoch has start data yet (an FSM deployment still in its bootstrap
 		// epoch): fall back to the lookback window rather than resolving a zero
 		// start block, which would full-index from genesis.
-		logger.Warnf(
+		logging.From(ctx, logging.Fsp).Warnf(
 			"Current reward epoch %d has no FSP start data yet; falling back to a %ds lookback from the confirmed tip",
 			currentEpochID, fspTxLookbackSeconds,
 		)
@@ -178,7 +229,7 @@ func resolveFullStartBlock(
 		return startBlock, currentEpochID, nil
 	}
 	if startEpochID > desiredEpochID {
-		logger.Errorf(
+		logging.From(ctx, logging.Fsp).Errorf(
 			"history_epochs=%d requests reward epoch %d, but this FSM deployment's start data begins at epoch %d; catching up from there — lower history_epochs to fit the deployment",
 			params.HistoryEpochs, desiredEpochID, startEpochID,
 		)
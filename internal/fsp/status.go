@@ -0,0 +1,79 @@
+package fsp
+
+import (
+	"context"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/contracts"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/status"
+
+	systemcontract "github.com/flare-foundation/go-flare-common/pkg/contracts/system"
+	"github.com/pkg/errors"
+)
+
+// StatusInfo returns a status.FspInfoFunc that recomputes the FSP epoch plan
+// from chain state: the current reward epoch, the start epoch startup
+// resolves for history_epochs, and that epoch's event anchor.
+func StatusInfo(
+	resolver *contracts.ContractResolver,
+	client *chain.Client,
+	historyEpochs uint64,
+) status.FspInfoFunc {
+	return func(ctx context.Context) (*status.FspInfo, error) {
+		fsmAddress, err := resolver.ResolveByName(ctx, fspFsmContractName)
+		if err != nil {
+			return nil, err
+		}
+		fsmCaller, err := systemcontract.NewFlareSystemsManagerCaller(fsmAddress, client)
+		if err != nil {
+			return nil, errors.Wrap(err, "bind FlareSystemsManager caller")
+		}
+
+		return fspStatusInfo(ctx, fsmCaller, historyEpochs)
+	}
+}
+
+func fspStatusInfo(ctx context.Context, fsm fsmReader, historyEpochs uint64) (*status.FspInfo, error) {
+	currentEpochID, err := fspCurrentEpochID(ctx, fsm)
+	if err != nil {
+		return nil, err
+	}
+
+	startEpochID, err := plannedStartEpochID(ctx, fsm, currentEpochID, historyEpochs)
+	if err != nil {
+		return nil, err
+	}
+
+	anchor, ok, err := fspEventAnchor(ctx, fsm, startEpochID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &status.FspInfo{
+		HistoryEpochs:        historyEpochs,
+		CurrentRewardEpoch:   currentEpochID,
+		StartRewardEpoch:     startEpochID,
+		HasEventAnchor:       ok,
+		EventAnchorBlock:     anchor.block,
+		EventAnchorTimestamp: anchor.timestamp,
+	}, nil
+}
+
+// plannedStartEpochID mirrors the epoch choice of resolveFullStartBlock: the
+// current epoch for history_epochs=0 or when no epoch has start data yet,
+// otherwise the oldest epoch with start data from the desired one onward.
+func plannedStartEpochID(ctx context.Context, fsm fsmReader, currentEpochID, historyEpochs uint64) (uint64, error) {
+	if historyEpochs == 0 {
+		return currentEpochID, nil
+	}
+
+	desiredEpochID := historyStartEpochID(currentEpochID, historyEpochs)
+	startEpochID, _, ok, err := resolveStartEpoch(ctx, fsm, desiredEpochID, currentEpochID)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return currentEpochID, nil
+	}
+	return startEpochID, nil
+}
@@ -0,0 +1,283 @@
+package fsp
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/core"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// signingPolicySignInfo and voterRegistrationData alias further anonymous
+// return structs of the generated FlareSystemsManager bindings.
+type signingPolicySignInfo = struct {
+	SigningPolicySignStartTs    uint64
+	SigningPolicySignStartBlock uint64
+	SigningPolicySignEndTs      uint64
+	SigningPolicySignEndBlock   uint64
+}
+
+type voterRegistrationData = struct {
+	VotePowerBlock *big.Int
+	Enabled        bool
+}
+
+// timelineReader is the subset of the FlareSystemsManager bindings the
+// reward epoch and voting round timeline is read from.
+type timelineReader interface {
+	fsmReader
+	GetStartVotingRoundId(opts *bind.CallOpts, rewardEpochID *big.Int) (uint32, error)
+	GetVoterRegistrationData(opts *bind.CallOpts, rewardEpochID *big.Int) (voterRegistrationData, error)
+	GetSigningPolicySignInfo(opts *bind.CallOpts, rewardEpochID *big.Int) (signingPolicySignInfo, error)
+	FirstVotingRoundStartTs(opts *bind.CallOpts) (uint64, error)
+	VotingEpochDurationSeconds(opts *bind.CallOpts) (uint64, error)
+}
+
+// maxTimelineRounds bounds the voting rounds mapped in one iteration, so a
+// long catchup is spread over several.
+const maxTimelineRounds = 1000
+
+// timeline maintains the reward_epochs and voting_rounds tables. fsm is
+// rebound by the rebind hook after the FlareSystemsManager was redeployed.
+type timeline struct {
+	db            *gorm.DB
+	mu            sync.RWMutex
+	fsm           timelineReader
+	historyEpochs uint64
+
+	firstRoundStartTs uint64
+	roundDuration     uint64
+}
+
+func newTimeline(ctx context.Context, db *gorm.DB, fsm timelineReader, historyEpochs uint64) (*timeline, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	firstRoundStartTs, err := fsm.FirstVotingRoundStartTs(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "firstVotingRoundStartTs")
+	}
+	roundDuration, err := fsm.VotingEpochDurationSeconds(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "votingEpochDurationSeconds")
+	}
+	if roundDuration == 0 {
+		return nil, errors.New("votingEpochDurationSeconds returned 0")
+	}
+
+	return &timeline{
+		db:                db,
+		fsm:               fsm,
+		historyEpochs:     historyEpochs,
+		firstRoundStartTs: firstRoundStartTs,
+		roundDuration:     roundDuration,
+	}, nil
+}
+
+// rebind is the core.ContractsHook binding the timeline to the
+// FlareSystemsManager the resolver returns after a redeployment. The voting
+// round timing is the protocol's, so it is not read again.
+func (t *timeline) rebind(ci *core.Engine) core.ContractsHook {
+	return func(ctx context.Context) error {
+		fsm, err := bindFsm(ctx, ci)
+		if err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.fsm = fsm
+		t.mu.Unlock()
+		return nil
+	}
+}
+
+func (t *timeline) reader() timelineReader {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.fsm
+}
+
+// runTimeline updates the timeline once per voting round until ctx is done.
+func runTimeline(ctx context.Context, t *timeline) {
+	interval := time.Duration(t.roundDuration) * time.Second
+	for {
+		if err := t.update(ctx); err != nil {
+			logging.From(ctx, logging.Fsp).Errorw("FSP timeline update error", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (t *timeline) update(ctx context.Context) error {
+	fsm := t.reader()
+	if err := t.updateEpochs(ctx, fsm); err != nil {
+		return err
+	}
+	return t.updateRounds(ctx)
+}
+
+// updateEpochs stores the reward epochs started since the last stored one,
+// from two epochs before the history_epochs window on an empty table, the
+// same epochs whose metadata events are backfilled. A started epoch's
+// timeline is final, so stored rows are never rewritten.
+func (t *timeline) updateEpochs(ctx context.Context, fsm timelineReader) error {
+	currentEpochID, err := fspCurrentEpochID(ctx, fsm)
+	if err != nil {
+		return err
+	}
+
+	db := t.db.WithContext(ctx)
+	from := saturatingSub(historyStartEpochID(currentEpochID, t.historyEpochs), 2)
+	var last []database.RewardEpoch
+	if err := db.Order("reward_epoch_id DESC").Limit(1).Find(&last).Error; err != nil {
+		return errors.Wrap(err, "read last reward epoch")
+	}
+	if len(last) != 0 && last[0].RewardEpochID+1 > from {
+		from = last[0].RewardEpochID + 1
+	}
+
+	for epochID := from; epochID <= currentEpochID; epochID++ {
+		epoch, ok, err := readRewardEpoch(ctx, fsm, epochID)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := db.Clauses(clause.Insert{Modifier: "IGNORE"}).Create(epoch).Error; err != nil {
+			return errors.Wrapf(err, "save reward epoch %d", epochID)
+		}
+	}
+	return nil
+}
+
+// readRewardEpoch reads the timeline of a reward epoch; ok is false when the
+// epoch has not started on this FSM deployment.
+func readRewardEpoch(ctx context.Context, fsm timelineReader, epochID uint64) (*database.RewardEpoch, bool, error) {
+	startInfo, err := epochStartInfo(ctx, fsm, epochID)
+	if err != nil {
+		return nil, false, err
+	}
+	if startInfo.RewardEpochStartTs == 0 {
+		return nil, false, nil
+	}
+
+	opts := &bind.CallOpts{Context: ctx}
+	id := new(big.Int).SetUint64(epochID)
+	raInfo, err := fsm.GetRandomAcquisitionInfo(opts, id)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "getRandomAcquisitionInfo(%d)", epochID)
+	}
+	registration, err := fsm.GetVoterRegistrationData(opts, id)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "getVoterRegistrationData(%d)", epochID)
+	}
+	signInfo, err := fsm.GetSigningPolicySignInfo(opts, id)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "getSigningPolicySignInfo(%d)", epochID)
+	}
+	startRoundID, err := fsm.GetStartVotingRoundId(opts, id)
+	if err != nil {
+		return nil, false, errors.Wrapf(err, "getStartVotingRoundId(%d)", epochID)
+	}
+
+	epoch := &database.RewardEpoch{
+		RewardEpochID:               epochID,
+		StartVotingRoundID:          uint64(startRoundID),
+		StartBlock:                  startInfo.RewardEpochStartBlock,
+		RandomAcquisitionStartBlock: raInfo.RandomAcquisitionStartBlock,
+		RandomAcquisitionEndBlock:   raInfo.RandomAcquisitionEndBlock,
+		SigningPolicySignStartBlock: signInfo.SigningPolicySignStartBlock,
+		SigningPolicySignEndBlock:   signInfo.SigningPolicySignEndBlock,
+		Timestamp:                   startInfo.RewardEpochStartTs,
+	}
+	if registration.VotePowerBlock != nil {
+		epoch.VotePowerBlock = registration.VotePowerBlock.Uint64()
+	}
+	return epoch, true, nil
+}
+
+// updateRounds maps the voting rounds whose whole window lies within the
+// indexed blocks, continuing after the last stored round. Blocks come from
+// the blocks table, so only rounds from first_database_block on are mapped;
+// a round without blocks gets no row.
+func (t *timeline) updateRounds(ctx context.Context) error {
+	db := t.db.WithContext(ctx)
+	states, err := database.GetStates(db, database.BlockFloor, database.LastIndexed)
+	if err != nil {
+		return errors.Wrap(err, "database.GetStates")
+	}
+	floor, last := states[database.BlockFloor], states[database.LastIndexed]
+	if !database.IsSet(floor) || !database.IsSet(last) {
+		return nil
+	}
+
+	next := t.firstRoundFrom(floor.BlockTimestamp)
+	var stored []database.VotingRound
+	if err := db.Order("voting_round_id DESC").Limit(1).Find(&stored).Error; err != nil {
+		return errors.Wrap(err, "read last voting round")
+	}
+	if len(stored) != 0 && stored[0].VotingRoundID+1 > next {
+		next = stored[0].VotingRoundID + 1
+	}
+
+	var rounds []*database.VotingRound
+	for i := 0; i < maxTimelineRounds; i, next = i+1, next+1 {
+		start := t.firstRoundStartTs + next*t.roundDuration
+		end := start + t.roundDuration
+		// Blocks are indexed in order, so the last indexed block reaching the
+		// round's end means all of its blocks are in.
+		if last.BlockTimestamp < end {
+			break
+		}
+
+		var blocks struct {
+			First *uint64
+			Last  *uint64
+		}
+		err := db.Model(&database.Block{}).
+			Select("MIN(number) AS first, MAX(number) AS last").
+			Where("timestamp >= ? AND timestamp < ?", start, end).
+			Scan(&blocks).Error
+		if err != nil {
+			return errors.Wrapf(err, "find blocks of voting round %d", next)
+		}
+		if blocks.First == nil {
+			continue
+		}
+
+		rounds = append(rounds, &database.VotingRound{
+			VotingRoundID: next,
+			FirstBlock:    *blocks.First,
+			LastBlock:     *blocks.Last,
+			Timestamp:     start,
+		})
+	}
+	if len(rounds) == 0 {
+		return nil
+	}
+
+	err = db.Clauses(clause.Insert{Modifier: "IGNORE"}).
+		CreateInBatches(rounds, database.DBTransactionBatchesSize).
+		Error
+	return errors.Wrap(err, "save voting rounds")
+}
+
+// firstRoundFrom returns the first voting round starting at or after
+// timestamp.
+func (t *timeline) firstRoundFrom(timestamp uint64) uint64 {
+	if timestamp <= t.firstRoundStartTs {
+		return 0
+	}
+	return (timestamp - t.firstRoundStartTs + t.roundDuration - 1) / t.roundDuration
+}
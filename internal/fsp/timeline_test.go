@@ -0,0 +1,70 @@
+package fsp
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/stretchr/testify/require"
+)
+
+// The timeline getters of fakeFSM derive their values from the epoch's start
+// data, so a started epoch reads consistently.
+
+func (f *fakeFSM) GetStartVotingRoundId(_ *bind.CallOpts, rewardEpochID *big.Int) (uint32, error) {
+	return uint32(rewardEpochID.Uint64() * 3360), nil
+}
+
+func (f *fakeFSM) GetVoterRegistrationData(_ *bind.CallOpts, rewardEpochID *big.Int) (voterRegistrationData, error) {
+	e := f.epochs[rewardEpochID.Uint64()]
+	return voterRegistrationData{VotePowerBlock: new(big.Int).SetUint64(e.raBlock - 50), Enabled: true}, nil
+}
+
+func (f *fakeFSM) GetSigningPolicySignInfo(_ *bind.CallOpts, rewardEpochID *big.Int) (signingPolicySignInfo, error) {
+	e := f.epochs[rewardEpochID.Uint64()]
+	return signingPolicySignInfo{
+		SigningPolicySignStartBlock: e.raBlock + 10,
+		SigningPolicySignEndBlock:   e.raBlock + 20,
+	}, nil
+}
+
+func (f *fakeFSM) FirstVotingRoundStartTs(_ *bind.CallOpts) (uint64, error) {
+	return 1000, nil
+}
+
+func (f *fakeFSM) VotingEpochDurationSeconds(_ *bind.CallOpts) (uint64, error) {
+	return 90, nil
+}
+
+func TestReadRewardEpoch(t *testing.T) {
+	fsm := &fakeFSM{current: 250, epochs: startedEpochs(223, 250)}
+
+	epoch, ok, err := readRewardEpoch(context.Background(), fsm, 240)
+	require.NoError(t, err)
+	require.True(t, ok)
+	e := fsm.epochs[240]
+	require.Equal(t, uint64(240), epoch.RewardEpochID)
+	require.Equal(t, uint64(240*3360), epoch.StartVotingRoundID)
+	require.Equal(t, e.startBlock, epoch.StartBlock)
+	require.Equal(t, e.startTs, epoch.Timestamp)
+	require.Equal(t, e.raBlock, epoch.RandomAcquisitionStartBlock)
+	require.Equal(t, e.raBlock-50, epoch.VotePowerBlock)
+	require.Equal(t, e.raBlock+10, epoch.SigningPolicySignStartBlock)
+	require.Equal(t, e.raBlock+20, epoch.SigningPolicySignEndBlock)
+
+	_, ok, err = readRewardEpoch(context.Background(), fsm, 222)
+	require.NoError(t, err)
+	require.False(t, ok, "epoch not started on this deployment")
+}
+
+func TestTimelineFirstRoundFrom(t *testing.T) {
+	tl, err := newTimeline(context.Background(), nil, &fakeFSM{}, 0)
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(0), tl.firstRoundFrom(500))
+	require.Equal(t, uint64(0), tl.firstRoundFrom(1000))
+	require.Equal(t, uint64(1), tl.firstRoundFrom(1001))
+	require.Equal(t, uint64(1), tl.firstRoundFrom(1090))
+	require.Equal(t, uint64(2), tl.firstRoundFrom(1091))
+}
@@ -1,41 +1,152 @@
 package health
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
 	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
-
-	"github.com/flare-foundation/go-flare-common/pkg/logger"
 )
 
-const listenAddress = ":8080"
+// extraRoutes are served alongside /health; register them with Handle before
+// calling Start.
+var extraRoutes = make(map[string]http.Handler)
 
-// Start launches an HTTP health endpoint on /health.
-// The endpoint returns:
-//   - 503 while the indexer is still catching up at startup
-//   - 200 once startup backfill is complete and continuous indexing begins
-func Start() {
-	go func() {
-		err := http.ListenAndServe(listenAddress, handler())
-		if err != nil {
-			logger.Errorf("Health server error: %s", err)
+// tracked are the indexers in the process; /health, /livez and /readyz need
+// all of them to pass. Without any, they follow the process-wide flag.
+var tracked []*ready.Flag
+
+// probes holds the probe thresholds, set by Start.
+var probes config.HealthConfig
+
+// Handle registers an additional endpoint on the health server. It must be
+// called before Start.
+func Handle(pattern string, handler http.Handler) {
+	extraRoutes[pattern] = handler
+}
+
+// Track adds one indexer to /health, /livez and /readyz. It must be called
+// before Start.
+func Track(f *ready.Flag) {
+	tracked = append(tracked, f)
+}
+
+// SyncedHandler serves /health of the given indexers: 200 once all of them
+// finished their startup backfill.
+func SyncedHandler(flags ...*ready.Flag) http.Handler {
+	return probeHandler(flags, func(f *ready.Flag, _ time.Time) string {
+		if !f.IsSynced() {
+			return "false"
 		}
-	}()
+		return ""
+	})
+}
 
-	logger.Infof("Health endpoint available at http://0.0.0.0%s/health", listenAddress)
+// LivenessHandler serves /livez of the given indexers: 503 once the indexing
+// loop of one of them has not gone round for liveness_timeout_seconds, i.e.
+// it is wedged and the process should be restarted.
+func LivenessHandler(flags ...*ready.Flag) http.Handler {
+	return probeHandler(flags, liveProblem)
 }
 
-func handler() http.Handler {
-	mux := http.NewServeMux()
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		if !ready.IsSynced() {
-			http.Error(w, "false", http.StatusServiceUnavailable)
+// ReadinessHandler serves /readyz of the given indexers: 200 while every one
+// of them is synced, live, within max_lag_blocks of the chain tip and has
+// committed within commit_timeout_seconds.
+func ReadinessHandler(flags ...*ready.Flag) http.Handler {
+	return probeHandler(flags, readyProblem)
+}
+
+func probeHandler(flags []*ready.Flag, problem func(*ready.Flag, time.Time) string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		now := time.Now()
+		var problems []string
+		for _, f := range flags {
+			if p := problem(f, now); p != "" {
+				problems = append(problems, p)
+			}
+		}
+		if len(problems) > 0 {
+			http.Error(w, strings.Join(problems, "; "), http.StatusServiceUnavailable)
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("true\n"))
 	})
+}
+
+func liveProblem(f *ready.Flag, now time.Time) string {
+	if since, ok := f.SinceHeartbeat(now); ok && since > probes.LivenessTimeout() {
+		return fmt.Sprintf("indexing loop stalled for %s", since.Round(time.Second))
+	}
+	return ""
+}
+
+func readyProblem(f *ready.Flag, now time.Time) string {
+	if !f.IsSynced() {
+		return "startup backfill in progress"
+	}
+	if p := liveProblem(f, now); p != "" {
+		return p
+	}
+	if lag := f.Lag(); probes.MaxLagBlocks > 0 && lag > probes.MaxLagBlocks {
+		return fmt.Sprintf("%d blocks behind the chain tip (max %d)", lag, probes.MaxLagBlocks)
+	}
+	if probes.CommitTimeoutSeconds > 0 {
+		if since, ok := f.SinceCommit(now); ok && since > probes.CommitTimeout() {
+			return fmt.Sprintf("no block committed for %s", since.Round(time.Second))
+		}
+	}
+	return ""
+}
+
+// Start launches the health server on cfg.ListenAddress:
+//   - /health returns 503 while the indexer is still catching up at startup
+//     and 200 once startup backfill is complete and continuous indexing
+//     begins
+//   - /livez returns 503 once the indexing loop is wedged
+//   - /readyz returns 200 only while the indexer is synced and keeping up
+//     with the chain
+func Start(cfg config.HealthConfig) {
+	probes = cfg
+
+	go func() {
+		err := http.ListenAndServe(cfg.ListenAddress, handler())
+		if err != nil {
+			logging.For(logging.Main).Errorw("Health server error", "error", err)
+		}
+	}()
+
+	logging.For(logging.Main).Infof("Health endpoint available at http://%s/health", displayAddress(cfg.ListenAddress))
+}
+
+func handler() http.Handler {
+	flags := tracked
+	if len(flags) == 0 {
+		flags = []*ready.Flag{ready.Process()}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/health", SyncedHandler(flags...))
+	mux.Handle("/livez", LivenessHandler(flags...))
+	mux.Handle("/readyz", ReadinessHandler(flags...))
+
+	for pattern, h := range extraRoutes {
+		mux.Handle(pattern, h)
+	}
 
 	return mux
 }
+
+// displayAddress fills in the wildcard host of a listen address like ":8080".
+func displayAddress(listenAddress string) string {
+	host, port, err := net.SplitHostPort(listenAddress)
+	if err != nil || host != "" {
+		return listenAddress
+	}
+	return net.JoinHostPort("0.0.0.0", port)
+}
@@ -0,0 +1,76 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/ready"
+
+	"github.com/stretchr/testify/require"
+)
+
+func probe(t *testing.T, h http.Handler) (int, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec.Code, rec.Body.String()
+}
+
+func TestProbes(t *testing.T) {
+	probes = config.HealthConfig{MaxLagBlocks: 10, LivenessTimeoutSeconds: 60}
+	t.Cleanup(func() { probes = config.HealthConfig{} })
+
+	f := new(ready.Flag)
+	ctx := ready.WithFlag(context.Background(), f)
+
+	// Catching up: alive, not ready.
+	code, body := probe(t, ReadinessHandler(f))
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Contains(t, body, "startup backfill")
+	code, _ = probe(t, LivenessHandler(f))
+	require.Equal(t, http.StatusOK, code)
+
+	// Synced and within the lag.
+	ready.SetSynced(ctx, true)
+	ready.Heartbeat(ctx)
+	ready.RecordChainTip(ctx, 1010)
+	ready.RecordCommit(ctx, 1000)
+	code, _ = probe(t, ReadinessHandler(f))
+	require.Equal(t, http.StatusOK, code)
+	code, _ = probe(t, SyncedHandler(f))
+	require.Equal(t, http.StatusOK, code)
+
+	// Fallen behind: /health stays up, /readyz goes down.
+	ready.RecordChainTip(ctx, 1011)
+	code, body = probe(t, ReadinessHandler(f))
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Contains(t, body, "11 blocks behind")
+	code, _ = probe(t, SyncedHandler(f))
+	require.Equal(t, http.StatusOK, code)
+
+	// No block committed within the timeout.
+	ready.RecordCommit(ctx, 1011)
+	probes.CommitTimeoutSeconds = 1
+	require.Empty(t, readyProblem(f, time.Now()))
+	require.Contains(t, readyProblem(f, time.Now().Add(2*time.Second)), "no block committed")
+}
+
+func TestLivenessDetectsWedgedLoop(t *testing.T) {
+	probes = config.HealthConfig{LivenessTimeoutSeconds: 60}
+	t.Cleanup(func() { probes = config.HealthConfig{} })
+
+	f := new(ready.Flag)
+	ready.Heartbeat(ready.WithFlag(context.Background(), f))
+
+	require.Empty(t, liveProblem(f, time.Now()))
+	require.Contains(t, liveProblem(f, time.Now().Add(2*time.Minute)), "indexing loop stalled")
+}
+
+func TestDisplayAddress(t *testing.T) {
+	require.Equal(t, "0.0.0.0:8080", displayAddress(":8080"))
+	require.Equal(t, "127.0.0.1:9000", displayAddress("127.0.0.1:9000"))
+}
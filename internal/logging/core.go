@@ -0,0 +1,89 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/flare-foundation/go-flare-common/pkg/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// fieldCore prepares the fields of a line before the encoder sees them.
+// Errors become their message, so a pkg/errors value does not add its stack
+// as an errorVerbose field. In console format (kv) the fields, including
+// those added with With, are rendered into the message as "k=v" pairs,
+// which keeps the line format of the go-flare-common logger.
+type fieldCore struct {
+	zapcore.Core
+	kv     bool
+	fields []zapcore.Field
+}
+
+func (c *fieldCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &fieldCore{Core: c.Core, kv: c.kv, fields: merged}
+}
+
+func (c *fieldCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *fieldCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	// Context fields go last, after the fields of the call itself.
+	all := make([]zapcore.Field, 0, len(fields)+len(c.fields))
+	all = append(append(all, fields...), c.fields...)
+	for i, f := range all {
+		if f.Type == zapcore.ErrorType {
+			if err, ok := f.Interface.(error); ok {
+				all[i] = zap.String(f.Key, err.Error())
+			}
+		}
+	}
+
+	if !c.kv {
+		return c.Core.Write(ent, all)
+	}
+	if len(all) > 0 {
+		ent.Message = ent.Message + ": " + renderFields(all)
+	}
+	return c.Core.Write(ent, nil)
+}
+
+// renderFields formats fields as "k1=v1, k2=v2" in the order given.
+func renderFields(fields []zapcore.Field) string {
+	var b strings.Builder
+	for i, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(f.Key)
+		b.WriteByte('=')
+		fmt.Fprintf(&b, "%v", enc.Fields[f.Key])
+	}
+	return b.String()
+}
+
+var levelColors = map[zapcore.Level]logger.Color{
+	zapcore.FatalLevel: logger.Red,
+	zapcore.ErrorLevel: logger.Orange,
+	zapcore.WarnLevel:  logger.Yellow,
+	zapcore.InfoLevel:  logger.Reset,
+	zapcore.DebugLevel: logger.LightBlue,
+}
+
+// colorLevelEncoder uses the level colors of the go-flare-common logger.
+func colorLevelEncoder(level zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	color, ok := levelColors[level]
+	if !ok {
+		color = logger.Reset
+	}
+	enc.AppendString(color.Wrap(level.CapitalString()))
+}
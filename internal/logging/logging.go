@@ -0,0 +1,285 @@
+// Package logging is the indexer's logger: zap loggers per subsystem, each
+// with its own level, writing either the console format of the
+// go-flare-common logger or JSON lines with typed fields.
+//
+// Log calls pass a short message and key/value pairs:
+//
+//	logging.From(ctx, logging.Engine).Infow("Processed batch", "from", from, "to", to)
+//
+// In console format the pairs are rendered into the message
+// ("Processed batch: from=1, to=2"); in JSON format they are fields of the
+// line. Fields carried by the context (chain name, correlation ID) are added
+// to every line logged through From.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/flare-foundation/go-flare-common/pkg/logger"
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Subsystem names a part of the indexer with its own log level.
+type Subsystem string
+
+const (
+	Engine   Subsystem = "engine"
+	Chain    Subsystem = "chain"
+	Fsp      Subsystem = "fsp"
+	Database Subsystem = "database"
+	// Main is everything else: startup, commands, HTTP endpoints, retries.
+	Main Subsystem = "main"
+)
+
+// Subsystems are the subsystems whose level can be configured.
+var Subsystems = []Subsystem{Engine, Chain, Fsp, Database, Main}
+
+const (
+	FormatConsole = "console"
+	FormatJSON    = "json"
+
+	// CorrelationIDKey is the field identifying one batch or continuous
+	// iteration across its engine, RPC and database log lines.
+	CorrelationIDKey = "correlation_id"
+
+	consoleTimeFormat = "[01-02|15:04:05.000]"
+)
+
+type Config struct {
+	Level       string
+	File        string
+	MaxFileSize int // In megabytes
+	Console     bool
+	Format      string
+	// Levels overrides Level per subsystem.
+	Levels map[Subsystem]string
+}
+
+type loggers struct {
+	levels map[Subsystem]zap.AtomicLevel
+	byName map[Subsystem]*zap.SugaredLogger
+	sync   func() error
+}
+
+var (
+	mu      sync.RWMutex
+	current *loggers
+)
+
+func init() {
+	if err := Setup(Config{Level: "DEBUG", Console: true, Format: FormatConsole}); err != nil {
+		panic(err)
+	}
+}
+
+// Setup replaces the loggers of every subsystem. In JSON format the
+// go-flare-common logger is silenced so that no console-format lines are
+// mixed into the output.
+func Setup(cfg Config) error {
+	format := cfg.Format
+	if format == "" {
+		format = FormatConsole
+	}
+	if format != FormatConsole && format != FormatJSON {
+		return errors.Errorf("invalid log format %q: must be %q or %q", cfg.Format, FormatConsole, FormatJSON)
+	}
+
+	levels := make(map[Subsystem]zap.AtomicLevel, len(Subsystems))
+	for _, s := range Subsystems {
+		level := cfg.Level
+		if override, ok := cfg.Levels[s]; ok {
+			level = override
+		}
+		parsed, err := ParseLevel(level)
+		if err != nil {
+			return errors.Wrapf(err, "log level of %s", s)
+		}
+		levels[s] = zap.NewAtomicLevelAt(parsed)
+	}
+
+	var (
+		outputs []zapcore.WriteSyncer
+		encoder func(console bool) zapcore.Encoder
+	)
+	if cfg.Console {
+		outputs = append(outputs, zapcore.Lock(noSyncWriter{os.Stdout}))
+	}
+	if cfg.File != "" {
+		outputs = append(outputs, zapcore.AddSync(&lumberjack.Logger{Filename: cfg.File, MaxSize: cfg.MaxFileSize}))
+	}
+	if format == FormatJSON {
+		encoder = func(bool) zapcore.Encoder { return jsonEncoder() }
+	} else {
+		encoder = consoleEncoder
+	}
+
+	l := &loggers{levels: levels, byName: make(map[Subsystem]*zap.SugaredLogger, len(Subsystems))}
+	for _, s := range Subsystems {
+		cores := make([]zapcore.Core, len(outputs))
+		for i, out := range outputs {
+			cores[i] = zapcore.NewCore(encoder(cfg.Console && i == 0), out, levels[s])
+		}
+		core := &fieldCore{Core: zapcore.NewTee(cores...), kv: format == FormatConsole}
+		l.byName[s] = zap.New(core, zap.AddCaller(), zap.AddStacktrace(zap.ErrorLevel)).Named(string(s)).Sugar()
+	}
+	l.sync = func() error {
+		for _, out := range outputs {
+			if err := out.Sync(); err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+
+	if format == FormatJSON {
+		logger.Set(logger.Config{Level: cfg.Level})
+	} else {
+		logger.Set(logger.Config{Level: cfg.Level, File: cfg.File, MaxFileSize: cfg.MaxFileSize, Console: cfg.Console})
+	}
+
+	mu.Lock()
+	current = l
+	mu.Unlock()
+	return nil
+}
+
+// ParseLevel parses a zap level name (DEBUG, INFO, WARN, ERROR, ...).
+func ParseLevel(level string) (zapcore.Level, error) {
+	return zapcore.ParseLevel(strings.ToLower(level))
+}
+
+// SetLevel changes the level of a subsystem at runtime.
+func SetLevel(s Subsystem, level string) error {
+	parsed, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	mu.RLock()
+	atom, ok := current.levels[s]
+	mu.RUnlock()
+	if !ok {
+		return errors.Errorf("unknown log subsystem %q", s)
+	}
+	atom.SetLevel(parsed)
+	return nil
+}
+
+// Levels returns the current level of every subsystem.
+func Levels() map[Subsystem]string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	levels := make(map[Subsystem]string, len(current.levels))
+	for s, atom := range current.levels {
+		levels[s] = atom.Level().CapitalString()
+	}
+	return levels
+}
+
+// Enabled reports whether s logs at level, to skip building expensive
+// fields for lines that would be dropped.
+func Enabled(s Subsystem, level zapcore.Level) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.levels[s].Enabled(level)
+}
+
+// For returns the logger of a subsystem.
+func For(s Subsystem) *zap.SugaredLogger {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current.byName[s]
+}
+
+// From returns the logger of a subsystem with the fields carried by ctx.
+func From(ctx context.Context, s Subsystem) *zap.SugaredLogger {
+	log := For(s)
+	if fields, ok := ctx.Value(fieldsKey{}).([]interface{}); ok {
+		return log.With(fields...)
+	}
+	return log
+}
+
+// Sync flushes buffered log output.
+func Sync() {
+	mu.RLock()
+	l := current
+	mu.RUnlock()
+	_ = l.sync()
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a context whose log lines carry the given key/value
+// pairs in addition to those ctx already carries.
+func WithFields(ctx context.Context, keysAndValues ...interface{}) context.Context {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	merged := make([]interface{}, 0, len(fields)+len(keysAndValues))
+	merged = append(merged, fields...)
+	merged = append(merged, keysAndValues...)
+	return context.WithValue(ctx, fieldsKey{}, merged)
+}
+
+// WithCorrelationID returns a context carrying a new random correlation ID.
+func WithCorrelationID(ctx context.Context) context.Context {
+	return WithFields(ctx, CorrelationIDKey, NewCorrelationID())
+}
+
+// CorrelationID returns the correlation ID carried by ctx, or "".
+func CorrelationID(ctx context.Context) string {
+	fields, _ := ctx.Value(fieldsKey{}).([]interface{})
+	id := ""
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i] == CorrelationIDKey {
+			id, _ = fields[i+1].(string)
+		}
+	}
+	return id
+}
+
+// NewCorrelationID returns a random 16-character hex ID.
+func NewCorrelationID() string {
+	var id [8]byte
+	_, _ = rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// consoleEncoder matches the go-flare-common logger: a bracketed time, a
+// colored level on the console and a plain one in files. The subsystem name
+// is left out to keep existing line formats.
+func consoleEncoder(console bool) zapcore.Encoder {
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.EncodeTime = zapcore.TimeEncoderOfLayout(consoleTimeFormat)
+	encoderCfg.NameKey = ""
+	if console {
+		encoderCfg.EncodeLevel = colorLevelEncoder
+	} else {
+		encoderCfg.EncodeLevel = zapcore.CapitalLevelEncoder
+	}
+	return zapcore.NewConsoleEncoder(encoderCfg)
+}
+
+func jsonEncoder() zapcore.Encoder {
+	encoderCfg := zap.NewProductionEncoderConfig()
+	encoderCfg.TimeKey = "time"
+	encoderCfg.NameKey = "subsystem"
+	encoderCfg.EncodeTime = zapcore.ISO8601TimeEncoder
+	return zapcore.NewJSONEncoder(encoderCfg)
+}
+
+type noSyncWriter struct {
+	*os.File
+}
+
+func (noSyncWriter) Sync() error {
+	return nil
+}
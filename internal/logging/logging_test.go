@@ -0,0 +1,97 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+// setupFile points the loggers at a file in a temporary directory and
+// returns a func reading the lines written so far.
+func setupFile(t *testing.T, cfg Config) func() []string {
+	t.Helper()
+	t.Cleanup(func() {
+		require.NoError(t, Setup(Config{Level: "DEBUG", Console: true}))
+	})
+
+	cfg.File = filepath.Join(t.TempDir(), "indexer.log")
+	require.NoError(t, Setup(cfg))
+
+	return func() []string {
+		Sync()
+		content, err := os.ReadFile(cfg.File)
+		require.NoError(t, err)
+		return strings.Split(strings.TrimSpace(string(content)), "\n")
+	}
+}
+
+func TestConsoleFormat(t *testing.T) {
+	lines := setupFile(t, Config{Level: "INFO"})
+
+	ctx := WithFields(context.Background(), "chain", "flare")
+	From(ctx, Engine).Infow("Processed batch", "from", 1, "to", 2)
+	For(Database).Errorw("History drop error", "error", errors.New("lock wait timeout"))
+
+	// Errors are followed by the stack of the call site, as with the
+	// go-flare-common logger.
+	got := lines()
+	require.Greater(t, len(got), 2)
+	require.Contains(t, got[0], "\tINFO\t")
+	require.Contains(t, got[0], "Processed batch: from=1, to=2, chain=flare")
+	// The error is its message only: no errorVerbose stack in the line.
+	require.Contains(t, got[1], "History drop error: error=lock wait timeout")
+	require.NotContains(t, got[1], "errorVerbose")
+}
+
+func TestJSONFormat(t *testing.T) {
+	lines := setupFile(t, Config{Level: "INFO", Format: FormatJSON})
+
+	ctx := WithCorrelationID(WithFields(context.Background(), "chain", "flare"))
+	From(ctx, Engine).Infow("Processed batch", "from", 1, "error", errors.New("boom"))
+
+	got := lines()
+	require.Len(t, got, 1)
+
+	var line map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(got[0]), &line))
+	require.Equal(t, "Processed batch", line["msg"])
+	require.Equal(t, "engine", line["subsystem"])
+	require.Equal(t, "info", line["level"])
+	require.EqualValues(t, 1, line["from"])
+	require.Equal(t, "boom", line["error"])
+	require.Equal(t, "flare", line["chain"])
+	require.Len(t, line[CorrelationIDKey], 16)
+}
+
+func TestSubsystemLevels(t *testing.T) {
+	lines := setupFile(t, Config{Level: "INFO", Levels: map[Subsystem]string{Chain: "DEBUG"}})
+
+	For(Chain).Debugw("RPC call", "method", "eth_getLogs")
+	For(Engine).Debugw("Fetched blocks")
+	require.True(t, Enabled(Chain, zapcore.DebugLevel))
+	require.False(t, Enabled(Engine, zapcore.DebugLevel))
+
+	require.NoError(t, SetLevel(Engine, "DEBUG"))
+	For(Engine).Debugw("Fetched logs")
+	require.Equal(t, "DEBUG", Levels()[Engine])
+
+	got := lines()
+	require.Len(t, got, 2)
+	require.Contains(t, got[0], "RPC call: method=eth_getLogs")
+	require.Contains(t, got[1], "Fetched logs")
+
+	require.Error(t, SetLevel("rpc", "DEBUG"))
+	require.Error(t, SetLevel(Engine, "LOUD"))
+}
+
+func TestSetupRejects(t *testing.T) {
+	require.Error(t, Setup(Config{Level: "INFO", Format: "logfmt"}))
+	require.Error(t, Setup(Config{Level: "INFO", Levels: map[Subsystem]string{Fsp: "LOUD"}}))
+}
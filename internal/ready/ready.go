@@ -1,13 +1,110 @@
 package ready
 
-import "sync/atomic"
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
 
-var synced atomic.Bool
+// Flag records whether one indexer has finished its startup backfill and how
+// its indexing keeps up since: the chain tip it last saw, the last block it
+// committed and when, and when its indexing loop last went round. Like
+// status.Progress it travels in the context (WithFlag); without one the
+// process-wide flag is used, which is all a single-chain indexer needs.
+type Flag struct {
+	synced atomic.Bool
 
-func SetSynced(value bool) {
-	synced.Store(value)
+	chainTip    atomic.Uint64
+	lastIndexed atomic.Uint64
+	// Unix nanoseconds; zero until the first commit or loop round.
+	committedAt atomic.Int64
+	heartbeat   atomic.Int64
 }
 
+func (f *Flag) Set(value bool) {
+	f.synced.Store(value)
+}
+
+func (f *Flag) IsSynced() bool {
+	return f.synced.Load()
+}
+
+// Lag is the number of blocks between the chain tip last seen and the last
+// committed block.
+func (f *Flag) Lag() uint64 {
+	tip, indexed := f.chainTip.Load(), f.lastIndexed.Load()
+	if tip <= indexed {
+		return 0
+	}
+	return tip - indexed
+}
+
+// SinceCommit is the time since the last commit, and false before the first.
+func (f *Flag) SinceCommit(now time.Time) (time.Duration, bool) {
+	return since(&f.committedAt, now)
+}
+
+// SinceHeartbeat is the time since the indexing loop last went round, and
+// false before it started.
+func (f *Flag) SinceHeartbeat(now time.Time) (time.Duration, bool) {
+	return since(&f.heartbeat, now)
+}
+
+func since(t *atomic.Int64, now time.Time) (time.Duration, bool) {
+	nanos := t.Load()
+	if nanos == 0 {
+		return 0, false
+	}
+	return now.Sub(time.Unix(0, nanos)), true
+}
+
+var processFlag Flag
+
+type flagKey struct{}
+
+// WithFlag returns a context whose indexer reports its readiness to f.
+func WithFlag(ctx context.Context, f *Flag) context.Context {
+	return context.WithValue(ctx, flagKey{}, f)
+}
+
+func flagFrom(ctx context.Context) *Flag {
+	if f, ok := ctx.Value(flagKey{}).(*Flag); ok {
+		return f
+	}
+	return &processFlag
+}
+
+// SetSynced sets the flag of the indexer running under ctx.
+func SetSynced(ctx context.Context, value bool) {
+	flagFrom(ctx).Set(value)
+}
+
+// RecordChainTip records the latest confirmed chain block seen by the
+// indexer running under ctx.
+func RecordChainTip(ctx context.Context, block uint64) {
+	flagFrom(ctx).chainTip.Store(block)
+}
+
+// RecordCommit records that the indexer running under ctx committed blocks
+// up to block.
+func RecordCommit(ctx context.Context, block uint64) {
+	f := flagFrom(ctx)
+	f.lastIndexed.Store(block)
+	f.committedAt.Store(time.Now().UnixNano())
+}
+
+// Heartbeat records that the indexing loop running under ctx went round,
+// whether or not it found new blocks.
+func Heartbeat(ctx context.Context) {
+	flagFrom(ctx).heartbeat.Store(time.Now().UnixNano())
+}
+
+// Process returns the process-wide flag.
+func Process() *Flag {
+	return &processFlag
+}
+
+// IsSynced reports the process-wide flag.
 func IsSynced() bool {
-	return synced.Load()
+	return processFlag.IsSynced()
 }
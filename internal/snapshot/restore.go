@@ -0,0 +1,283 @@
+package snapshot
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Verify reads a whole archive and checks that it is complete and
+// consistent with its states: the trailer row counts match, and every row
+// tied to the indexed range lies within the coverage the states claim
+// (block floor or log floor up to last_database_block).
+func Verify(r io.Reader) (*Header, *Trailer, error) {
+	rd, err := newReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err := rd.header()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cov, err := newCoverage(header.States)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	counts := make(map[string]uint64, len(tables))
+	for {
+		rec, err := rd.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if rec.Trailer != nil {
+			for _, t := range tables {
+				name := t.name()
+				if counts[name] != rec.Trailer.Rows[name] {
+					return nil, nil, errors.Errorf(
+						"archive has %d %s rows, trailer records %d", counts[name], name, rec.Trailer.Rows[name],
+					)
+				}
+			}
+			return header, rec.Trailer, nil
+		}
+
+		if err := cov.check(rec); err != nil {
+			return nil, nil, err
+		}
+		counts[rec.Table]++
+	}
+}
+
+// coverage is the block range the archive's states claim.
+type coverage struct {
+	lastIndexed uint64
+	blockFloor  uint64
+	logFloor    uint64
+}
+
+func newCoverage(states []database.State) (*coverage, error) {
+	byName := make(map[database.StateName]database.State, len(states))
+	for _, s := range states {
+		byName[database.StateName(s.Name)] = s
+	}
+
+	lastIndexed := byName[database.LastIndexed]
+	if !database.IsSet(lastIndexed) {
+		return nil, errors.New("snapshot has no last_database_block state")
+	}
+
+	cov := &coverage{lastIndexed: lastIndexed.Index}
+	if floor := byName[database.BlockFloor]; database.IsSet(floor) {
+		cov.blockFloor = floor.Index
+	}
+	cov.logFloor = cov.blockFloor
+	if floor := byName[database.LogFloor]; database.IsSet(floor) {
+		cov.logFloor = floor.Index
+	}
+	return cov, nil
+}
+
+func (c *coverage) check(rec *record) error {
+	t, err := lookupTable(rec.Table)
+	if err != nil {
+		return err
+	}
+	number, kind, err := t.block(rec.Row)
+	if err != nil {
+		return err
+	}
+
+	var floor uint64
+	switch kind {
+	case noFloor:
+		return nil
+	case blockFloor:
+		floor = c.blockFloor
+	case logFloor:
+		floor = c.logFloor
+	}
+	if number < floor || number > c.lastIndexed {
+		return errors.Errorf(
+			"%s row at block %d is outside the covered range %d-%d", rec.Table, number, floor, c.lastIndexed,
+		)
+	}
+	return nil
+}
+
+// RestoreOptions relax the checks of Restore.
+type RestoreOptions struct {
+	// AllowConfigChange restores a snapshot taken with other transaction
+	// and log filters.
+	AllowConfigChange bool
+}
+
+// Restore loads an archive into an empty, migrated database. open is called
+// twice: the archive is verified completely before the first row is written.
+// Rows are inserted first and the states last, so an interrupted restore
+// leaves no states and the next indexer start does not resume from it; drop
+// the tables and restore again in that case. Afterwards the indexer resumes
+// from the restored last_database_block like after a restart.
+func Restore(
+	ctx context.Context, db *gorm.DB, open func() (io.ReadCloser, error), expect Meta, opts RestoreOptions,
+) (*Header, error) {
+	db = db.WithContext(ctx)
+
+	header, trailer, err := verifyArchive(open)
+	if err != nil {
+		return nil, errors.Wrap(err, "verify snapshot")
+	}
+	if err := checkHeader(header, expect, opts); err != nil {
+		return nil, err
+	}
+	if err := checkEmpty(db); err != nil {
+		return nil, err
+	}
+
+	f, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rd, err := newReader(f)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := rd.header(); err != nil {
+		return nil, err
+	}
+
+	w := newBatchWriter(db)
+	start := time.Now()
+	for {
+		rec, err := rd.next()
+		if err != nil {
+			return nil, err
+		}
+		if rec.Trailer != nil {
+			break
+		}
+		if err := w.add(rec); err != nil {
+			return nil, err
+		}
+	}
+	if err := w.flush(); err != nil {
+		return nil, err
+	}
+
+	if len(header.States) > 0 {
+		if err := db.Create(&header.States).Error; err != nil {
+			return nil, errors.Wrap(err, "restore states")
+		}
+	}
+
+	logging.From(ctx, logging.Main).Infow(
+		"Snapshot restored",
+		"blocks", trailer.Rows[tableBlocks],
+		"transactions", trailer.Rows[tableTransactions],
+		"logs", trailer.Rows[tableLogs],
+		"internal_calls", trailer.Rows[tableInternalCalls],
+		"submission_payloads", trailer.Rows[tableSubmissionPayloads],
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	return header, nil
+}
+
+func verifyArchive(open func() (io.ReadCloser, error)) (*Header, *Trailer, error) {
+	f, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	return Verify(f)
+}
+
+func checkHeader(header *Header, expect Meta, opts RestoreOptions) error {
+	if header.ChainID != expect.ChainID {
+		return errors.Errorf("snapshot is of chain ID %s, the node is on chain ID %s", header.ChainID, expect.ChainID)
+	}
+	if header.Mode != expect.Mode {
+		return errors.Errorf("snapshot was taken in %q mode, the indexer runs in %q mode", header.Mode, expect.Mode)
+	}
+	if latest := database.LatestSchemaVersion(); header.SchemaVersion != latest {
+		return errors.Errorf(
+			"snapshot has schema version %d, this indexer %d; restore it with a matching indexer version",
+			header.SchemaVersion, latest,
+		)
+	}
+	if header.ConfigHash != expect.ConfigHash && !opts.AllowConfigChange {
+		return errors.New(
+			"snapshot was taken with different collect_transactions/collect_logs settings; " +
+				"its data would not match what this indexer collects (override with --allow-config-change)",
+		)
+	}
+	return nil
+}
+
+func checkEmpty(db *gorm.DB) error {
+	models := []interface{}{&database.State{}}
+	for _, t := range tables {
+		models = append(models, t.model())
+	}
+	for _, model := range models {
+		var count int64
+		if err := db.Model(model).Limit(1).Count(&count).Error; err != nil {
+			return errors.Wrapf(err, "check %T", model)
+		}
+		if count > 0 {
+			return errors.Errorf("database is not empty (%T rows present); restore into an empty database", model)
+		}
+	}
+	return nil
+}
+
+// batchWriter inserts archive rows in batches of
+// database.DBTransactionBatchesSize.
+type batchWriter struct {
+	db    *gorm.DB
+	table string
+	rows  buffer
+}
+
+func newBatchWriter(db *gorm.DB) *batchWriter {
+	return &batchWriter{db: db}
+}
+
+func (w *batchWriter) add(rec *record) error {
+	if rec.Table != w.table {
+		// Tables follow each other in the archive; finish the previous one
+		// first so rows never go in before the rows they reference.
+		if err := w.flush(); err != nil {
+			return err
+		}
+		t, err := lookupTable(rec.Table)
+		if err != nil {
+			return err
+		}
+		w.table, w.rows = rec.Table, t.newBuffer()
+	}
+	if err := w.rows.add(rec.Row); err != nil {
+		return errors.Wrapf(err, "decode %s row", rec.Table)
+	}
+
+	if w.rows.len() >= database.DBTransactionBatchesSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func (w *batchWriter) flush() error {
+	if w.rows == nil {
+		return nil
+	}
+	return w.rows.insert(w.db)
+}
@@ -0,0 +1,160 @@
+// Package snapshot writes and restores portable archives of an indexer
+// database, so a new indexer can start from another one's data instead of
+// indexing and backfilling from scratch.
+//
+// An archive is a gzip-compressed stream of JSON lines: a header with the
+// chain ID, collection config hash, schema version and the states rows,
+// then every row of the indexed tables, table by table (see tables), then a
+// trailer with the row counts. Rows are the entities' hex strings, so an archive restores
+// into either storage format.
+package snapshot
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	// formatVersion is bumped when the archive layout changes. Version 2
+	// added the internal call and FSP tables.
+	formatVersion = 2
+
+	readBatchSize = 5000
+)
+
+// Meta identifies the indexer a snapshot belongs to.
+type Meta struct {
+	ChainID    string `json:"chain_id"`
+	ConfigHash string `json:"config_hash"`
+	Mode       string `json:"mode"`
+}
+
+type Header struct {
+	FormatVersion int `json:"format_version"`
+	Meta
+	SchemaVersion uint64           `json:"schema_version"`
+	CreatedAt     time.Time        `json:"created_at"`
+	States        []database.State `json:"states"`
+}
+
+type Trailer struct {
+	Rows map[string]uint64 `json:"rows"`
+}
+
+// record is one line of the archive; exactly one field is set.
+type record struct {
+	Header  *Header         `json:"header,omitempty"`
+	Table   string          `json:"table,omitempty"`
+	Row     json.RawMessage `json:"row,omitempty"`
+	Trailer *Trailer        `json:"trailer,omitempty"`
+}
+
+// Create writes a snapshot of db to w. All rows are read in one read-only
+// REPEATABLE READ transaction, so the archive is consistent with its states
+// even while an indexer keeps writing.
+func Create(ctx context.Context, db *gorm.DB, w io.Writer, meta Meta) (*Trailer, error) {
+	tx := db.WithContext(ctx).Begin(&sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if tx.Error != nil {
+		return nil, errors.Wrap(tx.Error, "begin snapshot transaction")
+	}
+	defer tx.Rollback()
+
+	migration, err := database.GetMigrationStatus(tx)
+	if err != nil {
+		return nil, err
+	}
+	if migration.Current != migration.Latest {
+		return nil, errors.Errorf(
+			"database is at schema version %d, this indexer at %d; migrate before creating a snapshot",
+			migration.Current, migration.Latest,
+		)
+	}
+
+	var states []database.State
+	if err := tx.Order("id ASC").Find(&states).Error; err != nil {
+		return nil, errors.Wrap(err, "read states")
+	}
+
+	gz := gzip.NewWriter(w)
+	enc := json.NewEncoder(gz)
+
+	err = enc.Encode(record{Header: &Header{
+		FormatVersion: formatVersion,
+		Meta:          meta,
+		SchemaVersion: migration.Current,
+		CreatedAt:     time.Now().UTC(),
+		States:        states,
+	}})
+	if err != nil {
+		return nil, errors.Wrap(err, "write header")
+	}
+
+	trailer := &Trailer{Rows: make(map[string]uint64, len(tables))}
+	for _, t := range tables {
+		start := time.Now()
+		n, err := t.dump(tx, enc)
+		if err != nil {
+			return nil, err
+		}
+		trailer.Rows[t.name()] = n
+		logging.From(ctx, logging.Main).Infow("Snapshot table written", "table", t.name(), "rows", n, "duration_ms", time.Since(start).Milliseconds())
+	}
+
+	if err := enc.Encode(record{Trailer: trailer}); err != nil {
+		return nil, errors.Wrap(err, "write trailer")
+	}
+	if err := gz.Close(); err != nil {
+		return nil, errors.Wrap(err, "finish archive")
+	}
+	return trailer, nil
+}
+
+// reader iterates the records of an archive.
+type reader struct {
+	gz  *gzip.Reader
+	dec *json.Decoder
+}
+
+func newReader(r io.Reader) (*reader, error) {
+	gz, err := gzip.NewReader(bufio.NewReader(r))
+	if err != nil {
+		return nil, errors.Wrap(err, "open archive")
+	}
+	return &reader{gz: gz, dec: json.NewDecoder(gz)}, nil
+}
+
+func (r *reader) next() (*record, error) {
+	var rec record
+	if err := r.dec.Decode(&rec); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("archive is truncated: no trailer")
+		}
+		return nil, errors.Wrap(err, "read archive")
+	}
+	return &rec, nil
+}
+
+func (r *reader) header() (*Header, error) {
+	rec, err := r.next()
+	if err != nil {
+		return nil, err
+	}
+	if rec.Header == nil {
+		return nil, errors.New("archive does not start with a header")
+	}
+	if rec.Header.FormatVersion != formatVersion {
+		return nil, errors.Errorf("unsupported snapshot format version %d", rec.Header.FormatVersion)
+	}
+	return rec.Header, nil
+}
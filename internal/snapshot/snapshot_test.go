@@ -0,0 +1,127 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"testing"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func archive(t *testing.T, records ...record) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	enc := json.NewEncoder(gz)
+	for _, rec := range records {
+		require.NoError(t, enc.Encode(rec))
+	}
+	require.NoError(t, gz.Close())
+	return &buf
+}
+
+func rowRecord(t *testing.T, table string, row any) record {
+	raw, err := json.Marshal(row)
+	require.NoError(t, err)
+	return record{Table: table, Row: raw}
+}
+
+func TestVerify(t *testing.T) {
+	header := record{Header: &Header{
+		FormatVersion: formatVersion,
+		States: []database.State{
+			{Name: string(database.LastIndexed), Index: 200},
+			{Name: string(database.BlockFloor), Index: 100},
+			{Name: string(database.LogFloor), Index: 50},
+		},
+	}}
+	block := rowRecord(t, tableBlocks, database.Block{Number: 150})
+	log := rowRecord(t, tableLogs, database.Log{BlockNumber: 60})
+	trailer := record{Trailer: &Trailer{Rows: map[string]uint64{tableBlocks: 1, tableLogs: 1}}}
+
+	t.Run("consistent archive", func(t *testing.T) {
+		_, got, err := Verify(archive(t, header, block, log, trailer))
+		require.NoError(t, err)
+		require.Equal(t, trailer.Trailer, got)
+	})
+
+	t.Run("truncated archive", func(t *testing.T) {
+		_, _, err := Verify(archive(t, header, block, log))
+		require.ErrorContains(t, err, "truncated")
+	})
+
+	t.Run("row count mismatch", func(t *testing.T) {
+		_, _, err := Verify(archive(t, header, block, trailer))
+		require.ErrorContains(t, err, "trailer records")
+	})
+
+	t.Run("block below the block floor", func(t *testing.T) {
+		below := rowRecord(t, tableTransactions, database.Transaction{BlockNumber: 60})
+		_, _, err := Verify(archive(t, header, block, below, log, trailer))
+		require.ErrorContains(t, err, "outside the covered range")
+	})
+
+	t.Run("block above last_database_block", func(t *testing.T) {
+		above := rowRecord(t, tableBlocks, database.Block{Number: 201})
+		_, _, err := Verify(archive(t, header, above, log, trailer))
+		require.ErrorContains(t, err, "outside the covered range")
+	})
+
+	t.Run("derived tables", func(t *testing.T) {
+		call := rowRecord(t, tableInternalCalls, database.InternalCall{BlockNumber: 150})
+		offer := rowRecord(t, tableRewardOffers, database.RewardOffer{BlockNumber: 60})
+		epoch := rowRecord(t, tableRewardEpochs, database.RewardEpoch{StartBlock: 10})
+		rows := record{Trailer: &Trailer{Rows: map[string]uint64{
+			tableBlocks: 1, tableLogs: 1, tableInternalCalls: 1, tableRewardOffers: 1, tableRewardEpochs: 1,
+		}}}
+		_, _, err := Verify(archive(t, header, block, log, call, offer, epoch, rows))
+		require.NoError(t, err, "reward epochs are not tied to the indexed range")
+
+		below := rowRecord(t, tableInternalCalls, database.InternalCall{BlockNumber: 60})
+		_, _, err = Verify(archive(t, header, block, log, below, offer, epoch, rows))
+		require.ErrorContains(t, err, "internal_calls row at block 60 is outside the covered range")
+
+		_, _, err = Verify(archive(t, header, block, log, call, offer, rows))
+		require.ErrorContains(t, err, "archive has 0 reward_epochs rows")
+	})
+
+	t.Run("contract deployments", func(t *testing.T) {
+		seed := rowRecord(t, tableContractDeployments, database.ContractDeployment{ContractName: "VoterRegistry"})
+		later := rowRecord(t, tableContractDeployments, database.ContractDeployment{ContractName: "VoterRegistry", ActiveFromBlock: 500})
+		rows := record{Trailer: &Trailer{Rows: map[string]uint64{tableBlocks: 1, tableLogs: 1, tableContractDeployments: 2}}}
+		_, _, err := Verify(archive(t, header, block, log, seed, later, rows))
+		require.NoError(t, err, "deployments are not tied to the indexed range")
+	})
+
+	t.Run("unknown table", func(t *testing.T) {
+		_, _, err := Verify(archive(t, header, block, log, record{Table: "states", Row: []byte("{}")}, trailer))
+		require.ErrorContains(t, err, "unexpected archive record")
+	})
+
+	t.Run("no last_database_block", func(t *testing.T) {
+		empty := record{Header: &Header{FormatVersion: formatVersion}}
+		_, _, err := Verify(archive(t, empty, trailer))
+		require.ErrorContains(t, err, "last_database_block")
+	})
+}
+
+func TestCheckHeader(t *testing.T) {
+	meta := Meta{ChainID: "14", ConfigHash: "abc", Mode: "fsp"}
+	header := &Header{FormatVersion: formatVersion, Meta: meta, SchemaVersion: database.LatestSchemaVersion()}
+	require.NoError(t, checkHeader(header, meta, RestoreOptions{}))
+
+	other := *header
+	other.ChainID = "19"
+	require.ErrorContains(t, checkHeader(&other, meta, RestoreOptions{}), "chain ID")
+
+	other = *header
+	other.SchemaVersion++
+	require.ErrorContains(t, checkHeader(&other, meta, RestoreOptions{}), "schema version")
+
+	other = *header
+	other.ConfigHash = "def"
+	require.Error(t, checkHeader(&other, meta, RestoreOptions{}))
+	require.NoError(t, checkHeader(&other, meta, RestoreOptions{AllowConfigChange: true}))
+}
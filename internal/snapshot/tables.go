@@ -0,0 +1,181 @@
+package snapshot
+
+import (
+	"encoding/json"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Names of the archived tables, as recorded in the archive regardless of
+// db.table_prefix.
+const (
+	tableBlocks              = "blocks"
+	tableTransactions        = "transactions"
+	tableLogs                = "logs"
+	tableInternalCalls       = "internal_calls"
+	tableSubmissionPayloads  = "submission_payloads"
+	tableSigningPolicies     = "signing_policies"
+	tableSigningPolicyVoters = "signing_policy_voters"
+	tableVoterRegistrations  = "voter_registrations"
+	tableRewardOffers        = "reward_offers"
+	tableRewardEpochs        = "reward_epochs"
+	tableVotingRounds        = "voting_rounds"
+	tableContractDeployments = "contract_deployments"
+)
+
+// floor is the coverage floor the block numbers of a table's rows are
+// checked against.
+type floor int
+
+const (
+	// noFloor tables describe the chain rather than the indexed range, and
+	// their rows are not checked.
+	noFloor floor = iota
+	blockFloor
+	logFloor
+)
+
+// table is an archived table with its entity.
+type table interface {
+	name() string
+	model() interface{}
+	dump(tx *gorm.DB, enc *json.Encoder) (uint64, error)
+	// block returns the block number of an archived row and the floor it
+	// is checked against.
+	block(raw json.RawMessage) (uint64, floor, error)
+	newBuffer() buffer
+}
+
+// buffer collects decoded rows of one table for a batch insert.
+type buffer interface {
+	add(raw json.RawMessage) error
+	len() int
+	insert(db *gorm.DB) error
+}
+
+// Tables in archive (and restore) order: transactions come before the logs,
+// internal calls and submission payloads referencing them.
+var tables = []table{
+	tableOf[database.Block]{tableBlocks, blockFloor,
+		func(r *database.Block) uint64 { return r.ID }, func(r *database.Block) uint64 { return r.Number }},
+	tableOf[database.Transaction]{tableTransactions, blockFloor,
+		func(r *database.Transaction) uint64 { return r.ID }, func(r *database.Transaction) uint64 { return r.BlockNumber }},
+	tableOf[database.Log]{tableLogs, logFloor,
+		func(r *database.Log) uint64 { return r.ID }, func(r *database.Log) uint64 { return r.BlockNumber }},
+	tableOf[database.InternalCall]{tableInternalCalls, blockFloor,
+		func(r *database.InternalCall) uint64 { return r.ID }, func(r *database.InternalCall) uint64 { return r.BlockNumber }},
+	tableOf[database.SubmissionPayload]{tableSubmissionPayloads, blockFloor,
+		func(r *database.SubmissionPayload) uint64 { return r.ID }, func(r *database.SubmissionPayload) uint64 { return r.BlockNumber }},
+	tableOf[database.SigningPolicy]{tableSigningPolicies, logFloor,
+		func(r *database.SigningPolicy) uint64 { return r.ID }, func(r *database.SigningPolicy) uint64 { return r.BlockNumber }},
+	tableOf[database.SigningPolicyVoter]{tableSigningPolicyVoters, logFloor,
+		func(r *database.SigningPolicyVoter) uint64 { return r.ID }, func(r *database.SigningPolicyVoter) uint64 { return r.BlockNumber }},
+	tableOf[database.VoterRegistration]{tableVoterRegistrations, logFloor,
+		func(r *database.VoterRegistration) uint64 { return r.ID }, func(r *database.VoterRegistration) uint64 { return r.BlockNumber }},
+	tableOf[database.RewardOffer]{tableRewardOffers, logFloor,
+		func(r *database.RewardOffer) uint64 { return r.ID }, func(r *database.RewardOffer) uint64 { return r.BlockNumber }},
+	tableOf[database.RewardEpoch]{tableRewardEpochs, noFloor,
+		func(r *database.RewardEpoch) uint64 { return r.ID }, nil},
+	tableOf[database.VotingRound]{tableVotingRounds, noFloor,
+		func(r *database.VotingRound) uint64 { return r.ID }, nil},
+	tableOf[database.ContractDeployment]{tableContractDeployments, noFloor,
+		func(r *database.ContractDeployment) uint64 { return r.ID }, nil},
+}
+
+func lookupTable(name string) (table, error) {
+	for _, t := range tables {
+		if t.name() == name {
+			return t, nil
+		}
+	}
+	return nil, errors.Errorf("unexpected archive record for table %q", name)
+}
+
+// tableOf is the table of entity E. id returns the BaseEntity ID used to
+// page through the table, blockNumber the block checked against floor.
+type tableOf[E any] struct {
+	tableName   string
+	floor       floor
+	id          func(*E) uint64
+	blockNumber func(*E) uint64
+}
+
+func (t tableOf[E]) name() string { return t.tableName }
+
+func (t tableOf[E]) model() interface{} { return new(E) }
+
+func (t tableOf[E]) dump(tx *gorm.DB, enc *json.Encoder) (uint64, error) {
+	var (
+		lastID uint64
+		count  uint64
+	)
+	for {
+		var rows []E
+		err := tx.Where("id > ?", lastID).Order("id ASC").Limit(readBatchSize).Find(&rows).Error
+		if err != nil {
+			return 0, errors.Wrapf(err, "read %s", t.tableName)
+		}
+
+		for i := range rows {
+			row, err := json.Marshal(&rows[i])
+			if err != nil {
+				return 0, errors.Wrapf(err, "encode %s row", t.tableName)
+			}
+			if err := enc.Encode(record{Table: t.tableName, Row: row}); err != nil {
+				return 0, errors.Wrapf(err, "write %s row", t.tableName)
+			}
+		}
+		count += uint64(len(rows))
+
+		if len(rows) < readBatchSize {
+			return count, nil
+		}
+		lastID = t.id(&rows[len(rows)-1])
+	}
+}
+
+func (t tableOf[E]) block(raw json.RawMessage) (uint64, floor, error) {
+	if t.floor == noFloor {
+		return 0, noFloor, nil
+	}
+	var row E
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return 0, noFloor, errors.Wrapf(err, "decode %s row", t.tableName)
+	}
+	return t.blockNumber(&row), t.floor, nil
+}
+
+func (t tableOf[E]) newBuffer() buffer {
+	return &rowsOf[E]{}
+}
+
+// rowsOf buffers rows of entity E.
+type rowsOf[E any] struct {
+	rows []E
+}
+
+func (b *rowsOf[E]) add(raw json.RawMessage) error {
+	var row E
+	if err := json.Unmarshal(raw, &row); err != nil {
+		return err
+	}
+	b.rows = append(b.rows, row)
+	return nil
+}
+
+func (b *rowsOf[E]) len() int { return len(b.rows) }
+
+func (b *rowsOf[E]) insert(db *gorm.DB) error {
+	if len(b.rows) == 0 {
+		return nil
+	}
+	if err := db.CreateInBatches(b.rows, database.DBTransactionBatchesSize).Error; err != nil {
+		var zero E
+		return errors.Wrapf(err, "insert %T rows", zero)
+	}
+	b.rows = b.rows[:0]
+	return nil
+}
@@ -0,0 +1,178 @@
+package status
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Phase names a stage of the indexer lifecycle, in the order they run.
+type Phase string
+
+const (
+	PhaseStarting         Phase = "starting"
+	PhaseFspEventBackfill Phase = "fsp_event_backfill"
+	PhaseCatchup          Phase = "catchup"
+	PhaseContinuous       Phase = "continuous"
+)
+
+// Progress tracks the startup lifecycle of one indexer. The engine and FSP
+// startup find it in their context (WithProgress) instead of having a handle
+// threaded through every call; without one they record into the
+// process-wide tracker, which is all a single-chain indexer uses.
+type Progress struct {
+	mu         sync.Mutex
+	phase      Phase
+	phaseStart time.Time
+	catchup    catchupTracker
+}
+
+var processProgress Progress
+
+type progressKey struct{}
+
+// WithProgress returns a context whose indexer records into p.
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFrom returns the tracker of the indexer running under ctx.
+func ProgressFrom(ctx context.Context) *Progress {
+	if p, ok := ctx.Value(progressKey{}).(*Progress); ok {
+		return p
+	}
+	return &processProgress
+}
+
+type catchupTracker struct {
+	active      bool
+	committed   bool
+	from        uint64
+	to          uint64
+	lastIndexed uint64
+	started     time.Time
+	updated     time.Time
+}
+
+// StartupProgress is a snapshot of the current lifecycle phase and, while a
+// catchup range is being indexed, how far it has come.
+type StartupProgress struct {
+	Phase          Phase            `json:"phase"`
+	PhaseStartedAt time.Time        `json:"phase_started_at"`
+	Catchup        *CatchupProgress `json:"catchup,omitempty"`
+}
+
+// CatchupProgress describes the catchup range. ETASeconds is extrapolated
+// from the average rate since catchup started and is omitted until the first
+// batch has committed.
+type CatchupProgress struct {
+	From            uint64   `json:"from"`
+	To              uint64   `json:"to"`
+	LastIndexed     uint64   `json:"last_indexed"`
+	Percent         float64  `json:"percent"`
+	BlocksPerSecond float64  `json:"blocks_per_second"`
+	ETASeconds      *float64 `json:"eta_seconds,omitempty"`
+}
+
+// SetPhase records the lifecycle phase the indexer has entered.
+func SetPhase(ctx context.Context, phase Phase) {
+	ProgressFrom(ctx).SetPhase(phase)
+}
+
+// StartCatchup records the block range catchup is about to index.
+func StartCatchup(ctx context.Context, from, to uint64) {
+	ProgressFrom(ctx).StartCatchup(from, to)
+}
+
+// RecordCatchup records a committed catchup batch; see Progress.RecordCatchup.
+func RecordCatchup(ctx context.Context, lastIndexed, to uint64) {
+	ProgressFrom(ctx).RecordCatchup(lastIndexed, to)
+}
+
+func (p *Progress) SetPhase(phase Phase) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.phase = phase
+	p.phaseStart = time.Now()
+}
+
+func (p *Progress) StartCatchup(from, to uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.catchup = catchupTracker{
+		active:  true,
+		from:    from,
+		to:      to,
+		started: now,
+		updated: now,
+	}
+}
+
+// RecordCatchup records a committed catchup batch. to is the current end of
+// the range, which grows when the history range is extended toward the tip.
+func (p *Progress) RecordCatchup(lastIndexed, to uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.catchup.active {
+		return
+	}
+	p.catchup.committed = true
+	p.catchup.lastIndexed = lastIndexed
+	p.catchup.to = to
+	p.catchup.updated = time.Now()
+}
+
+// Startup returns a snapshot of the tracked progress, or nil before the
+// first phase has been recorded (e.g. in the status CLI, which observes a
+// different process).
+func (p *Progress) Startup() *StartupProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.phase == "" {
+		return nil
+	}
+
+	snapshot := &StartupProgress{
+		Phase:          p.phase,
+		PhaseStartedAt: p.phaseStart,
+	}
+	if p.catchup.active {
+		snapshot.Catchup = p.catchup.snapshot()
+	}
+	return snapshot
+}
+
+func (c catchupTracker) snapshot() *CatchupProgress {
+	cp := &CatchupProgress{
+		From:        c.from,
+		To:          c.to,
+		LastIndexed: c.lastIndexed,
+	}
+
+	total := uint64(0)
+	if c.to >= c.from {
+		total = c.to + 1 - c.from
+	}
+	done := uint64(0)
+	if c.committed && c.lastIndexed+1 > c.from {
+		done = min(c.lastIndexed+1-c.from, total)
+	}
+	if total > 0 {
+		cp.Percent = 100 * float64(done) / float64(total)
+	}
+
+	elapsed := c.updated.Sub(c.started).Seconds()
+	if done == 0 || elapsed <= 0 {
+		return cp
+	}
+	cp.BlocksPerSecond = float64(done) / elapsed
+	eta := float64(total-done) / cp.BlocksPerSecond
+	cp.ETASeconds = &eta
+
+	return cp
+}
@@ -0,0 +1,56 @@
+package status
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCatchupSnapshot(t *testing.T) {
+	started := time.Unix(1_000_000, 0)
+
+	t.Run("no batch committed yet has no ETA", func(t *testing.T) {
+		cp := catchupTracker{active: true, from: 100, to: 199, started: started, updated: started}.snapshot()
+		require.Zero(t, cp.Percent)
+		require.Nil(t, cp.ETASeconds)
+	})
+
+	t.Run("ETA extrapolates the average rate", func(t *testing.T) {
+		cp := catchupTracker{
+			active:      true,
+			committed:   true,
+			from:        100,
+			to:          199,
+			lastIndexed: 124,
+			started:     started,
+			updated:     started.Add(5 * time.Second),
+		}.snapshot()
+		require.InDelta(t, 25.0, cp.Percent, 1e-9)
+		require.InDelta(t, 5.0, cp.BlocksPerSecond, 1e-9)
+		require.NotNil(t, cp.ETASeconds)
+		require.InDelta(t, 15.0, *cp.ETASeconds, 1e-9)
+	})
+
+	t.Run("empty range does not underflow", func(t *testing.T) {
+		cp := catchupTracker{active: true, from: 200, to: 199, started: started, updated: started}.snapshot()
+		require.Zero(t, cp.Percent)
+		require.Nil(t, cp.ETASeconds)
+	})
+}
+
+func TestLag(t *testing.T) {
+	require.Nil(t, lag(map[database.StateName]database.State{}), "no lag before both states are written")
+
+	states := map[database.StateName]database.State{
+		database.ChainTip:    {Index: 1_000, BlockTimestamp: 5_000},
+		database.LastIndexed: {Index: 990, BlockTimestamp: 4_980},
+	}
+	require.Equal(t, &Lag{Blocks: 10, Seconds: 20}, lag(states))
+
+	// LastIndexed can briefly pass a stale ChainTip row; lag saturates at 0.
+	states[database.LastIndexed] = database.State{Index: 1_001, BlockTimestamp: 5_001}
+	require.Equal(t, &Lag{}, lag(states))
+}
@@ -0,0 +1,263 @@
+// Package status reports what the indexer covers and how far along it is,
+// for both the status CLI command and the /status HTTP endpoint.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/admin"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/boff"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/chain"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/config"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/database"
+	"github.com/flare-foundation/flare-system-c-chain-indexer/internal/logging"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Report is the JSON document served on /status and printed by the status
+// command.
+type Report struct {
+	Mode      string           `json:"mode"`
+	ChainID   uint64           `json:"chain_id"`
+	Contracts []ContractFilter `json:"contracts"`
+	States    []StateEntry     `json:"states"`
+	Lag       *Lag             `json:"lag,omitempty"`
+	Startup   *StartupProgress `json:"startup,omitempty"`
+	Fsp       *FspInfo         `json:"fsp,omitempty"`
+	Admin     *admin.State     `json:"admin,omitempty"`
+	// Failures counts the failed attempts of retried operations in the
+	// process per error class; only the running indexer reports it.
+	Failures map[string]uint64 `json:"rpc_failures,omitempty"`
+}
+
+// ContractFilter is one configured collector with its resolved address.
+type ContractFilter struct {
+	Kind            string   `json:"kind"` // "transaction" or "log"
+	ContractName    string   `json:"contract_name,omitempty"`
+	ContractAddress string   `json:"contract_address"`
+	FuncSig         string   `json:"func_sig,omitempty"`
+	FromAddresses   []string `json:"from_addresses,omitempty"`
+	Topic           string   `json:"topic,omitempty"`
+}
+
+// StateEntry is one row of the states table; Set is false for a row that
+// has not been written yet.
+type StateEntry struct {
+	Name           database.StateName `json:"name"`
+	Set            bool               `json:"set"`
+	Index          uint64             `json:"index"`
+	BlockTimestamp uint64             `json:"block_timestamp"`
+	Updated        time.Time          `json:"updated"`
+}
+
+// Lag is how far the indexed range trails the last observed chain tip.
+type Lag struct {
+	Blocks  uint64 `json:"blocks"`
+	Seconds uint64 `json:"seconds"`
+}
+
+// FspInfo is the FSP-mode epoch planning state, computed from the
+// FlareSystemsManager at most every fspInfoTTL.
+type FspInfo struct {
+	HistoryEpochs        uint64 `json:"history_epochs"`
+	CurrentRewardEpoch   uint64 `json:"current_reward_epoch"`
+	StartRewardEpoch     uint64 `json:"start_reward_epoch"`
+	HasEventAnchor       bool   `json:"has_event_anchor"`
+	EventAnchorBlock     uint64 `json:"event_anchor_block,omitempty"`
+	EventAnchorTimestamp uint64 `json:"event_anchor_timestamp,omitempty"`
+}
+
+// FspInfoFunc computes the FSP section of the report. It is injected by the
+// caller so this package does not depend on the fsp package.
+type FspInfoFunc func(ctx context.Context) (*FspInfo, error)
+
+// fspInfoTTL is how long a computed FSP section is reused. It takes several
+// contract calls, and reward epochs change every few days, so a monitor
+// polling /status does not have to cost RPC calls on every hit.
+const fspInfoTTL = 30 * time.Second
+
+type Reporter struct {
+	cfg      *config.Config
+	db       *gorm.DB
+	client   *chain.Client
+	progress *Progress
+	fspInfo  FspInfoFunc
+	controls *admin.Controls
+
+	// mu guards the RPC-backed sections below, and is held while they are
+	// fetched so concurrent requests wait for one fetch.
+	mu sync.Mutex
+	// chainIDValue is 0 until the chain ID is read; a node's chain ID does
+	// not change, so it is read once.
+	chainIDValue uint64
+	fsp          *FspInfo
+	fspUpdated   time.Time
+}
+
+// NewReporter builds a Reporter. cfg must already have its contract
+// addresses resolved. progress is the startup tracker of the indexer being
+// reported on, nil when it runs in another process, and so are its admin
+// controls. fspInfo may be nil outside FSP mode.
+func NewReporter(
+	cfg *config.Config,
+	db *gorm.DB,
+	client *chain.Client,
+	progress *Progress,
+	fspInfo FspInfoFunc,
+	controls *admin.Controls,
+) *Reporter {
+	return &Reporter{cfg: cfg, db: db, client: client, progress: progress, fspInfo: fspInfo, controls: controls}
+}
+
+func (r *Reporter) Report(ctx context.Context) (*Report, error) {
+	chainID, err := r.chainID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := database.GetStates(r.db.WithContext(ctx), database.AllStateNames...)
+	if err != nil {
+		return nil, errors.Wrap(err, "database.GetStates")
+	}
+
+	report := &Report{
+		Mode:      r.cfg.Indexer.Mode,
+		ChainID:   chainID,
+		Contracts: contractFilters(r.cfg.Indexer),
+		States:    StateEntries(states),
+		Lag:       lag(states),
+	}
+	if r.progress != nil {
+		report.Startup = r.progress.Startup()
+		report.Failures = boff.FailureCounts()
+	}
+	if r.controls != nil {
+		report.Admin = r.controls.State()
+	}
+
+	if r.cfg.Indexer.IsFspMode() && r.fspInfo != nil {
+		report.Fsp, err = r.fspSection(ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "FSP status")
+		}
+	}
+
+	return report, nil
+}
+
+// Handler serves the report as JSON.
+func (r *Reporter) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		report, err := r.Report(req.Context())
+		if err != nil {
+			logging.For(logging.Main).Warnw("Status report error", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			logging.For(logging.Main).Warnw("Status report write error", "error", err)
+		}
+	})
+}
+
+func (r *Reporter) chainID(ctx context.Context) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.chainIDValue != 0 {
+		return r.chainIDValue, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.RPCTimeout)
+	defer cancel()
+
+	chainID, err := r.client.ChainID(ctx)
+	if err != nil {
+		return 0, errors.Wrap(err, "get chain ID")
+	}
+	r.chainIDValue = chainID.Uint64()
+	return r.chainIDValue, nil
+}
+
+// fspSection returns the FSP section, computed at most once per fspInfoTTL.
+func (r *Reporter) fspSection(ctx context.Context) (*FspInfo, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fsp != nil && time.Since(r.fspUpdated) < fspInfoTTL {
+		return r.fsp, nil
+	}
+
+	info, err := r.fspInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.fsp, r.fspUpdated = info, time.Now()
+	return info, nil
+}
+
+func contractFilters(cfg config.IndexerConfig) []ContractFilter {
+	filters := make([]ContractFilter, 0, len(cfg.CollectTransactions)+len(cfg.CollectLogs))
+	for _, tx := range cfg.CollectTransactions {
+		filters = append(filters, ContractFilter{
+			Kind:            "transaction",
+			ContractName:    tx.ContractName,
+			ContractAddress: tx.ContractAddress,
+			FuncSig:         tx.FuncSig,
+			FromAddresses:   tx.Senders(),
+		})
+	}
+	for _, lg := range cfg.CollectLogs {
+		filters = append(filters, ContractFilter{
+			Kind:            "log",
+			ContractName:    lg.ContractName,
+			ContractAddress: lg.ContractAddress,
+			Topic:           lg.Topic,
+		})
+	}
+	return filters
+}
+
+// StateEntries lists the coverage states in AllStateNames order, including
+// rows that have not been written yet.
+func StateEntries(states map[database.StateName]database.State) []StateEntry {
+	entries := make([]StateEntry, 0, len(database.AllStateNames))
+	for _, name := range database.AllStateNames {
+		state := states[name]
+		entries = append(entries, StateEntry{
+			Name:           name,
+			Set:            database.IsSet(state),
+			Index:          state.Index,
+			BlockTimestamp: state.BlockTimestamp,
+			Updated:        state.Updated,
+		})
+	}
+	return entries
+}
+
+// lag is nil until both the chain tip and the indexed top have been written.
+func lag(states map[database.StateName]database.State) *Lag {
+	tip := states[database.ChainTip]
+	last := states[database.LastIndexed]
+	if !database.IsSet(tip) || !database.IsSet(last) {
+		return nil
+	}
+
+	return &Lag{
+		Blocks:  saturatingSub(tip.Index, last.Index),
+		Seconds: saturatingSub(tip.BlockTimestamp, last.BlockTimestamp),
+	}
+}
+
+func saturatingSub(a, b uint64) uint64 {
+	if a <= b {
+		return 0
+	}
+	return a - b
+}
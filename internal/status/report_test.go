@@ -0,0 +1,30 @@
+package status
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFspSectionIsCached(t *testing.T) {
+	calls := 0
+	r := NewReporter(nil, nil, nil, nil, func(context.Context) (*FspInfo, error) {
+		calls++
+		return &FspInfo{CurrentRewardEpoch: uint64(calls)}, nil
+	}, nil)
+
+	info, err := r.fspSection(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), info.CurrentRewardEpoch)
+	info, err = r.fspSection(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), info.CurrentRewardEpoch)
+	require.Equal(t, 1, calls, "served from the cache")
+
+	r.fspUpdated = time.Now().Add(-fspInfoTTL)
+	info, err = r.fspSection(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), info.CurrentRewardEpoch, "recomputed after the TTL")
+}
@@ -0,0 +1,77 @@
+// Package tracing exports OpenTelemetry traces of the indexer to an OTLP/HTTP
+// collector. Without Setup, or with an empty endpoint, spans go to the no-op
+// global tracer provider and cost next to nothing.
+package tracing
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/flare-foundation/flare-system-c-chain-indexer"
+
+type Config struct {
+	// Endpoint is the host:port of the OTLP/HTTP collector; empty disables
+	// tracing.
+	Endpoint string
+	// Insecure sends spans over plain HTTP.
+	Insecure    bool
+	SampleRatio float64
+	ServiceName string
+}
+
+// Setup installs the global tracer provider exporting to cfg.Endpoint and
+// returns the func flushing and stopping it. With an empty endpoint it does
+// nothing.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.Endpoint)}
+	if cfg.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "create OTLP trace exporter")
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", cfg.ServiceName))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		return errors.Wrap(provider.Shutdown(ctx), "shut down tracer provider")
+	}, nil
+}
+
+// Start starts a span as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Block is a block number attribute.
+func Block(key string, number uint64) attribute.KeyValue {
+	return attribute.Int64(key, int64(number))
+}
@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+)
+
+func TestSetupExportsToCollector(t *testing.T) {
+	var exports atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/traces" {
+			exports.Add(1)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	shutdown, err := Setup(context.Background(), Config{
+		Endpoint:    strings.TrimPrefix(collector.URL, "http://"),
+		Insecure:    true,
+		SampleRatio: 1,
+		ServiceName: "indexer-test",
+	})
+	require.NoError(t, err)
+
+	_, span := Start(context.Background(), "core.indexBatch", Block("from", 1))
+	End(span, nil)
+
+	// Shutdown flushes the batch of ended spans to the collector.
+	require.NoError(t, shutdown(context.Background()))
+	require.EqualValues(t, 1, exports.Load())
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	previous := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+	require.Equal(t, previous, otel.GetTracerProvider())
+}
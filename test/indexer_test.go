@@ -104,7 +104,7 @@ func TestIndexer(t *testing.T) {
 	require.NoError(t, err)
 
 	// connect to the database
-	db, err := database.ConnectAndInitialize(ctx, &cfg.DB)
+	db, err := database.ConnectAndInitialize(ctx, &cfg.DB, nil)
 	if err != nil {
 		logger.Fatalf("Database connect and initialize error: %s", err)
 	}
@@ -179,7 +179,7 @@ func runIndexer(ctx context.Context, mockChain *MockChain, db *gorm.DB, cfg *con
 		logger.Fatalf("Invalid node URL in config: %s", err)
 	}
 
-	ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RpcConcurrency)
+	ethClient, err := chain.DialRPCNode(nodeURL, cfg.Chain.ChainType, cfg.Indexer.RPCLimits())
 	if err != nil {
 		logger.Fatalf("Could not connect to the Ethereum node: %s", err)
 	}